@@ -9,11 +9,15 @@ import (
 
 	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
 	"github.com/unklstewy/mmdvm_ghost/pkg/dmr"
 	"github.com/unklstewy/mmdvm_ghost/pkg/dstar"
+	"github.com/unklstewy/mmdvm_ghost/pkg/fm"
 	"github.com/unklstewy/mmdvm_ghost/pkg/log"
 	"github.com/unklstewy/mmdvm_ghost/pkg/m17"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 	"github.com/unklstewy/mmdvm_ghost/pkg/nxdn"
+	"github.com/unklstewy/mmdvm_ghost/pkg/p25"
 	"github.com/unklstewy/mmdvm_ghost/pkg/pocsag"
 	"github.com/unklstewy/mmdvm_ghost/pkg/ysf"
 )
@@ -24,6 +28,57 @@ func handleSignals() chan os.Signal {
 	return signalChan
 }
 
+// handleModemFrame passes a frame read from the modem to the handler of its mode, replacing the modem
+// command with the tag the handler expects. AX.25 frames carry no tag and FM takes the command itself.
+func handleModemFrame(command byte, payload []byte) {
+	tagged := func(tag byte) []byte {
+		return append([]byte{tag}, payload...)
+	}
+
+	switch command {
+	case modem.CmdDStarHeader:
+		dstar.HandleDStarPacket(tagged(dstar.TagHeader))
+	case modem.CmdDStarData:
+		dstar.HandleDStarPacket(tagged(dstar.TagData))
+	case modem.CmdDStarLost:
+		dstar.HandleDStarPacket(tagged(dstar.TagLost))
+	case modem.CmdDStarEOT:
+		dstar.HandleDStarPacket(tagged(dstar.TagEOT))
+	case modem.CmdDMRData1:
+		dmr.HandleDMRPacket(1, tagged(dmr.TagData))
+	case modem.CmdDMRLost1:
+		dmr.HandleDMRPacket(1, tagged(dmr.TagLost))
+	case modem.CmdDMRData2:
+		dmr.HandleDMRPacket(2, tagged(dmr.TagData))
+	case modem.CmdDMRLost2:
+		dmr.HandleDMRPacket(2, tagged(dmr.TagLost))
+	case modem.CmdYSFData:
+		ysf.HandleYSFPacket(tagged(ysf.TagData))
+	case modem.CmdYSFLost:
+		ysf.HandleYSFPacket(tagged(ysf.TagLost))
+	case modem.CmdP25Header, modem.CmdP25LDU:
+		p25.HandleP25Packet(tagged(p25.TagData))
+	case modem.CmdP25Lost:
+		p25.HandleP25Packet(tagged(p25.TagLost))
+	case modem.CmdNXDNData:
+		nxdn.HandleNXDNPacket(tagged(nxdn.TagData))
+	case modem.CmdNXDNLost:
+		nxdn.HandleNXDNPacket(tagged(nxdn.TagLost))
+	case modem.CmdM17LinkSetup:
+		m17.HandleM17Packet(tagged(m17.TagHeader))
+	case modem.CmdM17Stream, modem.CmdM17Packet:
+		m17.HandleM17Packet(tagged(m17.TagData))
+	case modem.CmdM17Lost:
+		m17.HandleM17Packet(tagged(m17.TagLost))
+	case modem.CmdM17EOT:
+		m17.HandleM17Packet(tagged(m17.TagEOT))
+	case modem.CmdAX25Data:
+		ax25.HandleAX25Packet(payload)
+	case modem.CmdFMData, modem.CmdFMStatus, modem.CmdFMEOT:
+		fm.HandleFMPacket(append([]byte{command}, payload...))
+	}
+}
+
 func main() {
 	configPath := flag.String("config", "mmdvm_ghost.db", "Path to SQLite configuration database")
 	verbose := flag.Bool("verbose", false, "Enable verbose logging")
@@ -46,6 +101,9 @@ func main() {
 		log.Fatal("Error loading config:", err)
 	}
 
+	// Undelivered pages are kept in the configuration database
+	pocsag.DatabasePath = *configPath
+
 	signalChan := handleSignals()
 	reload := false
 
@@ -58,18 +116,28 @@ func main() {
 			reload = false
 		}
 
+		// Open the modem first so the protocol handlers can transmit through it
+		if err := modem.InitModem(config, handleModemFrame); err != nil {
+			log.Error("Modem not opened:", err)
+		}
+
 		log.Info("Initializing protocol handlers...")
 		// Initialize protocol handlers here
+		dmr.InitLookup(config.FilePaths.DMRID)
 		dmr.Init(config.DMR)
 		dstar.Init(config.DStar)
 		m17.Init(config.M17)
 		ax25.Init(config.AX25)
+		ax25.InitAPRS(config.APRS, config.General)
 		nxdn.Init(config.NXDN)
+		p25.Init(config.P25)
 		pocsag.Init(config.Pocsag)
 		ysf.Init(config.YSF)
+		fm.Init(config.FM)
+		cwid.Init(config.CWId, config.General)
 
 		// Example usage of ProcessWakeup in the main loop
-		data := []byte{dmr.TAG_DATA, dmr.DMR_IDLE_RX | dmr.DMR_SYNC_DATA | dmr.DT_CSBK, 0x01, 0x02}
+		data := []byte{dmr.TagData, dmr.DmrIdleRx | dmr.DmrSyncData | dmr.DtCSBK, 0x01, 0x02}
 		if err := dmr.ProcessWakeup(data); err != nil {
 			log.Error("ProcessWakeup failed:", err)
 		}
@@ -1,6 +1,259 @@
+// Package ambe provides forward error correction for AMBE voice frames carried by DMR, YSF and D-Star.
 package ambe
 
+import (
+	"errors"    // For error handling
+	"fmt"       // For formatted errors
+	"math/bits" // For bit error counting
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/utils" // For Golay(24,12) and Golay(23,12)
+)
+
+// FrameLength is the length in bytes of a single 72-bit AMBE frame.
+const FrameLength = 9
+
+// DataLength is the length in bytes of the 49 voice parameter bits of an AMBE+2 3600x2450 frame,
+// without FEC, as carried by the NXDN network protocols.
+const DataLength = 7
+
+// DMRBurstLength is the length in bytes of a DMR voice burst carrying three AMBE+2 frames.
+const DMRBurstLength = 33
+
+// Bit positions of the C0 (a), C1 (b) and C2 (c) vectors within a 72-bit AMBE+2 3600x2450 frame,
+// as used by DMR and YSF. The frame is interleaved with a stride of 4.
+var (
+	ambe2450A = interleave(4, 0, 24)
+	ambe2450B = interleave(4, 24, 23)
+	ambe2450C = interleave(4, 47, 25)
+)
+
+// Bit positions of the C0 (a) and C1 (b) vectors within a 72-bit AMBE 3600x2400 frame,
+// as used by D-Star. The frame is interleaved with a stride of 6, the remaining bits are unprotected.
+var (
+	ambe2400A = interleave(6, 0, 24)
+	ambe2400B = interleave(6, 24, 24)
+)
+
+// Silence vectors substituted for frames with too many errors to be trusted.
+const (
+	silence2450A = 0xF80148
+	silence2450B = 0x54150D
+	silence2450C = 0x13F19C1
+	silence2400A = 0xF85334
+	silence2400B = 0x439A0E
+)
+
+// interleave returns count bit positions starting at the offset-th position of the sequence
+// 0, stride, 2*stride, ..., 1, 1+stride, ... over a 72-bit frame.
+func interleave(stride, offset, count int) []int {
+	order := make([]int, 0, 72)
+	for start := 0; start < stride; start++ {
+		for pos := start; pos < 72; pos += stride {
+			order = append(order, pos)
+		}
+	}
+	return order[offset : offset+count]
+}
+
+// prng returns the whitening mask applied to the C1 vector, seeded from the 12 data bits of C0.
+func prng(seed uint32, length int) uint32 {
+	pr := 16 * seed
+	var mask uint32
+	for i := 0; i < length; i++ {
+		pr = (173*pr + 13849) % 65536
+		mask = mask<<1 | pr>>15
+	}
+	return mask
+}
+
+// readVector gathers the bits at the given positions into an integer, first position in the MSB.
+func readVector(frame []byte, positions []int) uint32 {
+	var v uint32
+	for _, pos := range positions {
+		v = v<<1 | uint32(frame[pos>>3]>>(7-uint(pos&7))&1)
+	}
+	return v
+}
+
+// writeVector scatters an integer back into the bits at the given positions.
+func writeVector(frame []byte, positions []int, v uint32) {
+	for i, pos := range positions {
+		mask := byte(0x80) >> uint(pos&7)
+		if v&(1<<uint(len(positions)-1-i)) != 0 {
+			frame[pos>>3] |= mask
+		} else {
+			frame[pos>>3] &^= mask
+		}
+	}
+}
+
+// tooManyErrors reports whether a frame should be replaced by silence.
+func tooManyErrors(errsA, errsB int) bool {
+	return errsA >= 4 || (errsA+errsB >= 6 && errsA >= 2)
+}
+
+// Regenerate2450 regenerates a 9-byte AMBE+2 3600x2450 frame (DMR, YSF) in place.
+// C0 is protected by Golay(24,12) and C1 by Golay(23,12) whitened with a PRNG seeded from C0.
+// It returns the number of bit errors found in C0 and C1.
+func Regenerate2450(frame []byte) (int, error) {
+	if len(frame) != FrameLength {
+		return 0, fmt.Errorf("invalid AMBE frame length %d, expected %d bytes", len(frame), FrameLength)
+	}
+
+	a := readVector(frame, ambe2450A)
+	b := readVector(frame, ambe2450B)
+	c := readVector(frame, ambe2450C)
+
+	dataA, errsA := utils.DecodeGolay24128(a)
+	p := prng(dataA, 23)
+	dataB, errsB := utils.DecodeGolay23127(b ^ p)
+
+	if tooManyErrors(errsA, errsB) {
+		a, b, c = silence2450A, silence2450B, silence2450C
+	} else {
+		a = utils.EncodeGolay24128(dataA)
+		b = utils.EncodeGolay23127(dataB) ^ p
+	}
+
+	writeVector(frame, ambe2450A, a)
+	writeVector(frame, ambe2450B, b)
+	writeVector(frame, ambe2450C, c)
+	return errsA + errsB, nil
+}
+
+// Decode2450 strips the FEC from a 9-byte AMBE+2 3600x2450 frame, returning the 49 voice parameter bits
+// packed MSB first into DataLength bytes (12 bits of C0, 12 of C1 and 25 of C2) and the bit errors found.
+func Decode2450(frame []byte) ([]byte, int, error) {
+	if len(frame) != FrameLength {
+		return nil, 0, fmt.Errorf("invalid AMBE frame length %d, expected %d bytes", len(frame), FrameLength)
+	}
+
+	dataA, errsA := utils.DecodeGolay24128(readVector(frame, ambe2450A))
+	dataB, errsB := utils.DecodeGolay23127(readVector(frame, ambe2450B) ^ prng(dataA, 23))
+	c := readVector(frame, ambe2450C)
+
+	if tooManyErrors(errsA, errsB) {
+		dataA, _ = utils.DecodeGolay24128(silence2450A)
+		dataB, _ = utils.DecodeGolay23127(silence2450B ^ prng(dataA, 23))
+		c = silence2450C
+	}
+
+	// The 49 bits are left aligned in 56
+	packed := (uint64(dataA)<<37 | uint64(dataB)<<25 | uint64(c)) << 7
+	data := make([]byte, DataLength)
+	for i := range data {
+		data[i] = byte(packed >> uint(48-8*i))
+	}
+	return data, errsA + errsB, nil
+}
+
+// Encode2450 adds the FEC to 49 voice parameter bits packed as returned by Decode2450, returning a 9-byte
+// AMBE+2 3600x2450 frame.
+func Encode2450(data []byte) ([]byte, error) {
+	if len(data) != DataLength {
+		return nil, fmt.Errorf("invalid AMBE data length %d, expected %d bytes", len(data), DataLength)
+	}
+
+	var packed uint64
+	for _, b := range data {
+		packed = packed<<8 | uint64(b)
+	}
+	packed >>= 7
+	dataA := uint32(packed>>37) & 0xFFF
+	dataB := uint32(packed>>25) & 0xFFF
+	c := uint32(packed) & 0x1FFFFFF
+
+	frame := make([]byte, FrameLength)
+	writeVector(frame, ambe2450A, utils.EncodeGolay24128(dataA))
+	writeVector(frame, ambe2450B, utils.EncodeGolay23127(dataB)^prng(dataA, 23))
+	writeVector(frame, ambe2450C, c)
+	return frame, nil
+}
+
+// Regenerate2400 regenerates a 9-byte AMBE 3600x2400 frame (D-Star) in place.
+// C0 is protected by Golay(24,12) and C1 by Golay(24,12) whitened with a PRNG seeded from C0.
+// It returns the number of bit errors found in C0 and C1.
+func Regenerate2400(frame []byte) (int, error) {
+	if len(frame) != FrameLength {
+		return 0, fmt.Errorf("invalid AMBE frame length %d, expected %d bytes", len(frame), FrameLength)
+	}
+
+	a := readVector(frame, ambe2400A)
+	b := readVector(frame, ambe2400B)
+
+	dataA, errsA := utils.DecodeGolay24128(a)
+	p := prng(dataA, 24)
+	dataB, errsB := utils.DecodeGolay24128(b ^ p)
+
+	if tooManyErrors(errsA, errsB) {
+		a, b = silence2400A, silence2400B
+	} else {
+		a = utils.EncodeGolay24128(dataA)
+		b = utils.EncodeGolay24128(dataB) ^ p
+	}
+
+	writeVector(frame, ambe2400A, a)
+	writeVector(frame, ambe2400B, b)
+	return errsA + errsB, nil
+}
+
+// RegenerateDMR regenerates the three AMBE+2 frames of a 33-byte DMR voice burst in place,
+// leaving the sync or embedded signalling field untouched. It returns the total bit errors.
+func RegenerateDMR(burst []byte) (int, error) {
+	if len(burst) != DMRBurstLength {
+		return 0, fmt.Errorf("invalid DMR burst length %d, expected %d bytes", len(burst), DMRBurstLength)
+	}
+
+	// The second frame straddles the 48-bit sync/embedded field in bits 108 to 155
+	frames := [3][]byte{
+		append([]byte{}, burst[0:9]...),
+		{burst[9], burst[10], burst[11], burst[12], burst[13]&0xF0 | burst[19]&0x0F, burst[20], burst[21], burst[22], burst[23]},
+		append([]byte{}, burst[24:33]...),
+	}
+
+	total := 0
+	for _, frame := range frames {
+		errs, err := Regenerate2450(frame)
+		if err != nil {
+			return 0, err
+		}
+		total += errs
+	}
+
+	copy(burst[0:9], frames[0])
+	copy(burst[9:13], frames[1][0:4])
+	burst[13] = frames[1][4]&0xF0 | burst[13]&0x0F
+	burst[19] = burst[19]&0xF0 | frames[1][4]&0x0F
+	copy(burst[20:24], frames[1][5:9])
+	copy(burst[24:33], frames[2])
+	return total, nil
+}
+
+// CountBits returns the number of differing bits between two equal length frames, for BER measurement.
+func CountBits(a, b []byte) int {
+	count := 0
+	for i := 0; i < len(a) && i < len(b); i++ {
+		count += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return count
+}
+
+// CorrectAMBEData returns a regenerated copy of a 9-byte AMBE+2 frame or a 33-byte DMR voice burst.
 func CorrectAMBEData(data []byte) ([]byte, error) {
-	// TODO: Add AMBE FEC logic
-	return nil, nil
+	corrected := append([]byte{}, data...)
+
+	switch len(data) {
+	case FrameLength:
+		if _, err := Regenerate2450(corrected); err != nil {
+			return nil, err
+		}
+	case DMRBurstLength:
+		if _, err := RegenerateDMR(corrected); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("invalid data length, expected 9 or 33 bytes")
+	}
+
+	return corrected, nil
 }
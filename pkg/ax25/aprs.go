@@ -0,0 +1,75 @@
+package ax25
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+)
+
+// aprsOutput receives APRS packets in TNC2 text format for transmission, nil until an APRS link is configured.
+var (
+	aprsMu     sync.Mutex
+	aprsOutput func(packet string)
+)
+
+// SetAPRSOutput sets the function APRS packets from the protocol handlers are passed to.
+func SetAPRSOutput(output func(packet string)) {
+	aprsMu.Lock()
+	defer aprsMu.Unlock()
+	aprsOutput = output
+}
+
+// SendAPRS passes an APRS packet in TNC2 text format (SRC>DEST,PATH:body), such as a position
+// heard on D-Star or DMR, to the APRS output.
+func SendAPRS(packet string) {
+	aprsMu.Lock()
+	output := aprsOutput
+	aprsMu.Unlock()
+
+	if output == nil {
+		log.Printf("APRS: No output configured, dropping %s", packet)
+		return
+	}
+	output(packet)
+}
+
+// aprsCoordinate formats a latitude (width 2) or longitude (width 3) as degrees, minutes and hemisphere.
+func aprsCoordinate(value float64, width int, positive, negative byte) string {
+	hemisphere := positive
+	if value < 0 {
+		hemisphere = negative
+		value = -value
+	}
+	hundredths := int(math.Round(value * 6000))
+	return fmt.Sprintf("%0*d%02d.%02d%c", width, hundredths/6000, hundredths%6000/100, hundredths%100, hemisphere)
+}
+
+// PositionBody formats an APRS position report without timestamp, the symbol given as table and code,
+// such as "/[", followed by a comment.
+func PositionBody(latitude, longitude float64, symbol, comment string) string {
+	if len(symbol) != 2 {
+		symbol = "/["
+	}
+	return "!" + aprsCoordinate(latitude, 2, 'N', 'S') + symbol[:1] + aprsCoordinate(longitude, 3, 'E', 'W') +
+		symbol[1:] + comment
+}
+
+// PositionPacket formats a position heard on RF as an APRS-IS packet from source, gated by the given station.
+func PositionPacket(source, tocall, gateway string, latitude, longitude float64, symbol, comment string) string {
+	return fmt.Sprintf("%s>%s,qAR,%s:%s", source, tocall, gateway, PositionBody(latitude, longitude, symbol, comment))
+}
+
+// Passcode returns the APRS-IS passcode of a callsign, ignoring any SSID.
+func Passcode(callsign string) int {
+	call, _, _ := strings.Cut(strings.ToUpper(callsign), "-")
+	hash := 0x73E2
+	for i := 0; i < len(call); i += 2 {
+		hash ^= int(call[i]) << 8
+		if i+1 < len(call) {
+			hash ^= int(call[i+1])
+		}
+	}
+	return hash & 0x7FFF
+}
@@ -0,0 +1,337 @@
+// Package ax25 provides AX.25 packet radio protocol logic, including the APRS-IS client.
+package ax25
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APRS-IS client identification sent at login.
+const (
+	aprsSoftware = "MMDVMGhost"
+	aprsVersion  = "1.0"
+	aprsTocall   = "APZMMG" // Experimental destination identifying packets from this software
+)
+
+// APRS-IS timing.
+const (
+	aprsConnectTimeout    = 10 * time.Second       // Timeout connecting to the server
+	aprsLinkTimeout       = 120 * time.Second      // Connection treated as dead without any lines for this long
+	aprsKeepaliveInterval = 5 * time.Minute        // Comment sent when nothing else was sent for this long
+	aprsReconnectDelay    = 10 * time.Second       // Delay before reconnecting after a failure
+	aprsReadPollInterval  = 100 * time.Millisecond // Read deadline used to run the timers
+)
+
+// APRSIS is a TCP client logging in to an APRS-IS server, gating packets heard on RF and beaconing the
+// position of the hotspot. Packets from each source are rate limited.
+type APRSIS struct {
+	callsign string
+	passcode int
+	filter   string
+	address  string
+
+	beacon         string
+	beaconInterval time.Duration
+	rateLimit      time.Duration
+	keepalive      time.Duration
+
+	mu         sync.Mutex
+	conn       net.Conn
+	reader     *bufio.Reader
+	partial    string
+	stop       chan struct{}
+	loggedIn   bool
+	lastRx     time.Time
+	lastTx     time.Time
+	lastBeacon time.Time
+	lastHeard  map[string]time.Time
+}
+
+// NewAPRSIS creates a new APRS-IS client logging in with the given callsign, passcode and server side filter
+// to the server at the given address and port. A negative passcode is computed from the callsign.
+func NewAPRSIS(callsign string, passcode int, filter, address string, port int) (*APRSIS, error) {
+	if _, err := ParseAddress(callsign); err != nil {
+		return nil, fmt.Errorf("invalid APRS-IS callsign: %w", err)
+	}
+	callsign = strings.ToUpper(callsign)
+	if passcode < 0 {
+		passcode = Passcode(callsign)
+	}
+
+	return &APRSIS{
+		callsign:  callsign,
+		passcode:  passcode,
+		filter:    filter,
+		address:   net.JoinHostPort(address, strconv.Itoa(port)),
+		keepalive: aprsKeepaliveInterval,
+		lastHeard: make(map[string]time.Time),
+	}, nil
+}
+
+// Callsign returns the callsign the client logs in with.
+func (a *APRSIS) Callsign() string {
+	return a.callsign
+}
+
+// SetBeacon sets the position report body sent for the hotspot itself every interval, a zero interval
+// disabling the beacon.
+func (a *APRSIS) SetBeacon(body string, interval time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.beacon = body
+	a.beaconInterval = interval
+	a.lastBeacon = time.Time{}
+}
+
+// SetRateLimit sets the minimum time between packets gated for one source, zero for no limit.
+func (a *APRSIS) SetRateLimit(limit time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rateLimit = limit
+}
+
+// Open starts the connection loop. The server is connected in the background and reconnected after failures.
+func (a *APRSIS) Open() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stop != nil {
+		return errors.New("APRS-IS already open")
+	}
+	a.stop = make(chan struct{})
+	go a.run(a.stop)
+	return nil
+}
+
+// Close stops the connection loop and closes the connection.
+func (a *APRSIS) Close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	a.stop = nil
+	a.disconnect()
+	log.Printf("APRS: APRS-IS closed")
+}
+
+// IsLoggedIn reports whether the server has answered the login.
+func (a *APRSIS) IsLoggedIn() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.loggedIn
+}
+
+// Send gates an APRS packet in TNC2 text format, dropping it when the source sent a packet within the rate
+// limit or the server is not connected.
+func (a *APRSIS) Send(packet string) error {
+	packet = strings.TrimRight(packet, "\r\n")
+	source, _, ok := strings.Cut(packet, ">")
+	if !ok || strings.ContainsAny(packet, "\r\n") {
+		return fmt.Errorf("invalid APRS packet %q", packet)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := a.lastHeard[source]; ok && now.Sub(last) < a.rateLimit {
+		return fmt.Errorf("rate limited packet from %s", source)
+	}
+	if !a.loggedIn {
+		return errors.New("APRS-IS not logged in")
+	}
+	if err := a.write(packet, now); err != nil {
+		return err
+	}
+	a.lastHeard[source] = now
+	return nil
+}
+
+// connect dials the server and sends the login. The caller must hold the lock.
+func (a *APRSIS) connect() error {
+	conn, err := net.DialTimeout("tcp", a.address, aprsConnectTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to APRS-IS server %s: %w", a.address, err)
+	}
+	a.conn = conn
+	a.reader = bufio.NewReader(conn)
+	a.partial = ""
+	a.lastRx = time.Now()
+
+	login := fmt.Sprintf("user %s pass %d vers %s %s", a.callsign, a.passcode, aprsSoftware, aprsVersion)
+	if a.filter != "" {
+		login += " filter " + a.filter
+	}
+	if err := a.write(login, a.lastRx); err != nil {
+		a.disconnect()
+		return fmt.Errorf("failed to log in to APRS-IS server: %w", err)
+	}
+	log.Printf("APRS: Connected to APRS-IS server %s as %s", a.address, a.callsign)
+	return nil
+}
+
+// disconnect closes the connection. The caller must hold the lock.
+func (a *APRSIS) disconnect() {
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn = nil
+		a.reader = nil
+	}
+	a.loggedIn = false
+}
+
+// write sends a line to the server. The caller must hold the lock.
+func (a *APRSIS) write(line string, now time.Time) error {
+	if a.conn == nil {
+		return errors.New("APRS-IS not connected")
+	}
+	if _, err := a.conn.Write([]byte(line + "\r\n")); err != nil {
+		return err
+	}
+	a.lastTx = now
+	return nil
+}
+
+// run connects, receives lines and reconnects after errors until stopped.
+func (a *APRSIS) run(stop chan struct{}) {
+	first := true
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		a.mu.Lock()
+		conn, reader := a.conn, a.reader
+		a.mu.Unlock()
+
+		if conn == nil {
+			if !a.reconnect(stop, first) {
+				return
+			}
+			first = false
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(aprsReadPollInterval))
+		line, err := reader.ReadString('\n')
+		now := time.Now()
+
+		a.mu.Lock()
+		a.partial += line
+		line = a.partial
+		if err == nil {
+			a.partial = ""
+		}
+		a.mu.Unlock()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("APRS: APRS-IS connection lost, reconnecting: %v", err)
+				a.mu.Lock()
+				a.disconnect()
+				a.mu.Unlock()
+				continue
+			}
+		} else {
+			a.receive(strings.TrimRight(line, "\r\n"), now)
+		}
+
+		a.clock(now)
+	}
+}
+
+// reconnect waits, unless this is the first attempt, and connects to the server, returning false if the
+// client was closed meanwhile.
+func (a *APRSIS) reconnect(stop chan struct{}, first bool) bool {
+	if !first {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(aprsReconnectDelay):
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stop != stop {
+		return false
+	}
+	if err := a.connect(); err != nil {
+		log.Printf("APRS: %v", err)
+	}
+	return true
+}
+
+// receive handles a line from the server. Only the login response is of interest, the server comments are
+// its keepalive and packets matching the filter are not transmitted on RF.
+func (a *APRSIS) receive(line string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.lastRx = now
+	if !strings.HasPrefix(line, "# logresp ") {
+		return
+	}
+	if a.loggedIn {
+		return
+	}
+	a.loggedIn = true
+	if strings.Contains(line, " unverified") {
+		log.Printf("APRS: Logged in to APRS-IS server %s unverified, check the passcode: %s", a.address, line)
+		return
+	}
+	log.Printf("APRS: Logged in to APRS-IS server %s", a.address)
+}
+
+// clock runs the connection watchdog, the keepalive, the hotspot beacon and the rate limit expiry.
+func (a *APRSIS) clock(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn != nil && now.Sub(a.lastRx) > aprsLinkTimeout {
+		log.Printf("APRS: APRS-IS server %s stopped responding, reconnecting", a.address)
+		a.disconnect()
+		return
+	}
+	if !a.loggedIn {
+		return
+	}
+
+	if a.beaconInterval > 0 && a.beacon != "" && now.Sub(a.lastBeacon) >= a.beaconInterval {
+		packet := fmt.Sprintf("%s>%s,TCPIP*:%s", a.callsign, aprsTocall, a.beacon)
+		if err := a.write(packet, now); err != nil {
+			log.Printf("APRS: Unable to send beacon: %v", err)
+		}
+		a.lastBeacon = now
+	}
+	if now.Sub(a.lastTx) >= a.keepalive {
+		if err := a.write("# "+aprsSoftware+" keepalive", now); err != nil {
+			log.Printf("APRS: Unable to send keepalive: %v", err)
+		}
+	}
+
+	for source, last := range a.lastHeard {
+		if now.Sub(last) >= a.rateLimit {
+			delete(a.lastHeard, source)
+		}
+	}
+}
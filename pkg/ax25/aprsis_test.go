@@ -0,0 +1,157 @@
+package ax25
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// aprsServer is a stand-in APRS-IS server accepting a single client.
+type aprsServer struct {
+	t        *testing.T
+	listener net.Listener
+	conn     net.Conn
+	reader   *bufio.Reader
+}
+
+// newAPRSServer starts listening on a local port.
+func newAPRSServer(t *testing.T) *aprsServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &aprsServer{t: t, listener: listener}
+	t.Cleanup(func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		listener.Close()
+	})
+	return s
+}
+
+// client creates a client of the server with the given callsign and filter, without opening it.
+func (s *aprsServer) client(callsign, filter string) *APRSIS {
+	s.t.Helper()
+	addr := s.listener.Addr().(*net.TCPAddr)
+	a, err := NewAPRSIS(callsign, -1, filter, "127.0.0.1", addr.Port)
+	if err != nil {
+		s.t.Fatalf("NewAPRSIS: %v", err)
+	}
+	s.t.Cleanup(a.Close)
+	return a
+}
+
+// accept waits for the client to connect.
+func (s *aprsServer) accept() {
+	s.t.Helper()
+	s.listener.(*net.TCPListener).SetDeadline(time.Now().Add(2 * time.Second))
+	conn, err := s.listener.Accept()
+	if err != nil {
+		s.t.Fatalf("accept: %v", err)
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+}
+
+// readLine returns the next line sent by the client.
+func (s *aprsServer) readLine(timeout time.Duration) string {
+	s.t.Helper()
+	s.conn.SetReadDeadline(time.Now().Add(timeout))
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		s.t.Fatalf("read: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// login accepts the client, checks its login line and answers it.
+func (s *aprsServer) login(a *APRSIS, want string) {
+	s.t.Helper()
+	s.accept()
+	if got := s.readLine(time.Second); got != want {
+		s.t.Fatalf("login line = %q, want %q", got, want)
+	}
+	if _, err := s.conn.Write([]byte("# logresp " + a.Callsign() + " verified, server TEST\r\n")); err != nil {
+		s.t.Fatalf("write: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !a.IsLoggedIn() {
+		if time.Now().After(deadline) {
+			s.t.Fatal("client did not log in")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPRSISLogin(t *testing.T) {
+	s := newAPRSServer(t)
+	a := s.client("n0call-10", "r/52/-1/50")
+	if err := a.Send("G4XYZ>APZDMR:!5200.00N/00100.00W["); err == nil {
+		t.Error("Send before login succeeded")
+	}
+	if err := a.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	s.login(a, "user N0CALL-10 pass 13023 vers MMDVMGhost 1.0 filter r/52/-1/50")
+}
+
+func TestAPRSISLoginWithoutFilter(t *testing.T) {
+	s := newAPRSServer(t)
+	a := s.client("G4XYZ", "")
+	if err := a.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	s.login(a, "user G4XYZ pass "+strconv.Itoa(Passcode("G4XYZ"))+" vers MMDVMGhost 1.0")
+}
+
+func TestAPRSISRateLimit(t *testing.T) {
+	s := newAPRSServer(t)
+	a := s.client("N0CALL", "")
+	a.SetRateLimit(time.Minute)
+	if err := a.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	s.login(a, "user N0CALL pass 13023 vers MMDVMGhost 1.0")
+
+	first := "G4XYZ>APZDMR,qAR,N0CALL:!5200.00N/00100.00W["
+	if err := a.Send(first); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got := s.readLine(time.Second); got != first {
+		t.Errorf("gated %q, want %q", got, first)
+	}
+
+	if err := a.Send("G4XYZ>APZDMR,qAR,N0CALL:!5201.00N/00100.00W["); err == nil {
+		t.Error("second packet from the same source was not rate limited")
+	}
+
+	other := "M0ABC>APZDMR,qAR,N0CALL:!5100.00N/00200.00W["
+	if err := a.Send(other); err != nil {
+		t.Fatalf("Send from another source: %v", err)
+	}
+	if got := s.readLine(time.Second); got != other {
+		t.Errorf("gated %q, want %q", got, other)
+	}
+}
+
+func TestAPRSISKeepalive(t *testing.T) {
+	s := newAPRSServer(t)
+	a := s.client("N0CALL", "")
+	a.keepalive = 300 * time.Millisecond
+	if err := a.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	s.login(a, "user N0CALL pass 13023 vers MMDVMGhost 1.0")
+
+	if got := s.readLine(2 * time.Second); got != "# MMDVMGhost keepalive" {
+		t.Errorf("idle client sent %q, want the keepalive", got)
+	}
+}
@@ -2,15 +2,122 @@ package ax25
 
 import (
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control passes frames between the modem and the KISS clients.
+var control *Control
+
+// kiss is the KISS TCP server, nil when disabled.
+var kiss *KISSServer
+
+// aprsis is the APRS-IS client, nil when disabled.
+var aprsis *APRSIS
+
+// HandleAX25Packet passes a modem frame (AX.25 frame with FCS) to the AX.25 controller.
 func HandleAX25Packet(packet []byte) {
-	// TODO: Add AX.25 packet handling logic
+	if control == nil {
+		log.Printf("AX25: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// writeModem transmits a frame with FCS on the modem.
+func writeModem(data []byte) {
+	if err := modem.Write(modem.CmdAX25Data, data); err != nil {
+		log.Printf("AX25: Unable to write to the modem: %v", err)
+	}
 }
 
-// Init initializes the AX.25 protocol handler with the given configuration.
+// Init initializes the AX.25 protocol handler with the given configuration. The port is the KISS TCP server
+// address, a bare port number listening on all interfaces.
 func Init(config config.AX25Config) {
+	control = NewControl()
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+
+	if kiss != nil {
+		kiss.Close()
+		kiss = nil
+	}
+	if config.Enable && config.Port != "" {
+		initKISS(config.Port)
+	}
 	fmt.Printf("AX.25 protocol handler initialized with Port: %s\n", config.Port)
 }
+
+// initKISS opens the KISS server and connects it to the controller.
+func initKISS(port string) {
+	address := port
+	if !strings.Contains(address, ":") {
+		address = ":" + address
+	}
+
+	s := NewKISSServer(address)
+	c := control
+	s.Frame = c.WriteKISS
+
+	if err := s.Open(); err != nil {
+		log.Printf("AX25: %v", err)
+		return
+	}
+	c.KISS = s
+	kiss = s
+}
+
+// InitAPRS initializes the APRS-IS gateway with the given configuration, logging in with the general
+// callsign when the APRS callsign is empty.
+func InitAPRS(config config.APRSConfig, general config.GeneralConfig) {
+	if aprsis != nil {
+		SetAPRSOutput(nil)
+		aprsis.Close()
+		aprsis = nil
+	}
+	if !config.Enable {
+		return
+	}
+
+	callsign := config.Callsign
+	if callsign == "" {
+		callsign = general.Callsign
+	}
+	a, err := NewAPRSIS(callsign, config.Passcode, config.Filter, config.Server, config.Port)
+	if err != nil {
+		log.Printf("APRS: %v", err)
+		return
+	}
+	a.SetRateLimit(time.Duration(config.RateLimit) * time.Second)
+	if config.Latitude != 0 || config.Longitude != 0 {
+		body := PositionBody(config.Latitude, config.Longitude, config.Symbol, config.Description)
+		a.SetBeacon(body, time.Duration(config.BeaconInterval)*time.Minute)
+	}
+
+	if err := a.Open(); err != nil {
+		log.Printf("APRS: %v", err)
+		return
+	}
+	SetAPRSOutput(func(packet string) {
+		if err := a.Send(packet); err != nil {
+			log.Printf("APRS: Not gated: %v", err)
+			return
+		}
+		log.Printf("APRS: Gated %s", packet)
+	})
+	aprsis = a
+	fmt.Printf("APRS-IS gateway initialized with Server: %s:%d\n", config.Server, config.Port)
+}
+
+// GatewayCallsign returns the callsign positions heard on RF are gated by, empty when APRS-IS is disabled.
+func GatewayCallsign() string {
+	if aprsis == nil {
+		return ""
+	}
+	return aprsis.Callsign()
+}
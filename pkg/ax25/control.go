@@ -0,0 +1,52 @@
+// Package ax25 provides AX.25 packet radio protocol logic, including the modem and KISS frame paths.
+package ax25
+
+import (
+	"log"
+)
+
+// Control passes AX.25 frames between the modem and the KISS clients.
+type Control struct {
+	Output func(data []byte) // Receives frames with FCS for transmission by the modem, may be nil
+	KISS   *KISSServer       // KISS server frames heard on RF are passed to, may be nil
+}
+
+// NewControl creates a new Control.
+func NewControl() *Control {
+	return &Control{}
+}
+
+// WriteModem handles a frame received by the modem, without flags and with its FCS. It returns false when
+// the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	body, err := CheckFCS(data)
+	if err != nil {
+		log.Printf("AX25: RF frame rejected: %v", err)
+		return false
+	}
+	frame, err := Decode(body)
+	if err != nil {
+		log.Printf("AX25: Invalid RF frame: %v", err)
+		return false
+	}
+
+	log.Printf("AX25: RF %s", frame)
+	if c.KISS != nil {
+		c.KISS.Broadcast(body)
+	}
+	return true
+}
+
+// WriteKISS transmits a frame without FCS received from a KISS client.
+func (c *Control) WriteKISS(data []byte) {
+	frame, err := Decode(data)
+	if err != nil {
+		log.Printf("AX25: Invalid KISS frame: %v", err)
+		return
+	}
+
+	log.Printf("AX25: Transmitting %s", frame)
+	if c.Output != nil {
+		c.Output(AppendFCS(data))
+	}
+}
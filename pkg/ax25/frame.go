@@ -0,0 +1,275 @@
+// Package ax25 provides AX.25 packet radio protocol logic, including UI frame encoding and decoding.
+package ax25
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Frame field values.
+const (
+	ControlUI = 0x03 // Unnumbered information frame, poll/final bit clear
+	PIDNone   = 0xF0 // No layer 3 protocol, as used by APRS
+)
+
+// Frame limits.
+const (
+	addressLength  = 7 // Bytes per address field
+	MaxDigipeaters = 8 // Digipeaters allowed in a path
+	FCSLength      = 2 // Bytes of frame check sequence
+)
+
+// Address is an AX.25 station address with the bit that follows the SSID.
+type Address struct {
+	Callsign string // Up to six characters
+	SSID     uint8  // 0 to 15
+	H        bool   // Has-been-repeated bit for digipeaters, command/response bit for source and destination
+}
+
+// ParseAddress parses an address written as CALL, CALL-SSID or CALL-SSID*, the asterisk marking a
+// repeated digipeater.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	if strings.HasSuffix(s, "*") {
+		a.H = true
+		s = strings.TrimSuffix(s, "*")
+	}
+
+	call, ssid, hasSSID := strings.Cut(strings.ToUpper(s), "-")
+	if call == "" || len(call) > 6 {
+		return a, fmt.Errorf("invalid callsign %q", s)
+	}
+	for _, c := range call {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return a, fmt.Errorf("invalid callsign %q", s)
+		}
+	}
+	a.Callsign = call
+
+	if hasSSID {
+		n, err := strconv.Atoi(ssid)
+		if err != nil || n < 0 || n > 15 {
+			return a, fmt.Errorf("invalid SSID in %q", s)
+		}
+		a.SSID = uint8(n)
+	}
+	return a, nil
+}
+
+// String returns the address as CALL or CALL-SSID.
+func (a Address) String() string {
+	if a.SSID == 0 {
+		return a.Callsign
+	}
+	return fmt.Sprintf("%s-%d", a.Callsign, a.SSID)
+}
+
+// encode writes the 7-byte address field, marking the last address of the header.
+func (a Address) encode(last bool) []byte {
+	b := make([]byte, addressLength)
+	call := a.Callsign + "      "
+	for i := 0; i < 6; i++ {
+		b[i] = call[i] << 1
+	}
+	b[6] = 0x60 | (a.SSID&0x0F)<<1
+	if a.H {
+		b[6] |= 0x80
+	}
+	if last {
+		b[6] |= 0x01
+	}
+	return b
+}
+
+// decodeAddress parses a 7-byte address field, reporting whether it is the last of the header.
+func decodeAddress(b []byte) (Address, bool) {
+	call := make([]byte, 6)
+	for i := range call {
+		call[i] = b[i] >> 1
+	}
+	return Address{
+		Callsign: strings.TrimRight(string(call), " "),
+		SSID:     b[6] >> 1 & 0x0F,
+		H:        b[6]&0x80 != 0,
+	}, b[6]&0x01 != 0
+}
+
+// Frame is an AX.25 frame. Only UI frames carry a PID and information field, other frame types keep
+// everything after the control byte in Info.
+type Frame struct {
+	Destination Address
+	Source      Address
+	Path        []Address // Digipeaters, in order
+	Control     byte
+	PID         byte
+	Info        []byte
+}
+
+// NewUIFrame creates a command UI frame without layer 3 protocol, as used by APRS.
+func NewUIFrame(source, destination Address, path []Address, info []byte) *Frame {
+	destination.H = true // Command frame
+	source.H = false
+	return &Frame{
+		Destination: destination,
+		Source:      source,
+		Path:        path,
+		Control:     ControlUI,
+		PID:         PIDNone,
+		Info:        info,
+	}
+}
+
+// IsUI reports whether the frame is an unnumbered information frame.
+func (f *Frame) IsUI() bool {
+	return f.Control&^0x10 == ControlUI
+}
+
+// Decode parses an AX.25 frame without flags or FCS, as carried by KISS.
+func Decode(data []byte) (*Frame, error) {
+	f := &Frame{}
+	var addresses []Address
+	pos := 0
+	for {
+		if len(data) < pos+addressLength {
+			return nil, errors.New("truncated address field")
+		}
+		a, last := decodeAddress(data[pos : pos+addressLength])
+		addresses = append(addresses, a)
+		pos += addressLength
+		if last {
+			break
+		}
+		if len(addresses) == 2+MaxDigipeaters {
+			return nil, errors.New("too many digipeaters")
+		}
+	}
+	if len(addresses) < 2 {
+		return nil, errors.New("missing source address")
+	}
+	f.Destination, f.Source, f.Path = addresses[0], addresses[1], addresses[2:]
+
+	if len(data) < pos+1 {
+		return nil, errors.New("missing control field")
+	}
+	f.Control = data[pos]
+	pos++
+
+	if f.IsUI() {
+		if len(data) < pos+1 {
+			return nil, errors.New("missing PID")
+		}
+		f.PID = data[pos]
+		pos++
+	}
+	f.Info = append([]byte{}, data[pos:]...)
+	return f, nil
+}
+
+// Bytes encodes the frame without flags or FCS, as carried by KISS.
+func (f *Frame) Bytes() []byte {
+	out := make([]byte, 0, (2+len(f.Path))*addressLength+2+len(f.Info))
+	out = append(out, f.Destination.encode(false)...)
+	out = append(out, f.Source.encode(len(f.Path) == 0)...)
+	for i, a := range f.Path {
+		out = append(out, a.encode(i == len(f.Path)-1)...)
+	}
+	out = append(out, f.Control)
+	if f.IsUI() {
+		out = append(out, f.PID)
+	}
+	return append(out, f.Info...)
+}
+
+// String returns the frame in TNC2 monitor format, SRC>DEST,DIGI*,DIGI:info.
+func (f *Frame) String() string {
+	var b strings.Builder
+	b.WriteString(f.Source.String())
+	b.WriteString(">")
+	b.WriteString(f.Destination.String())
+	for _, a := range f.Path {
+		b.WriteString(",")
+		b.WriteString(a.String())
+		if a.H {
+			b.WriteString("*")
+		}
+	}
+	b.WriteString(":")
+	if f.IsUI() {
+		b.Write(f.Info)
+	} else {
+		fmt.Fprintf(&b, "<control 0x%02X>", f.Control)
+	}
+	return b.String()
+}
+
+// ParseTNC2 parses a UI frame written in TNC2 format, SRC>DEST,PATH:info.
+func ParseTNC2(s string) (*Frame, error) {
+	header, info, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, errors.New("missing information field")
+	}
+	source, rest, ok := strings.Cut(header, ">")
+	if !ok {
+		return nil, errors.New("missing destination")
+	}
+	fields := strings.Split(rest, ",")
+	if len(fields) > 1+MaxDigipeaters {
+		return nil, errors.New("too many digipeaters")
+	}
+
+	src, err := ParseAddress(source)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := ParseAddress(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	var path []Address
+	for _, field := range fields[1:] {
+		a, err := ParseAddress(field)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, a)
+	}
+	return NewUIFrame(src, dest, path, []byte(info)), nil
+}
+
+// crcCCITT computes the AX.25 FCS: CRC-CCITT with reflected polynomial 0x8408, initial value 0xFFFF,
+// inverted result.
+func crcCCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+// AppendFCS returns the frame followed by its FCS, low byte first.
+func AppendFCS(data []byte) []byte {
+	crc := crcCCITT(data)
+	return append(append([]byte{}, data...), byte(crc), byte(crc>>8))
+}
+
+// CheckFCS validates the FCS held in the last two bytes and returns the frame without it.
+func CheckFCS(data []byte) ([]byte, error) {
+	if len(data) < FCSLength {
+		return nil, errors.New("frame too short")
+	}
+	body := data[:len(data)-FCSLength]
+	crc := crcCCITT(body)
+	if data[len(data)-2] != byte(crc) || data[len(data)-1] != byte(crc>>8) {
+		return nil, errors.New("invalid FCS")
+	}
+	return body, nil
+}
@@ -0,0 +1,226 @@
+// Package ax25 provides AX.25 packet radio protocol logic, including the KISS TCP server.
+package ax25
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+)
+
+// KISS special characters.
+const (
+	kissFEND  = 0xC0 // Frame end
+	kissFESC  = 0xDB // Frame escape
+	kissTFEND = 0xDC // Transposed frame end
+	kissTFESC = 0xDD // Transposed frame escape
+)
+
+// KISS commands, in the low nibble of the first byte of a frame. The high nibble is the port.
+const (
+	kissData       = 0x00
+	kissTXDelay    = 0x01
+	kissPersist    = 0x02
+	kissSlotTime   = 0x03
+	kissTXTail     = 0x04
+	kissFullDuplex = 0x05
+	kissHardware   = 0x06
+	kissReturn     = 0x0F
+)
+
+// maxKISSFrame bounds the size of a frame accepted from a client.
+const maxKISSFrame = 1024
+
+// KISSServer is a TCP server speaking KISS, letting APRS and packet software use the modem as a TNC.
+type KISSServer struct {
+	Frame func(frame []byte) // Receives AX.25 frames without FCS sent by clients, may be nil
+
+	address string
+
+	mu       sync.Mutex
+	listener net.Listener
+	clients  map[net.Conn]bool
+}
+
+// NewKISSServer creates a new KISSServer listening on the given address.
+func NewKISSServer(address string) *KISSServer {
+	return &KISSServer{
+		address: address,
+		clients: make(map[net.Conn]bool),
+	}
+}
+
+// Open starts listening for clients.
+func (s *KISSServer) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return errors.New("KISS server already open")
+	}
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to open KISS server: %w", err)
+	}
+	s.listener = listener
+	go s.accept(listener)
+	log.Printf("AX25: KISS server listening on %s", listener.Addr())
+	return nil
+}
+
+// Addr returns the address the server is listening on, nil when closed.
+func (s *KISSServer) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops listening and disconnects all clients.
+func (s *KISSServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return
+	}
+	s.listener.Close()
+	s.listener = nil
+	for conn := range s.clients {
+		conn.Close()
+	}
+	s.clients = make(map[net.Conn]bool)
+	log.Printf("AX25: KISS server closed")
+}
+
+// Broadcast sends an AX.25 frame without FCS to every client.
+func (s *KISSServer) Broadcast(frame []byte) {
+	packet := kissEncode(kissData, frame)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.clients {
+		if _, err := conn.Write(packet); err != nil {
+			log.Printf("AX25: KISS client %s write failed: %v", conn.RemoteAddr(), err)
+			conn.Close()
+			delete(s.clients, conn)
+		}
+	}
+}
+
+// accept accepts clients until the listener is closed.
+func (s *KISSServer) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.listener != listener {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.clients[conn] = true
+		s.mu.Unlock()
+
+		log.Printf("AX25: KISS client %s connected", conn.RemoteAddr())
+		go s.serve(conn)
+	}
+}
+
+// serve reads KISS frames from a client until it disconnects.
+func (s *KISSServer) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+		conn.Close()
+		log.Printf("AX25: KISS client %s disconnected", conn.RemoteAddr())
+	}()
+
+	reader := bufio.NewReader(conn)
+	var frame []byte
+	escaped := false
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch {
+		case b == kissFEND:
+			if len(frame) > 0 {
+				s.handle(conn, frame)
+			}
+			frame = frame[:0]
+			escaped = false
+		case b == kissFESC:
+			escaped = true
+		case escaped:
+			escaped = false
+			switch b {
+			case kissTFEND:
+				frame = append(frame, kissFEND)
+			case kissTFESC:
+				frame = append(frame, kissFESC)
+			default:
+				// Protocol violation, the escape is dropped
+				frame = append(frame, b)
+			}
+		default:
+			frame = append(frame, b)
+		}
+
+		if len(frame) > maxKISSFrame {
+			log.Printf("AX25: KISS client %s sent an oversized frame", conn.RemoteAddr())
+			frame = frame[:0]
+		}
+	}
+}
+
+// handle dispatches a KISS frame from a client. Timing parameters are managed by the modem and ignored.
+func (s *KISSServer) handle(conn net.Conn, frame []byte) {
+	command := frame[0] & 0x0F
+	port := frame[0] >> 4
+
+	switch command {
+	case kissData:
+		if port != 0 {
+			log.Printf("AX25: KISS client %s sent a frame for unknown port %d", conn.RemoteAddr(), port)
+			return
+		}
+		if s.Frame != nil {
+			s.Frame(append([]byte{}, frame[1:]...))
+		}
+	case kissTXDelay, kissPersist, kissSlotTime, kissTXTail, kissFullDuplex, kissHardware:
+		// Channel access is handled by the modem
+	case kissReturn:
+		// Leaving KISS mode has no meaning over TCP
+	default:
+		log.Printf("AX25: KISS client %s sent unknown command 0x%02X", conn.RemoteAddr(), frame[0])
+	}
+}
+
+// kissEncode builds a KISS frame for a command and its data, escaping special characters.
+func kissEncode(command byte, data []byte) []byte {
+	out := make([]byte, 0, len(data)+4)
+	out = append(out, kissFEND, command)
+	for _, b := range data {
+		switch b {
+		case kissFEND:
+			out = append(out, kissFESC, kissTFEND)
+		case kissFESC:
+			out = append(out, kissFESC, kissTFESC)
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, kissFEND)
+}
@@ -1,6 +1,183 @@
+// Package bch provides the BCH(63,16,23) code protecting the P25 network identifier.
 package bch
 
-func CorrectBCHData(data []byte) ([]byte, error) {
-	// TODO: Add BCH error correction logic
-	return nil, nil
+import (
+	"errors" // For error handling
+	"fmt"    // For formatted errors
+)
+
+// Code parameters of BCH(63,16,23).
+const (
+	N = 63 // Codeword length in bits
+	K = 16 // Data length in bits
+	T = 11 // Number of bit errors the code corrects
+)
+
+// parityBits is the number of parity bits of a codeword.
+const parityBits = N - K
+
+// generator is the generator polynomial, octal 6331141367235453, with roots alpha^1 to alpha^(2T).
+const generator = 0xCD930BDD3B2B
+
+// gfExp and gfLog are the antilog and log tables of GF(64) with primitive polynomial x^6+x+1.
+// gfExp is doubled in length to avoid reducing sums of logs.
+var gfExp, gfLog = buildGF64()
+
+// buildGF64 builds the antilog and log tables of GF(64).
+func buildGF64() ([2 * N]byte, [N + 1]byte) {
+	var exp [2 * N]byte
+	var log [N + 1]byte
+	x := byte(1)
+	for i := 0; i < N; i++ {
+		exp[i] = x
+		exp[i+N] = x
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x40 != 0 {
+			x ^= 0x43
+		}
+	}
+	return exp, log
+}
+
+// gfMul multiplies two elements of GF(64).
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns the multiplicative inverse of a non-zero element of GF(64).
+func gfInv(a byte) byte {
+	return gfExp[N-int(gfLog[a])]
+}
+
+// Encode returns the 63-bit codeword of 16 data bits. Bit i holds the coefficient of x^i, so the data
+// occupies the top 16 bits and the parity the low 47.
+func Encode(data uint16) uint64 {
+	word := uint64(data) << parityBits
+	remainder := word
+	for i := N - 1; i >= parityBits; i-- {
+		if remainder&(1<<uint(i)) != 0 {
+			remainder ^= generator << uint(i-parityBits)
+		}
+	}
+	return word | remainder
+}
+
+// syndromes returns S1 to S2T of a received word, reporting whether all are zero.
+func syndromes(received uint64) ([2 * T]byte, bool) {
+	var s [2 * T]byte
+	clean := true
+	for j := 1; j <= 2*T; j++ {
+		var sum byte
+		for i := 0; i < N; i++ {
+			if received&(1<<uint(i)) != 0 {
+				sum ^= gfExp[(i*j)%N]
+			}
+		}
+		s[j-1] = sum
+		if sum != 0 {
+			clean = false
+		}
+	}
+	return s, clean
+}
+
+// berlekampMassey returns the error locator polynomial for the syndromes, lowest power first.
+func berlekampMassey(s [2 * T]byte) []byte {
+	locator := []byte{1}  // C(x)
+	previous := []byte{1} // B(x)
+	length := 0
+	shift := 1
+	lastDiscrepancy := byte(1)
+
+	for n := 0; n < 2*T; n++ {
+		discrepancy := s[n]
+		for i := 1; i <= length && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], s[n-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		// C(x) -= d/b * x^shift * B(x)
+		scale := gfMul(discrepancy, gfInv(lastDiscrepancy))
+		next := make([]byte, max(len(locator), len(previous)+shift))
+		copy(next, locator)
+		for i, b := range previous {
+			next[i+shift] ^= gfMul(scale, b)
+		}
+
+		if 2*length <= n {
+			previous = locator
+			length = n + 1 - length
+			lastDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	return locator[:length+1]
+}
+
+// chienSearch returns the bit positions whose inverse locators are roots of the error locator polynomial.
+func chienSearch(locator []byte) []int {
+	var positions []int
+	for i := 0; i < N; i++ {
+		// Evaluate at alpha^-i
+		var sum byte
+		for j, c := range locator {
+			if c != 0 {
+				sum ^= gfMul(c, gfExp[(N-(i*j)%N)%N])
+			}
+		}
+		if sum == 0 {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// Decode corrects a 63-bit codeword laid out as by Encode, returning the 16 data bits and the number of bit
+// errors corrected. It fails when the word has more errors than the code can correct.
+func Decode(received uint64) (uint16, int, error) {
+	received &= 1<<N - 1
+
+	s, clean := syndromes(received)
+	if clean {
+		return uint16(received >> parityBits), 0, nil
+	}
+
+	locator := berlekampMassey(s)
+	errs := len(locator) - 1
+	if errs > T {
+		return 0, 0, fmt.Errorf("uncorrectable BCH codeword, more than %d errors", T)
+	}
+
+	positions := chienSearch(locator)
+	if len(positions) != errs {
+		return 0, 0, fmt.Errorf("uncorrectable BCH codeword, more than %d errors", T)
+	}
+	for _, pos := range positions {
+		received ^= 1 << uint(pos)
+	}
+	return uint16(received >> parityBits), errs, nil
+}
+
+// CorrectBCHData corrects a BCH(63,16,23) codeword given as 8 bytes, the codeword in the top 63 bits as in a
+// P25 NID, returning the corrected 16 data bits and the number of bit errors corrected.
+func CorrectBCHData(data []byte) (uint16, int, error) {
+	if len(data) != 8 {
+		return 0, 0, errors.New("invalid data length, expected 8 bytes")
+	}
+
+	var word uint64
+	for _, b := range data {
+		word = word<<8 | uint64(b)
+	}
+	return Decode(word >> 1)
 }
@@ -4,145 +4,158 @@ package bptc
 import (
 	"errors" // Provides error handling utilities
 	"fmt"
-	"log" // Provides logging utilities for debugging
 	"testing"
 )
 
 // CorrectBPTCData decodes and corrects BPTC19696 data.
-// It expects a 33-byte input, processes it through several steps (bit extraction, deinterleaving, error correction, payload extraction),
-// and returns the final 12-byte payload or an error if the input is invalid.
+// It expects a 33-byte burst, extracts the 196 coded bits around the slot type and sync, deinterleaves them,
+// corrects the 15x13 matrix with the Hamming(13,9) columns and Hamming(15,11) rows, and returns the 12-byte
+// payload or an error if the input is invalid.
 func CorrectBPTCData(data []byte) ([]byte, error) {
 	// Check that the input data is exactly 33 bytes (as required by BPTC19696)
 	if len(data) != 33 {
-		log.Printf("CorrectBPTCData: Invalid data length: %d, expected 33 bytes", len(data))
 		return nil, errors.New("invalid data length, expected 33 bytes")
 	}
 
-	log.Printf("CorrectBPTCData: Starting with input data: %v", data)
-
-	// Step 1: Convert the 33 bytes into a slice of 196 bits (bools)
 	rawData := extractBinary(data)
-	log.Printf("CorrectBPTCData: Extracted raw binary data: %v", rawData)
-
-	// Step 2: Deinterleave the bits to restore their original order
 	deInterData := deInterleave(rawData)
-	log.Printf("CorrectBPTCData: Deinterleaved data: %v", deInterData)
-
-	// Step 3: Perform error correction (Hamming code) on the deinterleaved bits
-	correctedData := errorCheck(deInterData)
-	log.Printf("CorrectBPTCData: Corrected data after errorCheck: %v", correctedData)
-
-	// Step 4: Extract the 12-byte payload from the corrected bits
-	payload := extractPayload(correctedData)
-	log.Printf("CorrectBPTCData: Final extracted payload: %v", payload)
-
-	return payload, nil
+	errorCheck(deInterData)
+	return extractPayload(deInterData), nil
 }
 
-// extractBinary converts a 33-byte input into a 196-bit slice (bools),
-// extracting each bit in big-endian order (MSB first for each byte).
+// extractBinary returns the 196 coded bits of a burst, MSB first: the 98 bits before the slot type and
+// sync in the middle of the burst, followed by the 98 bits after them.
 func extractBinary(data []byte) []bool {
-	rawData := make([]bool, 196) // Output slice for 196 bits
-	bitIndex := 0                // Tracks the current bit position
-
-	log.Printf("extractBinary: Input data: %v", data)
-
-	// Iterate over each byte in the input
-	for byteIndex, byteVal := range data {
-		log.Printf("extractBinary: Processing byte %d (0x%X)", byteIndex, byteVal)
-		// For each byte, extract bits from MSB (bit 7) to LSB (bit 0)
-		for i := 7; i >= 0; i-- {
-			// Stop if we've filled all 196 bits
-			if bitIndex >= len(rawData) {
-				log.Printf("extractBinary: Warning - Excess bits ignored after index %d", bitIndex)
-				return rawData
-			}
-			// Extract the i-th bit and store as a bool
-			rawData[bitIndex] = (byteVal & (1 << i)) != 0
-			log.Printf("extractBinary: Bit %d: %v", bitIndex, rawData[bitIndex])
-			bitIndex++
+	rawData := make([]bool, 0, 196)
+	appendBits := func(b byte, from, to int) {
+		for i := from; i < to; i++ {
+			rawData = append(rawData, b&(0x80>>i) != 0)
 		}
 	}
 
-	log.Printf("extractBinary: Extracted binary data: %v", rawData)
+	for _, b := range data[:12] {
+		appendBits(b, 0, 8)
+	}
+	appendBits(data[12], 0, 2)
+	appendBits(data[20], 6, 8)
+	for _, b := range data[21:33] {
+		appendBits(b, 0, 8)
+	}
 	return rawData
 }
 
-// deInterleave reverses the interleaving process applied to the 196 bits.
-// It uses the formula (i * 181) % 196 to map each output bit to its original position.
+// deInterleave reverses the interleaving of the 196 bits: coded bit i was sent at position (i * 181) % 196.
 func deInterleave(rawData []bool) []bool {
-	deInterData := make([]bool, 196) // Output slice for deinterleaved bits
-	log.Printf("deInterleave: Input raw data: %v", rawData)
-	// For each output bit position, compute the original (interleaved) index
-	for i := 0; i < 196; i++ {
-		interleaveSequence := (i * 181) % 196        // Calculate the original bit position
-		deInterData[i] = rawData[interleaveSequence] // Assign the bit to its deinterleaved position
+	deInterData := make([]bool, 196)
+	for i := range deInterData {
+		deInterData[i] = rawData[(i*181)%196]
 	}
-	log.Printf("deInterleave: Deinterleaved data: %v", deInterData)
 	return deInterData
 }
 
-// errorCheck applies Hamming(8,4) error correction to the deinterleaved bits.
-// It processes each group of 8 bits, checks/corrects parity bits, and flips a bit if a single-bit error is detected.
-func errorCheck(deInterData []bool) []bool {
-	correctedData := make([]bool, len(deInterData)) // Output slice for corrected bits
-	copy(correctedData, deInterData)                // Start with a copy of the input
+// The 196 bits form a matrix of 13 rows of 15 bits after a leading unused bit R(3). The first 9 rows are
+// Hamming(15,11) codewords and the 15 columns Hamming(13,9) codewords.
+const (
+	matrixColumns = 15
+	matrixRows    = 13
+	dataRows      = 9
+)
+
+// hamming15113Parity lists the data bits covered by each of the four parity bits of a Hamming(15,11) row.
+var hamming15113Parity = [][]int{
+	{0, 1, 2, 3, 5, 7, 8},
+	{1, 2, 3, 4, 6, 8, 9},
+	{2, 3, 4, 5, 7, 9, 10},
+	{0, 1, 2, 4, 6, 7, 10},
+}
 
-	log.Printf("errorCheck: Input deinterleaved data: %v", deInterData)
+// hamming1393Parity lists the data bits covered by each of the four parity bits of a Hamming(13,9) column.
+var hamming1393Parity = [][]int{
+	{0, 1, 3, 5, 6},
+	{0, 1, 2, 4, 6, 7},
+	{0, 1, 2, 3, 5, 7, 8},
+	{0, 2, 4, 5, 8},
+}
+
+// hammingSyndrome computes the syndrome of a codeword whose parity bits follow its data bits.
+func hammingSyndrome(d []bool, parity [][]int) int {
+	dataBits := len(d) - len(parity)
+	syndrome := 0
+	for p, covered := range parity {
+		check := d[dataBits+p]
+		for _, i := range covered {
+			check = check != d[i]
+		}
+		if check {
+			syndrome |= 1 << p
+		}
+	}
+	return syndrome
+}
 
-	// Process each 8-bit block (Hamming(8,4) code)
-	for i := 0; i < len(correctedData); i += 8 {
-		// If less than 8 bits remain, skip (incomplete block)
-		if i+7 >= len(correctedData) {
-			log.Printf("errorCheck: Warning - Incomplete byte encountered at index %d", i)
-			break
+// correctHamming corrects a single bit error in a codeword in place, returning true when a bit was flipped.
+func correctHamming(d []bool, parity [][]int) bool {
+	if hammingSyndrome(d, parity) == 0 {
+		return false
+	}
+	for i := range d {
+		d[i] = !d[i]
+		if hammingSyndrome(d, parity) == 0 {
+			return true
 		}
+		d[i] = !d[i]
+	}
+	return false
+}
+
+// errorCheck corrects the deinterleaved bits in place, alternating between the columns and the data rows
+// while corrections are still being made, as a column fix may make a row correctable and vice versa.
+func errorCheck(deInterData []bool) {
+	column := make([]bool, matrixRows)
+	for pass := 0; pass < 5; pass++ {
+		fixing := false
 
-		// Parity bit positions for Hamming(8,4): 0, 1, 3, 7
-		parityBits := []int{0, 1, 3, 7}
-		parityError := 0 // Accumulates the error position (if any)
-		// For each parity bit, check if the parity matches
-		for _, p := range parityBits {
-			parity := false
-			for j := 0; j < 8; j++ {
-				// Only include bits that are not the parity bit itself and are covered by this parity
-				if j != p && ((j+1)&(p+1)) != 0 {
-					parity = parity != correctedData[i+j]
+		for c := 0; c < matrixColumns; c++ {
+			for r := range column {
+				column[r] = deInterData[1+c+r*matrixColumns]
+			}
+			if correctHamming(column, hamming1393Parity) {
+				for r := range column {
+					deInterData[1+c+r*matrixColumns] = column[r]
 				}
+				fixing = true
 			}
-			// If the calculated parity does not match the stored parity, increment error position
-			if parity != correctedData[i+p] {
-				parityError += (p + 1)
+		}
+
+		for r := 0; r < dataRows; r++ {
+			row := deInterData[1+r*matrixColumns : 1+(r+1)*matrixColumns]
+			if correctHamming(row, hamming15113Parity) {
+				fixing = true
 			}
 		}
 
-		// If a single-bit error is detected (error position in 1..8), flip the bit
-		if parityError > 0 && parityError <= 8 {
-			log.Printf("errorCheck: Correcting bit at index %d", i+parityError-1)
-			correctedData[i+parityError-1] = !correctedData[i+parityError-1]
+		if !fixing {
+			return
 		}
 	}
-
-	log.Printf("errorCheck: Corrected data: %v", correctedData)
-	return correctedData
 }
 
-// extractPayload converts the first 96 bits (12 bytes) of the corrected data into a byte slice.
-// It packs each group of 8 bits into a byte, with the most significant bit first.
+// extractPayload packs the 96 data bits of the corrected matrix into 12 bytes, MSB first. The first row
+// carries 8 data bits after the reserved bits R(2) to R(0), the other data rows 11 each.
 func extractPayload(correctedData []bool) []byte {
-	payload := make([]byte, 12) // Output slice for 12 bytes
-	// For each byte in the payload
-	for i := 0; i < 12; i++ {
-		// For each bit in the byte (MSB to LSB)
-		for j := 0; j < 8; j++ {
-			bitIndex := i*8 + j // Compute the bit index in the corrected data
-			// If the bit is set, set the corresponding bit in the output byte
-			if bitIndex < len(correctedData) && correctedData[bitIndex] {
-				payload[i] |= (1 << (7 - j)) // Set bit (MSB first)
-			}
+	bits := make([]bool, 0, 96)
+	bits = append(bits, correctedData[4:12]...)
+	for r := 1; r < dataRows; r++ {
+		start := 1 + r*matrixColumns
+		bits = append(bits, correctedData[start:start+11]...)
+	}
+
+	payload := make([]byte, 12)
+	for i, bit := range bits {
+		if bit {
+			payload[i/8] |= 0x80 >> (i % 8)
 		}
 	}
-	log.Printf("extractPayload: Extracted payload: %v", payload)
 	return payload
 }
 
@@ -3,6 +3,7 @@ package config
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -19,9 +20,13 @@ type Config struct {
 	Display   DisplayConfig
 	FilePaths FilePaths
 	AX25      AX25Config
+	APRS      APRSConfig
 	NXDN      NXDNConfig
+	P25       P25Config
 	Pocsag    PocsagConfig
 	YSF       YSFConfig
+	FM        FMConfig
+	CWId      CWIdConfig
 }
 
 // GeneralConfig stores general configuration parameters
@@ -67,20 +72,36 @@ type DMRConfig struct {
 	SelfOnly       bool `gorm:"column:self_only"`
 	EmbeddedLCOnly bool `gorm:"column:embedded_lc_only"`
 	DumpTAData     bool `gorm:"column:dump_ta_data"`
+	BeaconInterval int  `gorm:"column:beacon_interval"` // Seconds between periodic beacons
+	BeaconDuration int  `gorm:"column:beacon_duration"` // Seconds each beacon keeps the transmitter keyed
+	DMRID          int  `gorm:"column:dmr_id"`          // Hotspot DMR ID, used by SelfOnly
 }
 
 // DStarConfig stores D-Star protocol configuration
 // Add GORM tags for table and column mapping
 type DStarConfig struct {
-	Enable bool   `gorm:"column:enable"`
-	Module string `gorm:"column:module"`
+	Enable     bool   `gorm:"column:enable"`
+	Module     string `gorm:"column:module"`
+	StatusText string `gorm:"column:status_text"` // Slow data text sent with network traffic on RF
+
+	NetworkEnable  bool   `gorm:"column:network_enable"`  // Link to an ircDDBGateway
+	GatewayAddress string `gorm:"column:gateway_address"` // ircDDBGateway address
+	GatewayPort    int    `gorm:"column:gateway_port"`    // ircDDBGateway repeater port
+	LocalPort      int    `gorm:"column:local_port"`      // Local UDP port the gateway sends to
 }
 
 // M17Config stores M17 protocol configuration
 // Add GORM tags for table and column mapping
 type M17Config struct {
-	Enable bool   `gorm:"column:enable"`
-	CAN    string `gorm:"column:can"`
+	Enable bool `gorm:"column:enable"`
+	CAN    int  `gorm:"column:can"` // Channel access number RF traffic must use, 0 to 15
+
+	Callsign         string `gorm:"column:callsign"`          // Callsign, with optional module suffix, used to link to the reflector
+	NetworkEnable    bool   `gorm:"column:network_enable"`    // Link to an M17 reflector
+	ReflectorAddress string `gorm:"column:reflector_address"` // Reflector address
+	ReflectorPort    int    `gorm:"column:reflector_port"`    // Reflector port
+	ReflectorModule  string `gorm:"column:reflector_module"`  // Reflector module to link to, A to Z
+	LocalPort        int    `gorm:"column:local_port"`        // Local UDP port
 }
 
 // NetworkConfig stores network connection parameters
@@ -113,7 +134,25 @@ type FilePaths struct {
 // Add GORM tags for table and column mapping
 type AX25Config struct {
 	Enable bool   `gorm:"column:enable"`
-	Port   string `gorm:"column:port"`
+	Port   string `gorm:"column:port"` // KISS TCP server address or port, empty to disable
+}
+
+// APRSConfig stores APRS-IS gateway configuration
+// Add GORM tags for table and column mapping
+type APRSConfig struct {
+	Enable   bool   `gorm:"column:enable"`
+	Server   string `gorm:"column:server"`   // APRS-IS server address
+	Port     int    `gorm:"column:port"`     // APRS-IS server port
+	Callsign string `gorm:"column:callsign"` // Login and beacon callsign, empty for the general callsign
+	Passcode int    `gorm:"column:passcode"` // APRS-IS passcode, -1 to compute it from the callsign
+	Filter   string `gorm:"column:filter"`   // Server side filter sent at login, empty for none
+
+	Latitude       float64 `gorm:"column:latitude"`        // Hotspot position in degrees, north positive
+	Longitude      float64 `gorm:"column:longitude"`       // Hotspot position in degrees, east positive
+	Symbol         string  `gorm:"column:symbol"`          // Beacon symbol table and code
+	Description    string  `gorm:"column:description"`     // Beacon comment
+	BeaconInterval int     `gorm:"column:beacon_interval"` // Minutes between hotspot beacons, 0 to disable
+	RateLimit      int     `gorm:"column:rate_limit"`      // Minimum seconds between positions gated for one station
 }
 
 // NXDNConfig stores NXDN protocol configuration
@@ -121,6 +160,29 @@ type AX25Config struct {
 type NXDNConfig struct {
 	Enable bool   `gorm:"column:enable"`
 	Port   string `gorm:"column:port"`
+	RAN    int    `gorm:"column:ran"` // Radio access number, 0 to 63, RF traffic must use
+
+	Callsign       string `gorm:"column:callsign"`        // Callsign identifying this repeater in gateway protocol polls
+	NetworkEnable  bool   `gorm:"column:network_enable"`  // Link to an NXDNGateway
+	Protocol       string `gorm:"column:protocol"`        // Network protocol: "icom" or "gateway"
+	GatewayAddress string `gorm:"column:gateway_address"` // NXDNGateway address
+	GatewayPort    int    `gorm:"column:gateway_port"`    // NXDNGateway port
+	LocalPort      int    `gorm:"column:local_port"`      // Local UDP port
+	GatewayTG      int    `gorm:"column:gateway_tg"`      // Talkgroup announced in gateway protocol polls
+}
+
+// P25Config stores P25 protocol configuration
+// Add GORM tags for table and column mapping
+type P25Config struct {
+	Enable bool   `gorm:"column:enable"`
+	Port   string `gorm:"column:port"`
+	NAC    int    `gorm:"column:nac"` // Network access code RF traffic must use, 0xF7E accepts any
+
+	Callsign       string `gorm:"column:callsign"`        // Callsign identifying this repeater to the gateway
+	NetworkEnable  bool   `gorm:"column:network_enable"`  // Link to a P25Gateway
+	GatewayAddress string `gorm:"column:gateway_address"` // P25Gateway address
+	GatewayPort    int    `gorm:"column:gateway_port"`    // P25Gateway port
+	LocalPort      int    `gorm:"column:local_port"`      // Local UDP port
 }
 
 // PocsagConfig stores POCSAG protocol configuration
@@ -128,6 +190,12 @@ type NXDNConfig struct {
 type PocsagConfig struct {
 	Enable    bool `gorm:"column:enable"`
 	Frequency int  `gorm:"column:frequency"`
+
+	Callsign      string `gorm:"column:callsign"`       // Transmitter callsign registered with DAPNET
+	NetworkEnable bool   `gorm:"column:network_enable"` // Connect to a DAPNET core
+	DAPNETAddress string `gorm:"column:dapnet_address"` // DAPNET core address
+	DAPNETPort    int    `gorm:"column:dapnet_port"`    // DAPNET core port
+	AuthKey       string `gorm:"column:auth_key"`       // Transmitter authentication key
 }
 
 // YSFConfig stores YSF protocol configuration
@@ -135,6 +203,78 @@ type PocsagConfig struct {
 type YSFConfig struct {
 	Enable bool   `gorm:"column:enable"`
 	Port   string `gorm:"column:port"`
+
+	Callsign       string `gorm:"column:callsign"`        // Callsign identifying this repeater to the gateway
+	NetworkEnable  bool   `gorm:"column:network_enable"`  // Link to a YSFGateway or reflector
+	GatewayAddress string `gorm:"column:gateway_address"` // YSFGateway or reflector address
+	GatewayPort    int    `gorm:"column:gateway_port"`    // YSFGateway or reflector port
+	LocalPort      int    `gorm:"column:local_port"`      // Local UDP port
+
+	LowDeviation  bool `gorm:"column:low_deviation"`  // Transmit with the low deviation used by some radios
+	RemoteGateway bool `gorm:"column:remote_gateway"` // Present network traffic to radios as local, for use with a remote gateway
+	TXHang        int  `gorm:"column:tx_hang"`        // Seconds the modem keeps transmitting after network traffic ends
+	SelfOnly      bool `gorm:"column:self_only"`      // Only accept RF traffic from Callsign
+
+	DGIDRules []YSFDGIDRule `gorm:"-"` // Routing rules by DG-ID, loaded from the YSFDGIDRule table
+}
+
+// FMConfig stores analog FM mode configuration
+// Add GORM tags for table and column mapping
+type FMConfig struct {
+	Enable bool `gorm:"column:enable"`
+
+	Callsign          string `gorm:"column:callsign"`            // CW ID text, empty for the general callsign
+	CallsignSpeed     int    `gorm:"column:callsign_speed"`      // CW ID speed in words per minute
+	CallsignFrequency int    `gorm:"column:callsign_frequency"`  // CW ID tone in Hz
+	CallsignTime      int    `gorm:"column:callsign_time"`       // Minutes between CW IDs
+	CallsignHoldoff   int    `gorm:"column:callsign_holdoff"`    // Minutes the CW ID is held off after a transmission
+	CallsignHighLevel int    `gorm:"column:callsign_high_level"` // CW ID level in percent while idle
+	CallsignLowLevel  int    `gorm:"column:callsign_low_level"`  // CW ID level in percent over a transmission
+	CallsignAtStart   bool   `gorm:"column:callsign_at_start"`   // Send the CW ID when the repeater opens
+	CallsignAtEnd     bool   `gorm:"column:callsign_at_end"`     // Send the CW ID when the repeater closes
+	CallsignAtLatch   bool   `gorm:"column:callsign_at_latch"`   // Send the CW ID when a transmission latches the repeater
+
+	RFAck        string `gorm:"column:rf_ack"`        // Courtesy tone text sent in CW at the end of a transmission
+	AckSpeed     int    `gorm:"column:ack_speed"`     // Courtesy tone speed in words per minute
+	AckFrequency int    `gorm:"column:ack_frequency"` // Courtesy tone in Hz
+	AckMinTime   int    `gorm:"column:ack_min_time"`  // Seconds a transmission must last to get a courtesy tone
+	AckDelay     int    `gorm:"column:ack_delay"`     // Milliseconds between the end of a transmission and the courtesy tone
+	AckLevel     int    `gorm:"column:ack_level"`     // Courtesy tone level in percent
+
+	Timeout            int     `gorm:"column:timeout"`              // Seconds a transmission may last, 0 for no limit
+	TimeoutLevel       int     `gorm:"column:timeout_level"`        // Timeout tone level in percent
+	CTCSSFrequency     float64 `gorm:"column:ctcss_frequency"`      // CTCSS tone in Hz, required to open and sent on transmit
+	CTCSSHighThreshold int     `gorm:"column:ctcss_high_threshold"` // CTCSS level opening the repeater
+	CTCSSLowThreshold  int     `gorm:"column:ctcss_low_threshold"`  // CTCSS level closing the repeater
+	CTCSSLevel         int     `gorm:"column:ctcss_level"`          // Transmitted CTCSS level in percent
+	KerchunkTime       int     `gorm:"column:kerchunk_time"`        // Seconds a transmission must last to open the repeater
+	HangTime           int     `gorm:"column:hang_time"`            // Seconds the repeater stays open after a transmission
+	UseCOS             bool    `gorm:"column:use_cos"`              // Open on the carrier detect input as well as CTCSS
+	COSInvert          bool    `gorm:"column:cos_invert"`           // Carrier detect input is active low
+	RFAudioBoost       int     `gorm:"column:rf_audio_boost"`       // Gain applied to RF audio
+	MaxDevLevel        int     `gorm:"column:max_dev_level"`        // Maximum deviation in percent
+
+	NetworkEnable  bool   `gorm:"column:network_enable"`  // Bridge audio to an FM gateway over UDP
+	GatewayAddress string `gorm:"column:gateway_address"` // FM gateway address
+	GatewayPort    int    `gorm:"column:gateway_port"`    // FM gateway port
+	LocalPort      int    `gorm:"column:local_port"`      // Local UDP port
+}
+
+// CWIdConfig stores Morse identifier configuration
+// Add GORM tags for table and column mapping
+type CWIdConfig struct {
+	Enable            bool `gorm:"column:enable"`
+	Time              int  `gorm:"column:time"`               // Minutes between IDs, 0 for none on a schedule
+	Speed             int  `gorm:"column:speed"`              // Words per minute
+	AfterTransmission bool `gorm:"column:after_transmission"` // Also identify at the end of each transmission
+}
+
+// YSFDGIDRule stores how YSF traffic on one DG-ID is routed
+// Add GORM tags for table and column mapping
+type YSFDGIDRule struct {
+	DGID  int    `gorm:"column:dgid"`  // DG-ID the rule applies to, 0 to 99
+	Route string `gorm:"column:route"` // "network" to forward, "local" to keep on RF and answer Wires-X locally, "block" to reject
+	Name  string `gorm:"column:name"`  // Room name shown in local Wires-X room lists
 }
 
 // LoadConfig loads configuration from the specified SQLite database file.
@@ -199,11 +339,21 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to load AX.25 config: %w", err)
 	}
 
+	// Load APRSConfig
+	if err := loadAPRSConfig(db, &config.APRS); err != nil {
+		return nil, fmt.Errorf("failed to load APRS config: %w", err)
+	}
+
 	// Load NXDNConfig
 	if err := loadNXDNConfig(db, &config.NXDN); err != nil {
 		return nil, fmt.Errorf("failed to load NXDN config: %w", err)
 	}
 
+	// Load P25Config
+	if err := loadP25Config(db, &config.P25); err != nil {
+		return nil, fmt.Errorf("failed to load P25 config: %w", err)
+	}
+
 	// Load PocsagConfig
 	if err := loadPocsagConfig(db, &config.Pocsag); err != nil {
 		return nil, fmt.Errorf("failed to load POCSAG config: %w", err)
@@ -214,6 +364,16 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to load YSF config: %w", err)
 	}
 
+	// Load FMConfig
+	if err := loadFMConfig(db, &config.FM); err != nil {
+		return nil, fmt.Errorf("failed to load FM config: %w", err)
+	}
+
+	// Load CWIdConfig
+	if err := loadCWIdConfig(db, &config.CWId); err != nil {
+		return nil, fmt.Errorf("failed to load CW ID config: %w", err)
+	}
+
 	return config, nil
 }
 
@@ -281,20 +441,49 @@ func loadModemConfig(db *sql.DB, modem *ModemConfig) error {
 
 // loadDMRConfig loads the DMR configuration section from the database.
 func loadDMRConfig(db *sql.DB, dmr *DMRConfig) error {
-	row := db.QueryRow(`SELECT Enable, Beacons, ColorCode, SelfOnly, EmbeddedLCOnly, DumpTAData FROM DMR`)
-	return row.Scan(&dmr.Enable, &dmr.Beacons, &dmr.ColorCode, &dmr.SelfOnly, &dmr.EmbeddedLCOnly, &dmr.DumpTAData)
+	row := db.QueryRow(`SELECT Enable, Beacons, ColorCode, SelfOnly, EmbeddedLCOnly, DumpTAData, BeaconInterval, BeaconDuration, DMRID FROM DMR`)
+	return row.Scan(&dmr.Enable, &dmr.Beacons, &dmr.ColorCode, &dmr.SelfOnly, &dmr.EmbeddedLCOnly, &dmr.DumpTAData, &dmr.BeaconInterval, &dmr.BeaconDuration, &dmr.DMRID)
 }
 
 // loadDStarConfig loads the D-Star configuration section from the database.
 func loadDStarConfig(db *sql.DB, dstar *DStarConfig) error {
-	row := db.QueryRow(`SELECT Enable, Module FROM DStar`)
-	return row.Scan(&dstar.Enable, &dstar.Module)
+	row := db.QueryRow(`SELECT Enable, Module, StatusText, NetworkEnable, GatewayAddress, GatewayPort, LocalPort FROM DStar`)
+	return row.Scan(&dstar.Enable, &dstar.Module, &dstar.StatusText,
+		&dstar.NetworkEnable, &dstar.GatewayAddress, &dstar.GatewayPort, &dstar.LocalPort)
 }
 
 // loadM17Config loads the M17 configuration section from the database.
 func loadM17Config(db *sql.DB, m17 *M17Config) error {
-	row := db.QueryRow(`SELECT Enable, CAN FROM M17`)
-	return row.Scan(&m17.Enable, &m17.CAN)
+	row := db.QueryRow(`SELECT Enable, CAN, Callsign, NetworkEnable, ReflectorAddress, ReflectorPort, ReflectorModule, LocalPort FROM M17`)
+	var can sql.NullString
+	if err := row.Scan(&m17.Enable, &can,
+		&m17.Callsign, &m17.NetworkEnable, &m17.ReflectorAddress, &m17.ReflectorPort, &m17.ReflectorModule, &m17.LocalPort); err != nil {
+		return err
+	}
+
+	value, err := parseCAN(can.String)
+	if err != nil {
+		return err
+	}
+	m17.CAN = value
+	return nil
+}
+
+// parseCAN parses an M17 channel access number. Older databases stored it as text holding a single hex
+// digit, such as the "A" default, so those are accepted alongside the decimal 0 to 15. Empty means 0.
+func parseCAN(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	if can, err := strconv.Atoi(text); err == nil && can >= 0 && can <= 15 {
+		return can, nil
+	}
+	if len(text) == 1 {
+		if can, err := strconv.ParseUint(text, 16, 8); err == nil {
+			return int(can), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid M17 CAN %q, expected 0 to 15", text)
 }
 
 // loadNetworkConfig loads the Network configuration section from the database.
@@ -321,22 +510,76 @@ func loadAX25Config(db *sql.DB, ax25 *AX25Config) error {
 	return row.Scan(&ax25.Enable, &ax25.Port)
 }
 
+// loadAPRSConfig loads the APRS-IS configuration section from the database.
+func loadAPRSConfig(db *sql.DB, aprs *APRSConfig) error {
+	row := db.QueryRow(`SELECT Enable, Server, Port, Callsign, Passcode, Filter, Latitude, Longitude, Symbol, Description, BeaconInterval, RateLimit FROM APRS`)
+	return row.Scan(&aprs.Enable, &aprs.Server, &aprs.Port, &aprs.Callsign, &aprs.Passcode, &aprs.Filter,
+		&aprs.Latitude, &aprs.Longitude, &aprs.Symbol, &aprs.Description, &aprs.BeaconInterval, &aprs.RateLimit)
+}
+
 // loadNXDNConfig loads the NXDN configuration section from the database.
 func loadNXDNConfig(db *sql.DB, nxdn *NXDNConfig) error {
-	row := db.QueryRow(`SELECT Enable, Port FROM NXDN`)
-	return row.Scan(&nxdn.Enable, &nxdn.Port)
+	row := db.QueryRow(`SELECT Enable, Port, RAN, Callsign, NetworkEnable, Protocol, GatewayAddress, GatewayPort, LocalPort, GatewayTG FROM NXDN`)
+	return row.Scan(&nxdn.Enable, &nxdn.Port, &nxdn.RAN,
+		&nxdn.Callsign, &nxdn.NetworkEnable, &nxdn.Protocol, &nxdn.GatewayAddress, &nxdn.GatewayPort, &nxdn.LocalPort, &nxdn.GatewayTG)
+}
+
+// loadP25Config loads the P25 configuration section from the database.
+func loadP25Config(db *sql.DB, p25 *P25Config) error {
+	row := db.QueryRow(`SELECT Enable, Port, NAC, Callsign, NetworkEnable, GatewayAddress, GatewayPort, LocalPort FROM P25`)
+	return row.Scan(&p25.Enable, &p25.Port, &p25.NAC,
+		&p25.Callsign, &p25.NetworkEnable, &p25.GatewayAddress, &p25.GatewayPort, &p25.LocalPort)
 }
 
 // loadPocsagConfig loads the POCSAG configuration section from the database.
 func loadPocsagConfig(db *sql.DB, pocsag *PocsagConfig) error {
-	row := db.QueryRow(`SELECT Enable, Frequency FROM Pocsag`)
-	return row.Scan(&pocsag.Enable, &pocsag.Frequency)
+	row := db.QueryRow(`SELECT Enable, Frequency, Callsign, NetworkEnable, DAPNETAddress, DAPNETPort, AuthKey FROM Pocsag`)
+	return row.Scan(&pocsag.Enable, &pocsag.Frequency,
+		&pocsag.Callsign, &pocsag.NetworkEnable, &pocsag.DAPNETAddress, &pocsag.DAPNETPort, &pocsag.AuthKey)
 }
 
 // loadYSFConfig loads the YSF configuration section from the database.
 func loadYSFConfig(db *sql.DB, ysf *YSFConfig) error {
-	row := db.QueryRow(`SELECT Enable, Port FROM YSF`)
-	return row.Scan(&ysf.Enable, &ysf.Port)
+	row := db.QueryRow(`SELECT Enable, Port, Callsign, NetworkEnable, GatewayAddress, GatewayPort, LocalPort, LowDeviation, RemoteGateway, TXHang, SelfOnly FROM YSF`)
+	if err := row.Scan(&ysf.Enable, &ysf.Port,
+		&ysf.Callsign, &ysf.NetworkEnable, &ysf.GatewayAddress, &ysf.GatewayPort, &ysf.LocalPort,
+		&ysf.LowDeviation, &ysf.RemoteGateway, &ysf.TXHang, &ysf.SelfOnly); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT DGID, Route, Name FROM YSFDGIDRule ORDER BY DGID`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ysf.DGIDRules = nil
+	for rows.Next() {
+		var rule YSFDGIDRule
+		if err := rows.Scan(&rule.DGID, &rule.Route, &rule.Name); err != nil {
+			return err
+		}
+		ysf.DGIDRules = append(ysf.DGIDRules, rule)
+	}
+	return rows.Err()
+}
+
+// loadFMConfig loads the FM configuration section from the database.
+func loadFMConfig(db *sql.DB, fm *FMConfig) error {
+	row := db.QueryRow(`SELECT Enable, Callsign, CallsignSpeed, CallsignFrequency, CallsignTime, CallsignHoldoff, CallsignHighLevel, CallsignLowLevel, CallsignAtStart, CallsignAtEnd, CallsignAtLatch, RFAck, AckSpeed, AckFrequency, AckMinTime, AckDelay, AckLevel, Timeout, TimeoutLevel, CTCSSFrequency, CTCSSHighThreshold, CTCSSLowThreshold, CTCSSLevel, KerchunkTime, HangTime, UseCOS, COSInvert, RFAudioBoost, MaxDevLevel, NetworkEnable, GatewayAddress, GatewayPort, LocalPort FROM FM`)
+	return row.Scan(&fm.Enable,
+		&fm.Callsign, &fm.CallsignSpeed, &fm.CallsignFrequency, &fm.CallsignTime, &fm.CallsignHoldoff,
+		&fm.CallsignHighLevel, &fm.CallsignLowLevel, &fm.CallsignAtStart, &fm.CallsignAtEnd, &fm.CallsignAtLatch,
+		&fm.RFAck, &fm.AckSpeed, &fm.AckFrequency, &fm.AckMinTime, &fm.AckDelay, &fm.AckLevel,
+		&fm.Timeout, &fm.TimeoutLevel, &fm.CTCSSFrequency, &fm.CTCSSHighThreshold, &fm.CTCSSLowThreshold, &fm.CTCSSLevel,
+		&fm.KerchunkTime, &fm.HangTime, &fm.UseCOS, &fm.COSInvert, &fm.RFAudioBoost, &fm.MaxDevLevel,
+		&fm.NetworkEnable, &fm.GatewayAddress, &fm.GatewayPort, &fm.LocalPort)
+}
+
+// loadCWIdConfig loads the CW ID configuration section from the database.
+func loadCWIdConfig(db *sql.DB, cwid *CWIdConfig) error {
+	row := db.QueryRow(`SELECT Enable, Time, Speed, AfterTransmission FROM CWId`)
+	return row.Scan(&cwid.Enable, &cwid.Time, &cwid.Speed, &cwid.AfterTransmission)
 }
 
 func (GeneralConfig) TableName() string {
@@ -359,10 +602,18 @@ func (AX25Config) TableName() string {
 	return "AX25Config"
 }
 
+func (APRSConfig) TableName() string {
+	return "APRSConfig"
+}
+
 func (NXDNConfig) TableName() string {
 	return "NXDNConfig"
 }
 
+func (P25Config) TableName() string {
+	return "P25Config"
+}
+
 func (PocsagConfig) TableName() string {
 	return "PocsagConfig"
 }
@@ -371,6 +622,18 @@ func (YSFConfig) TableName() string {
 	return "YSFConfig"
 }
 
+func (YSFDGIDRule) TableName() string {
+	return "YSFDGIDRule"
+}
+
+func (FMConfig) TableName() string {
+	return "FMConfig"
+}
+
+func (CWIdConfig) TableName() string {
+	return "CWIdConfig"
+}
+
 // Ensure all required structs are present
-// GeneralConfig, DMRConfig, DStarConfig, M17Config, AX25Config, NXDNConfig, PocsagConfig, YSFConfig are already defined.
+// GeneralConfig, DMRConfig, DStarConfig, M17Config, AX25Config, APRSConfig, NXDNConfig, P25Config, PocsagConfig, YSFConfig, YSFDGIDRule, FMConfig, CWIdConfig are already defined.
 // No additional structs are missing.
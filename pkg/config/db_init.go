@@ -21,9 +21,14 @@ func InitializeDatabase(dbPath string) error {
 		&DStarConfig{},
 		&M17Config{},
 		&AX25Config{},
+		&APRSConfig{},
 		&NXDNConfig{},
+		&P25Config{},
 		&PocsagConfig{},
 		&YSFConfig{},
+		&YSFDGIDRule{},
+		&FMConfig{},
+		&CWIdConfig{},
 	}
 
 	// Drop the GeneralConfig table if it exists to ensure schema consistency
@@ -42,13 +47,20 @@ func InitializeDatabase(dbPath string) error {
 	fmt.Println("Inserting default values...") // Log default value insertion
 	defaults := map[string]interface{}{
 		"GeneralConfig": GeneralConfig{Callsign: "NOCALL", Timeout: 60, Duplex: false},
-		"DMRConfig":     DMRConfig{Enable: true, ColorCode: 1},
-		"DStarConfig":   DStarConfig{Enable: true, Module: "C"},
-		"M17Config":     M17Config{Enable: true, CAN: "A"},
-		"AX25Config":    AX25Config{Enable: false, Port: ""},
-		"NXDNConfig":    NXDNConfig{Enable: false, Port: ""},
-		"PocsagConfig":  PocsagConfig{Enable: false, Frequency: 0},
-		"YSFConfig":     YSFConfig{Enable: true, Port: ""},
+		"DMRConfig":     DMRConfig{Enable: true, ColorCode: 1, BeaconInterval: 60, BeaconDuration: 3},
+		"DStarConfig":   DStarConfig{Enable: true, Module: "C", GatewayAddress: "127.0.0.1", GatewayPort: 20010, LocalPort: 20011},
+		"M17Config":     M17Config{Enable: true, CAN: 0, Callsign: "NOCALL", ReflectorPort: 17000, ReflectorModule: "A", LocalPort: 17011},
+		"AX25Config":    AX25Config{Enable: false, Port: "8001"},
+		"APRSConfig":    APRSConfig{Enable: false, Server: "rotate.aprs2.net", Port: 14580, Passcode: -1, Symbol: "/r", Description: "MMDVM Ghost hotspot", BeaconInterval: 30, RateLimit: 60},
+		"NXDNConfig":    NXDNConfig{Enable: false, Port: "", RAN: 1, Callsign: "NOCALL", Protocol: "icom", GatewayAddress: "127.0.0.1", GatewayPort: 14020, LocalPort: 14021},
+		"P25Config":     P25Config{Enable: false, Port: "", NAC: 0x293, Callsign: "NOCALL", GatewayAddress: "127.0.0.1", GatewayPort: 42020, LocalPort: 32010},
+		"PocsagConfig":  PocsagConfig{Enable: false, Frequency: 0, Callsign: "NOCALL", DAPNETAddress: "dapnet.afu.rwth-aachen.de", DAPNETPort: 43434},
+		"YSFConfig":     YSFConfig{Enable: true, Port: "", Callsign: "NOCALL", GatewayAddress: "127.0.0.1", GatewayPort: 4200, LocalPort: 3200, TXHang: 4},
+		"CWIdConfig":    CWIdConfig{Enable: false, Time: 10, Speed: 20},
+		"FMConfig": FMConfig{Enable: false, CallsignSpeed: 20, CallsignFrequency: 1000, CallsignTime: 10, CallsignHighLevel: 50, CallsignLowLevel: 20,
+			CallsignAtStart: true, CallsignAtEnd: true, RFAck: "K", AckSpeed: 20, AckFrequency: 1750, AckMinTime: 4, AckDelay: 1000, AckLevel: 50,
+			Timeout: 180, TimeoutLevel: 80, CTCSSFrequency: 88.4, CTCSSHighThreshold: 30, CTCSSLowThreshold: 20, CTCSSLevel: 20, HangTime: 7,
+			UseCOS: true, RFAudioBoost: 1, MaxDevLevel: 90, GatewayAddress: "127.0.0.1", GatewayPort: 4810, LocalPort: 3810},
 	}
 
 	for tableName, defaultValue := range defaults {
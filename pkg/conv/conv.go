@@ -0,0 +1,73 @@
+// Package conv provides the K=5 rate 1/2 convolutional code shared by M17, NXDN and System Fusion,
+// G1 = 1 + D^3 + D^4 and G2 = 1 + D + D^2 + D^4. Bits are held one per byte.
+package conv
+
+// Encode encodes bits with the code. The input must end with the four zero tail bits that return the
+// encoder to state 0.
+func Encode(in []byte) []byte {
+	out := make([]byte, 0, len(in)*2)
+	var d1, d2, d3, d4 byte
+	for _, d := range in {
+		out = append(out, d^d3^d4, d^d1^d2^d4)
+		d4, d3, d2, d1 = d3, d2, d1, d
+	}
+	return out
+}
+
+// Decode decodes the code with hard decisions, ignoring the coded bits marked in erased, which may be nil
+// when nothing was punctured. It returns the decoded bits, including the tail, and the path error count.
+func Decode(in []byte, erased []bool) ([]byte, int) {
+	steps := len(in) / 2
+	const inf = 1 << 30
+
+	// The state holds the previous four input bits as d1<<3 | d2<<2 | d3<<1 | d4
+	var metrics [16]int
+	for i := 1; i < 16; i++ {
+		metrics[i] = inf
+	}
+	history := make([][16]byte, steps)
+
+	for n := 0; n < steps; n++ {
+		var next [16]int
+		for i := range next {
+			next[i] = inf
+		}
+		var from [16]byte
+		for state := 0; state < 16; state++ {
+			if metrics[state] >= inf {
+				continue
+			}
+			d1, d2, d3, d4 := byte(state>>3&1), byte(state>>2&1), byte(state>>1&1), byte(state&1)
+			for d := byte(0); d < 2; d++ {
+				cost := metrics[state]
+				if !isErased(erased, 2*n) && d^d3^d4 != in[2*n] {
+					cost++
+				}
+				if !isErased(erased, 2*n+1) && d^d1^d2^d4 != in[2*n+1] {
+					cost++
+				}
+				ns := int(d<<3 | d1<<2 | d2<<1 | d3)
+				if cost < next[ns] {
+					next[ns] = cost
+					from[ns] = byte(state)
+				}
+			}
+		}
+		metrics = next
+		history[n] = from
+	}
+
+	// The tail bits leave the encoder in state 0
+	state := 0
+	out := make([]byte, steps)
+	for n := steps - 1; n >= 0; n-- {
+		out[n] = byte(state >> 3)
+		state = int(history[n][state])
+	}
+	return out, metrics[0]
+}
+
+// isErased reports whether coded bit i is marked as erased.
+func isErased(erased []bool, i int) bool {
+	return erased != nil && erased[i]
+}
@@ -0,0 +1,79 @@
+package cwid
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
+)
+
+// identifier sends the CW ID, nil when disabled.
+var identifier *Identifier
+
+// Transmissions in progress, counted from the protocol handlers' events.
+var (
+	activeMu sync.Mutex
+	active   int
+)
+
+// Init initializes the CW identifier with the given configuration, identifying with the general callsign.
+func Init(config config.CWIdConfig, general config.GeneralConfig) {
+	if identifier != nil {
+		identifier.Stop()
+		identifier = nil
+	}
+	if !config.Enable {
+		return
+	}
+
+	id, err := NewIdentifier(general.Callsign, time.Duration(config.Time)*time.Minute)
+	if err != nil {
+		log.Printf("CWID: %v", err)
+		return
+	}
+	id.AfterTransmission = config.AfterTransmission
+	id.Busy = busy
+	if modem.IsOpen() {
+		id.Output = func(data []byte) {
+			if err := modem.WriteFrame(data); err != nil {
+				log.Printf("CWID: Unable to write to the modem: %v", err)
+			}
+		}
+	}
+	id.Start()
+	identifier = id
+	fmt.Printf("CW identifier initialized with Callsign: %s\n", general.Callsign)
+}
+
+// Event takes a transmission start or end notification from a protocol handler, holding IDs off while
+// transmissions are in progress and scheduling one after each transmission.
+func Event(event string) {
+	activeMu.Lock()
+	switch event {
+	case "rf_start", "net_start":
+		active++
+	case "rf_end", "net_end":
+		active = max(active-1, 0)
+	}
+	activeMu.Unlock()
+
+	if (event == "rf_end" || event == "net_end") && identifier != nil {
+		identifier.TransmissionEnded()
+	}
+}
+
+// Active reports whether a protocol handler has a transmission in progress, as counted from the events
+// passed to Event.
+func Active() bool {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	return active > 0
+}
+
+// busy reports whether a transmission is in progress or the modem is transmitting.
+func busy() bool {
+	return Active() || modem.Transmitting()
+}
@@ -0,0 +1,130 @@
+// Package cwid provides the Morse code identifier, including the ID schedule.
+package cwid
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
+)
+
+// pollInterval is how often a pending ID is retried while the modem is busy.
+const pollInterval = 100 * time.Millisecond
+
+// sendHoldoff is how long after sending an ID no other is sent, giving the modem time to report that it is
+// transmitting it.
+const sendHoldoff = time.Second
+
+// Identifier sends the station callsign in Morse every Interval and, when AfterTransmission is set, at the
+// end of each transmission. An ID that falls due while the modem is busy is sent once it is idle. The modem
+// keys the ID at its own speed.
+type Identifier struct {
+	Interval          time.Duration     // Time between periodic IDs (0 disables periodic IDs)
+	AfterTransmission bool              // Also send an ID when a transmission ends
+	Busy              func() bool       // Reports whether the modem is busy, may be nil
+	Output            func(data []byte) // Receives the SEND_CWID modem frame
+
+	callsign string
+	frame    []byte
+
+	mu        sync.Mutex
+	pending   bool
+	lastID    time.Time
+	busyUntil time.Time
+	stop      chan struct{}
+}
+
+// NewIdentifier creates a new Identifier for the given callsign.
+func NewIdentifier(callsign string, interval time.Duration) (*Identifier, error) {
+	if err := Check(callsign); err != nil {
+		return nil, fmt.Errorf("invalid CW ID: %w", err)
+	}
+	frame, err := modem.CWIdFrame(callsign)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CW ID: %w", err)
+	}
+
+	return &Identifier{
+		Interval: interval,
+		callsign: callsign,
+		frame:    frame,
+	}, nil
+}
+
+// Start launches the ID timer. Calling Start on a running identifier has no effect.
+func (i *Identifier) Start() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.stop != nil {
+		return
+	}
+	i.stop = make(chan struct{})
+	i.lastID = time.Now()
+	go i.run(i.stop)
+	log.Printf("CWID: Started for %s every %v", i.callsign, i.Interval)
+}
+
+// Stop halts the ID timer.
+func (i *Identifier) Stop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.stop == nil {
+		return
+	}
+	close(i.stop)
+	i.stop = nil
+	log.Printf("CWID: Stopped")
+}
+
+// TransmissionEnded schedules an ID after a transmission when AfterTransmission is set.
+func (i *Identifier) TransmissionEnded() {
+	if !i.AfterTransmission {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.pending = true
+}
+
+// run checks the schedule until stopped.
+func (i *Identifier) run(stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			i.Clock(now)
+		}
+	}
+}
+
+// Clock sends the ID if it is due and the modem is free.
+func (i *Identifier) Clock(now time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.Interval > 0 && now.Sub(i.lastID) >= i.Interval {
+		i.pending = true
+	}
+	if !i.pending || now.Before(i.busyUntil) {
+		return
+	}
+	if i.Busy != nil && i.Busy() {
+		return
+	}
+
+	log.Printf("CWID: Sending %s", i.callsign)
+	if i.Output != nil {
+		i.Output(append([]byte{}, i.frame...))
+	}
+	i.pending = false
+	i.lastID = now
+	i.busyUntil = now.Add(sendHoldoff)
+}
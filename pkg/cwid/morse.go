@@ -0,0 +1,32 @@
+// Package cwid provides the Morse code identifier, including the Morse character set.
+package cwid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// morse holds the dot and dash pattern of each supported character.
+var morse = map[rune]string{
+	'A': ".-", 'B': "-...", 'C': "-.-.", 'D': "-..", 'E': ".", 'F': "..-.", 'G': "--.", 'H': "....",
+	'I': "..", 'J': ".---", 'K': "-.-", 'L': ".-..", 'M': "--", 'N': "-.", 'O': "---", 'P': ".--.",
+	'Q': "--.-", 'R': ".-.", 'S': "...", 'T': "-", 'U': "..-", 'V': "...-", 'W': ".--", 'X': "-..-",
+	'Y': "-.--", 'Z': "--..",
+	'0': "-----", '1': ".----", '2': "..---", '3': "...--", '4': "....-",
+	'5': ".....", '6': "-....", '7': "--...", '8': "---..", '9': "----.",
+	'/': "-..-.", '-': "-....-", '.': ".-.-.-", ',': "--..--", '?': "..--..", '=': "-...-",
+}
+
+// Check reports an error if the text is empty or has a character with no Morse code.
+func Check(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("empty CW text")
+	}
+	for _, char := range strings.ToUpper(text) {
+		if _, ok := morse[char]; !ok && char != ' ' {
+			return fmt.Errorf("character %q has no Morse code", char)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package dmr provides DMR protocol logic, including the idle beacon transmitter.
+package dmr
+
+import (
+	"log"  // For logging debug/info messages
+	"sync" // For guarding the beacon lifecycle
+	"time" // For beacon interval and duration timing
+)
+
+// beaconBurstPeriod is the length of a single DMR timeslot burst.
+const beaconBurstPeriod = 30 * time.Millisecond
+
+// IdleBurst is the 33-byte idle burst (slot type DtIdle) transmitted while a beacon is keyed.
+// The slot type is left blank; the beacon fills in its color code before transmission.
+// The modem firmware prepends the CACH on the way out.
+var IdleBurst = []byte{
+	0x53, 0xC2, 0x5E, 0xAB, 0xA8, 0x67, 0x1D, 0xC7, 0x38, 0x3B, 0xD9,
+	0x36, 0x00, 0x0D, 0xFF, 0x57, 0xD7, 0x5D, 0xF5, 0xD0, 0x03, 0xF6,
+	0xE4, 0x65, 0x17, 0x1B, 0x48, 0xCA, 0x6D, 0x4F, 0xC6, 0x10, 0xB4,
+}
+
+// Beacon keys the transmitter with idle bursts to wake up the repeater.
+// Beacons are sent every Interval, or on demand from the network via Trigger,
+// and last for Duration. A beacon is inhibited while either slot is active.
+type Beacon struct {
+	Interval time.Duration               // Time between periodic beacons (0 disables periodic beacons)
+	Duration time.Duration               // How long each beacon keeps the transmitter keyed
+	Slots    []*DMRSlot                  // Slots checked for activity before and during a beacon
+	Output   func(slotNo uint, b []byte) // Receives each idle burst for transmission, may be nil
+	Key      func(on bool)               // Keys the transmitter before a beacon and releases it after, may be nil
+
+	idle    []byte // Idle burst with the configured color code
+	mu      sync.Mutex
+	trigger chan struct{}
+	stop    chan struct{}
+	running bool
+}
+
+// NewBeacon creates a new Beacon for the given slots.
+func NewBeacon(colorCode uint8, interval, duration time.Duration, slots []*DMRSlot, output func(slotNo uint, b []byte)) *Beacon {
+	idle := make([]byte, len(IdleBurst))
+	copy(idle, IdleBurst)
+	slotType := &SlotType{ColorCode: colorCode, DataType: DtIdle}
+	slotType.GetData(idle)
+
+	return &Beacon{
+		Interval: interval,
+		Duration: duration,
+		Slots:    slots,
+		Output:   output,
+		idle:     idle,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Start launches the beacon timer. Calling Start on a running beacon has no effect.
+func (b *Beacon) Start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.running = true
+	go b.run(b.stop)
+	log.Printf("Beacon: Started with interval %v and duration %v", b.Interval, b.Duration)
+}
+
+// Stop halts the beacon timer and any beacon currently being transmitted.
+func (b *Beacon) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.running {
+		return
+	}
+	close(b.stop)
+	b.running = false
+	log.Printf("Beacon: Stopped")
+}
+
+// Trigger requests a beacon from the network. Requests made while a beacon is already pending are merged.
+func (b *Beacon) Trigger() {
+	select {
+	case b.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// run waits for the periodic timer or a network trigger and transmits beacons until stopped.
+func (b *Beacon) run(stop chan struct{}) {
+	var tick <-chan time.Time
+	if b.Interval > 0 {
+		ticker := time.NewTicker(b.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick:
+			b.transmit(stop, "periodic")
+		case <-b.trigger:
+			b.transmit(stop, "network")
+		}
+	}
+}
+
+// transmit sends idle bursts alternately on both slots for the beacon duration.
+func (b *Beacon) transmit(stop chan struct{}, reason string) {
+	if b.slotsActive() {
+		log.Printf("Beacon: %s beacon inhibited, slot active", reason)
+		return
+	}
+
+	log.Printf("Beacon: Transmitting %s beacon", reason)
+	if b.Key != nil {
+		b.Key(true)
+		defer b.Key(false)
+	}
+
+	ticker := time.NewTicker(beaconBurstPeriod)
+	defer ticker.Stop()
+	deadline := time.After(b.Duration)
+
+	slotNo := uint(1)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-deadline:
+			log.Printf("Beacon: %s beacon complete", reason)
+			return
+		case <-ticker.C:
+			if b.slotsActive() {
+				log.Printf("Beacon: %s beacon aborted, slot active", reason)
+				return
+			}
+			if b.Output != nil {
+				burst := make([]byte, len(b.idle))
+				copy(burst, b.idle)
+				b.Output(slotNo, burst)
+			}
+			slotNo = 3 - slotNo // Alternate between slot 1 and slot 2
+		}
+	}
+}
+
+// slotsActive reports whether any of the beacon's slots is carrying traffic.
+func (b *Beacon) slotsActive() bool {
+	for _, slot := range b.Slots {
+		if slot != nil && slot.IsActive() {
+			return true
+		}
+	}
+	return false
+}
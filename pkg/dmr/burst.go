@@ -0,0 +1,226 @@
+// Package dmr provides DMR protocol logic, including classification of 33-byte DMR bursts.
+package dmr
+
+import (
+	"errors"    // For error handling
+	"fmt"       // For formatted errors
+	"math/bits" // For sync pattern bit error counting
+)
+
+// DMR sync patterns carried in the centre 48 bits of a burst.
+const (
+	BSVoiceSync = 0x755FD7DF75F7 // Base station sourced voice
+	BSDataSync  = 0xDFF57D75DF5D // Base station sourced data
+	MSVoiceSync = 0x7F7D5DD57DFD // Mobile station sourced voice
+	MSDataSync  = 0xD5D7F77FD757 // Mobile station sourced data
+)
+
+// syncErrorThreshold is the number of bit errors tolerated when matching a sync pattern.
+const syncErrorThreshold = 4
+
+// BurstLength is the length in bytes of a DMR burst without the CACH.
+const BurstLength = 33
+
+// BurstKind classifies a DMR burst.
+type BurstKind int
+
+const (
+	BurstUnknown BurstKind = iota // No sync found and not part of a voice superframe
+	BurstVoice                    // Voice burst carrying three AMBE+2 frames
+	BurstData                     // Data burst carrying a slot type
+	BurstIdle                     // Data burst with the idle data type
+)
+
+// String returns the name of the burst kind.
+func (k BurstKind) String() string {
+	switch k {
+	case BurstVoice:
+		return "voice"
+	case BurstData:
+		return "data"
+	case BurstIdle:
+		return "idle"
+	default:
+		return "unknown"
+	}
+}
+
+// SyncType identifies which sync pattern a burst carries.
+type SyncType int
+
+const (
+	SyncNone    SyncType = iota // Embedded signalling instead of a sync pattern
+	SyncBSVoice                 // Base station sourced voice sync
+	SyncBSData                  // Base station sourced data sync
+	SyncMSVoice                 // Mobile station sourced voice sync
+	SyncMSData                  // Mobile station sourced data sync
+)
+
+// IsVoice reports whether the sync type is a voice sync.
+func (s SyncType) IsVoice() bool {
+	return s == SyncBSVoice || s == SyncMSVoice
+}
+
+// IsData reports whether the sync type is a data sync.
+func (s SyncType) IsData() bool {
+	return s == SyncBSData || s == SyncMSData
+}
+
+// Burst is a decoded 33-byte DMR burst.
+type Burst struct {
+	Kind     BurstKind // Voice, data, idle or unknown
+	Sync     SyncType  // Sync pattern found in the burst
+	Position byte      // Superframe position 'A' to 'F' for voice bursts, 0 otherwise
+	SlotType *SlotType // Slot type of data and idle bursts
+	AMBE     [3][]byte // The three 9-byte AMBE+2 frames of voice bursts
+	Embedded []byte    // The 6-byte embedded signalling field (sync or EMB plus embedded data)
+	EMB      *EMB      // Decoded EMB of voice bursts B to F
+	Data     []byte    // Raw burst
+}
+
+// EmbeddedData returns the 32 bits of embedded data carried by voice bursts B to F.
+func (b *Burst) EmbeddedData() []byte {
+	if b.Kind != BurstVoice || b.Position == 'A' || len(b.Embedded) != 6 {
+		return nil
+	}
+
+	// The EMB occupies the first and last byte of the embedded field
+	return append([]byte{}, b.Embedded[1:5]...)
+}
+
+// BurstDecoder classifies bursts received on one slot, tracking the voice superframe position.
+type BurstDecoder struct {
+	voiceSeq int // Position of the last voice burst in the superframe (0 = A), -1 when not in a superframe
+}
+
+// NewBurstDecoder creates a new BurstDecoder.
+func NewBurstDecoder() *BurstDecoder {
+	return &BurstDecoder{voiceSeq: -1}
+}
+
+// Reset forgets the current superframe position.
+func (d *BurstDecoder) Reset() {
+	d.voiceSeq = -1
+}
+
+// Decode classifies a 33-byte burst.
+func (d *BurstDecoder) Decode(data []byte) (*Burst, error) {
+	if len(data) != BurstLength {
+		return nil, fmt.Errorf("invalid burst length %d, expected %d bytes", len(data), BurstLength)
+	}
+
+	burst := &Burst{
+		Sync:     FindSync(data),
+		Embedded: extractEmbedded(data),
+		Data:     data,
+	}
+
+	switch {
+	case burst.Sync.IsData():
+		d.voiceSeq = -1
+		slotType := &SlotType{}
+		if err := slotType.PutData(data); err != nil {
+			return nil, err
+		}
+		burst.SlotType = slotType
+		burst.Kind = BurstData
+		if slotType.DataType == DtIdle {
+			burst.Kind = BurstIdle
+		}
+
+	case burst.Sync.IsVoice():
+		d.voiceSeq = 0
+		burst.Kind = BurstVoice
+		burst.Position = 'A'
+		burst.AMBE = extractAMBE(data)
+
+	case d.voiceSeq >= 0 && d.voiceSeq < 5:
+		d.voiceSeq++
+		burst.Kind = BurstVoice
+		burst.Position = byte('A' + d.voiceSeq)
+		burst.AMBE = extractAMBE(data)
+		emb := &EMB{}
+		if err := emb.PutData(data); err != nil {
+			return nil, err
+		}
+		burst.EMB = emb
+
+	default:
+		d.voiceSeq = -1
+		burst.Kind = BurstUnknown
+		return burst, errors.New("no sync pattern found")
+	}
+
+	return burst, nil
+}
+
+// FindSync returns the sync pattern carried by a 33-byte burst, or SyncNone.
+func FindSync(data []byte) SyncType {
+	if len(data) < 20 {
+		return SyncNone
+	}
+
+	var pattern uint64
+	for _, b := range extractEmbedded(data) {
+		pattern = pattern<<8 | uint64(b)
+	}
+
+	candidates := []struct {
+		sync uint64
+		kind SyncType
+	}{
+		{BSVoiceSync, SyncBSVoice},
+		{BSDataSync, SyncBSData},
+		{MSVoiceSync, SyncMSVoice},
+		{MSDataSync, SyncMSData},
+	}
+	for _, c := range candidates {
+		if bits.OnesCount64(pattern^c.sync) <= syncErrorThreshold {
+			return c.kind
+		}
+	}
+	return SyncNone
+}
+
+// extractEmbedded returns the 48 bits between the two halves of the payload (bits 108 to 155).
+func extractEmbedded(data []byte) []byte {
+	embedded := make([]byte, 6)
+	for i := 0; i < 6; i++ {
+		embedded[i] = data[13+i]<<4 | data[14+i]>>4
+	}
+	return embedded
+}
+
+// extractAMBE separates the three 72-bit AMBE+2 frames of a voice burst.
+// The second frame straddles the embedded signalling field.
+func extractAMBE(data []byte) [3][]byte {
+	var ambe [3][]byte
+	ambe[0] = append([]byte{}, data[0:9]...)
+	ambe[1] = []byte{
+		data[9], data[10], data[11], data[12],
+		data[13]&0xF0 | data[19]&0x0F,
+		data[20], data[21], data[22], data[23],
+	}
+	ambe[2] = append([]byte{}, data[24:33]...)
+	return ambe
+}
+
+// InsertAMBE writes three 9-byte AMBE+2 frames back into a voice burst, leaving the embedded field untouched.
+func InsertAMBE(data []byte, ambe [3][]byte) error {
+	if len(data) != BurstLength {
+		return fmt.Errorf("invalid burst length %d, expected %d bytes", len(data), BurstLength)
+	}
+	for i := range ambe {
+		if len(ambe[i]) != 9 {
+			return fmt.Errorf("invalid AMBE frame %d length %d, expected 9 bytes", i, len(ambe[i]))
+		}
+	}
+
+	copy(data[0:9], ambe[0])
+	copy(data[9:13], ambe[1][0:4])
+	data[13] = ambe[1][4]&0xF0 | data[13]&0x0F
+	data[19] = data[19]&0xF0 | ambe[1][4]&0x0F
+	copy(data[20:24], ambe[1][5:9])
+	copy(data[24:33], ambe[2])
+	return nil
+}
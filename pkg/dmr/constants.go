@@ -1,9 +1,24 @@
 package dmr
 
-// Placeholder values for constants. Update these based on protocol specifications.
+// MMDVM modem frame tags and DMR flag bits, as defined by the MMDVM firmware.
 const (
-	TAG_DATA      = 0x01 // Example value, replace with actual value
-	DMR_IDLE_RX   = 0x02 // Example value, replace with actual value
-	DMR_SYNC_DATA = 0x04 // Example value, replace with actual value
-	DT_CSBK       = 0x08 // Example value, replace with actual value
+	TagData      = 0x01 // Frame carries a DMR burst
+	TagLost      = 0x02 // Modem lost the signal
+	DmrIdleRx    = 0x80 // Modem received the burst while idle
+	DmrSyncData  = 0x40 // Burst carries a data sync pattern
+	DmrSyncAudio = 0x20 // Burst carries a voice sync pattern
+	DmrDtMask    = 0x0F // Mask for the data type (data bursts) or voice sequence (voice bursts)
+)
+
+// DMR data types carried in the slot type field of data bursts.
+const (
+	DtVoicePIHeader    = 0x00 // Privacy indicator header
+	DtVoiceLCHeader    = 0x01 // Voice LC header
+	DtTerminatorWithLC = 0x02 // Terminator with LC
+	DtCSBK             = 0x03 // Control signalling block
+	DtDataHeader       = 0x06 // Data header
+	DtRate12Data       = 0x07 // Rate 1/2 data
+	DtRate34Data       = 0x08 // Rate 3/4 data
+	DtIdle             = 0x09 // Idle burst
+	DtRate1Data        = 0x0A // Rate 1 data
 )
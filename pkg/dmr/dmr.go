@@ -2,49 +2,233 @@
 package dmr
 
 import (
-	"fmt" // For formatted output
+	"fmt"     // For formatted output
+	"log"     // For logging debug/info messages
+	"strconv" // For APRS source IDs
+	"time"    // For beacon timing
 
+	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"   // For gating GPS positions to APRS
 	"github.com/unklstewy/mmdvm_ghost/pkg/config" // For DMR configuration
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"   // For holding CW IDs off during transmissions
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"  // For transmitting beacons
 )
 
-// Exported constants for DMR packet processing (used for identifying packet types)
-const (
-	TagData     = 0x00 // Tag for data packets (example value)
-	DmrIdleRx   = 0x01 // Idle RX state (example value)
-	DmrSyncData = 0x02 // Sync data state (example value)
-	DtCSBK      = 0x03 // CSBK data type (example value)
-	// BsdWnAct     = 0x04 // Example value, replace with actual if needed
+// Package-level DMR state shared by the RF and network paths.
+var (
+	slots    = []*DMRSlot{NewDMRSlot(1, 0), NewDMRSlot(2, 0)}        // Slot 1 and slot 2 state
+	beacon   *Beacon                                                 // Beacon transmitter, nil when beacons are disabled
+	rfFilter *RFFilter                                               // Filter applied to bursts received over RF
+	decoders = []*BurstDecoder{NewBurstDecoder(), NewBurstDecoder()} // Burst decoders for slot 1 and slot 2
+	lcs      = []*EmbeddedLC{NewEmbeddedLC(), NewEmbeddedLC()}       // Embedded LC reassembly for slot 1 and slot 2
+	lookup   Lookup                                                  // Maps IDs heard on RF to callsigns, nil without an ID file
 )
 
-// HandleDMRPacket is the main entry point for handling DMR packets.
-// It validates the packet, checks for CSBK, and processes it using the CSBK logic.
-func HandleDMRPacket(packet []byte) {
-	// Check for minimum packet length
-	if len(packet) < 3 {
-		fmt.Println("Invalid packet: too short")
+// HandleDMRPacket passes a modem frame received on the given slot to the RF path. The frame is a tag byte,
+// followed for TagData by the flags byte and the 33-byte burst.
+func HandleDMRPacket(slotNo uint, packet []byte) {
+	if slotNo < 1 || slotNo > 2 || len(packet) < 1 {
+		log.Printf("HandleDMRPacket: Invalid frame of %d bytes on slot %d", len(packet), slotNo)
 		return
 	}
 
-	// Validate wakeup packet (header check)
-	if packet[0] != TagData || packet[1] != (DmrIdleRx|DmrSyncData|DtCSBK) {
-		fmt.Println("Invalid wakeup packet")
+	if packet[0] == TagLost {
+		log.Printf("DMR: Slot %d RF signal lost", slotNo)
+		endOfRF(slotNo)
 		return
 	}
+	WriteModem(slotNo, packet)
+}
 
-	// Process CSBK (Control Signaling Block)
-	csbk := NewCSBK()
-	if err := csbk.Put(packet[2:]); err != nil {
-		fmt.Println("Invalid CSBK data:", err)
+// InitLookup loads the DMR ID file used to map the IDs heard on RF to callsigns. Positions are only gated
+// to APRS for IDs it knows.
+func InitLookup(path string) {
+	lookup = nil
+	if path == "" {
 		return
 	}
 
-	csbko := csbk.GetCSBKO()
-	fmt.Printf("Processed CSBKO: %v\n", csbko)
+	l, err := NewIDLookup(path)
+	if err != nil {
+		log.Printf("DMR: %v", err)
+		return
+	}
+	log.Printf("DMR: Loaded %d IDs from %s", l.Len(), path)
+	lookup = l
 }
 
 // Init initializes the DMR protocol handler with the given configuration.
 // This function is intended to be called at startup to set up DMR state.
 func Init(cfg config.DMRConfig) {
-	// TODO: Add initialization logic for DMR
+	// Stop any beacon left over from a previous configuration
+	if beacon != nil {
+		beacon.Stop()
+		beacon = nil
+	}
+
+	// Apply the SelfOnly rule to the shared access control and build the RF filter
+	accessControl.SelfOnly = cfg.SelfOnly
+	accessControl.ID = uint32(cfg.DMRID)
+	rfFilter = NewRFFilter(cfg, accessControl)
+
+	if cfg.Beacons {
+		interval := time.Duration(cfg.BeaconInterval) * time.Second
+		duration := time.Duration(cfg.BeaconDuration) * time.Second
+		beacon = NewBeacon(uint8(cfg.ColorCode), interval, duration, slots, nil)
+		if modem.IsOpen() {
+			beacon.Output = writeBeacon
+			beacon.Key = keyBeacon
+		}
+		beacon.Start()
+	}
+
 	fmt.Printf("DMR protocol handler initialized with ColorCode: %d\n", cfg.ColorCode)
 }
+
+// writeBeacon transmits an idle beacon burst on the given slot.
+func writeBeacon(slotNo uint, burst []byte) {
+	command := byte(modem.CmdDMRData1)
+	if slotNo == 2 {
+		command = modem.CmdDMRData2
+	}
+	if err := modem.Write(command, append([]byte{DmrSyncData | DtIdle}, burst...)); err != nil {
+		log.Printf("Beacon: Unable to write to the modem: %v", err)
+	}
+}
+
+// keyBeacon starts or stops the modem transmitter around a beacon.
+func keyBeacon(on bool) {
+	var start byte
+	if on {
+		start = 0x01
+	}
+	if err := modem.Write(modem.CmdDMRStart, []byte{start}); err != nil {
+		log.Printf("Beacon: Unable to write to the modem: %v", err)
+	}
+}
+
+// WriteModem handles a DMR frame received from the modem on the given slot.
+// It returns false when the frame is rejected by the RF filter.
+func WriteModem(slotNo uint, data []byte) bool {
+	if slotNo < 1 || slotNo > 2 {
+		log.Printf("WriteModem: Invalid slot number: %d", slotNo)
+		return false
+	}
+
+	if rfFilter == nil || !rfFilter.Accept(data) {
+		return false
+	}
+
+	burst, err := decoders[slotNo-1].Decode(data[2:35])
+	if err != nil {
+		log.Printf("WriteModem: Slot %d unable to decode burst: %v", slotNo, err)
+		return false
+	}
+
+	// Track RF activity so beacons are inhibited while a transmission is in progress
+	slot := slots[slotNo-1]
+	switch burst.Kind {
+	case BurstData:
+		switch burst.SlotType.DataType {
+		case DtVoiceLCHeader:
+			if slot.SetRFState("AUDIO") != "AUDIO" {
+				cwid.Event("rf_start")
+			}
+		case DtTerminatorWithLC:
+			endOfRF(slotNo)
+		}
+	case BurstVoice:
+		if burst.EMB != nil {
+			handleEmbeddedLC(slot, lcs[slotNo-1], burst)
+		}
+		// Voice is only relayed once a header or the embedded LC has been accepted, so late entry waits for
+		// the embedded LC and transmissions rejected by the access rules are dropped until they end
+		return slot.GetRFState() == "AUDIO"
+	}
+
+	return true
+}
+
+// endOfRF ends the RF transmission on a slot, after a terminator or when the modem lost the signal.
+func endOfRF(slotNo uint) {
+	if slots[slotNo-1].SetRFState("LISTENING") == "AUDIO" {
+		cwid.Event("rf_end")
+	}
+	slots[slotNo-1].RFSrcID = 0
+	decoders[slotNo-1].Reset()
+	lcs[slotNo-1].Reset()
+}
+
+// handleEmbeddedLC collects the embedded LC of a voice burst, applying the access rules to the talker of
+// the slot and gating GPS positions to APRS.
+func handleEmbeddedLC(slot *DMRSlot, assembler *EmbeddedLC, burst *Burst) {
+	lc, err := assembler.Add(burst.EMB.LCSS, burst.EmbeddedData())
+	if err != nil {
+		log.Printf("WriteModem: Slot %d invalid embedded LC: %v", slot.SlotNo, err)
+		return
+	}
+	if lc == nil {
+		return
+	}
+	// With EmbeddedLCOnly, talker alias and GPS are not relayed
+	flco := lc[0] & 0x3F
+	if !rfFilter.KeepEmbeddedData(flco) {
+		return
+	}
+	slot.EmbeddedLC = lc
+
+	switch flco {
+	case FLCOGroupVoice, FLCOUnitVoice:
+		srcID := uint32(lc[6])<<16 | uint32(lc[7])<<8 | uint32(lc[8])
+		state := slot.GetRFState()
+		if state == "REJECTED" || srcID == slot.RFSrcID {
+			return
+		}
+		slot.RFSrcID = srcID
+
+		// The access rules also apply to transmissions joined late, without a voice LC header
+		if !rfFilter.AcceptSrcID(srcID) {
+			if slot.SetRFState("REJECTED") == "AUDIO" {
+				cwid.Event("rf_end")
+			}
+			return
+		}
+		if state == "LISTENING" {
+			log.Printf("DMR: Slot %d late entry from %d", slot.SlotNo, srcID)
+			slot.SetRFState("AUDIO")
+			cwid.Event("rf_start")
+		}
+	case FLCOGPSInfo:
+		latitude, longitude, ok := GPSPosition(lc)
+		if !ok || slot.RFSrcID == 0 {
+			return
+		}
+		log.Printf("DMR: Slot %d position from %d: %.5f, %.5f", slot.SlotNo, slot.RFSrcID, latitude, longitude)
+
+		// APRS sources are callsigns, so positions from IDs without one are not gated
+		source := callsignOf(slot.RFSrcID)
+		if gateway := ax25.GatewayCallsign(); gateway != "" && source != "" {
+			ax25.SendAPRS(ax25.PositionPacket(source, "APZDMR", gateway, latitude, longitude, "/[", ""))
+		}
+	}
+}
+
+// callsignOf returns the callsign of a DMR ID, empty when it is not known.
+func callsignOf(id uint32) string {
+	if lookup == nil {
+		return ""
+	}
+	callsign := lookup.Find(id)
+	if callsign == strconv.FormatUint(uint64(id), 10) {
+		return ""
+	}
+	return callsign
+}
+
+// TriggerBeacon requests a network-triggered beacon. It does nothing when beacons are disabled.
+func TriggerBeacon() {
+	if beacon == nil {
+		log.Printf("TriggerBeacon: Beacons are disabled")
+		return
+	}
+	beacon.Trigger()
+}
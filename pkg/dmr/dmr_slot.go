@@ -2,6 +2,7 @@ package dmr
 
 import (
 	"fmt"
+	"sync"
 )
 
 // DMRSlot represents a DMR slot and its state.
@@ -12,9 +13,12 @@ type DMRSlot struct {
 	RFState      string
 	NetState     string
 	Queue        []byte
+	RFSrcID      uint32
 	EmbeddedLC   []byte
 	EmbeddedData []byte
 	State        string
+
+	mu sync.Mutex // Guards RFState and NetState, which the beacon reads from its own goroutine
 }
 
 // NewDMRSlot creates a new DMRSlot instance.
@@ -32,21 +36,50 @@ func NewDMRSlot(slotNo uint, timeout uint) *DMRSlot {
 
 // PrintState prints the current state of the DMR slot.
 func (slot *DMRSlot) PrintState() {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
 	fmt.Printf("Slot %d: RFState=%s, NetState=%s\n", slot.SlotNo, slot.RFState, slot.NetState)
 }
 
 // UpdateState updates the RF and network states of the slot.
 func (slot *DMRSlot) UpdateState(rfState, netState string) {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
 	slot.RFState = rfState
 	slot.NetState = netState
 	fmt.Printf("Slot %d state updated: RFState=%s, NetState=%s\n", slot.SlotNo, slot.RFState, slot.NetState)
 }
 
+// SetRFState sets the RF state of the slot, returning the previous state.
+func (slot *DMRSlot) SetRFState(state string) string {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	previous := slot.RFState
+	slot.RFState = state
+	return previous
+}
+
+// GetRFState returns the RF state of the slot.
+func (slot *DMRSlot) GetRFState() string {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	return slot.RFState
+}
+
+// IsActive reports whether the slot is carrying RF or network traffic.
+func (slot *DMRSlot) IsActive() bool {
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	return slot.RFState != "LISTENING" || slot.NetState != "IDLE"
+}
+
 // HandleTimeout checks if the slot has timed out and resets its state if necessary.
 func (slot *DMRSlot) HandleTimeout(elapsedTime uint) {
 	if elapsedTime > slot.Timeout {
+		slot.mu.Lock()
 		slot.RFState = "LISTENING"
 		slot.NetState = "IDLE"
+		slot.mu.Unlock()
 		slot.Queue = make([]byte, 0, 5000)
 		fmt.Printf("Slot %d timed out and reset to default state\n", slot.SlotNo)
 	}
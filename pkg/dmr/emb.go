@@ -0,0 +1,98 @@
+// Package dmr provides DMR protocol logic, including the EMB field of voice bursts.
+package dmr
+
+import (
+	"errors"    // For error handling
+	"math/bits" // For Hamming distance and parity calculation
+)
+
+// qr1676GenPoly is the generator polynomial of the QR(17,9) code the QR(16,7,6) code is derived from.
+const qr1676GenPoly = 0x139
+
+// qr1676Codewords holds the 16-bit codeword for each of the 128 possible 7-bit values.
+var qr1676Codewords = buildQR1676Codewords()
+
+// buildQR1676Codewords encodes every 7-bit value: 7 data bits, 8 cyclic parity bits and an overall parity bit.
+func buildQR1676Codewords() [128]uint16 {
+	var table [128]uint16
+	for value := 0; value < 128; value++ {
+		rem := uint32(value) << 8
+		for i := 14; i >= 8; i-- {
+			if rem&(1<<uint(i)) != 0 {
+				rem ^= qr1676GenPoly << uint(i-8)
+			}
+		}
+		word := uint16(value)<<9 | uint16(rem&0xFF)<<1
+		if bits.OnesCount16(word)%2 != 0 {
+			word |= 1
+		}
+		table[value] = word
+	}
+	return table
+}
+
+// EncodeQR1676 returns the QR(16,7,6) codeword for a 7-bit value.
+func EncodeQR1676(value uint8) uint16 {
+	return qr1676Codewords[value&0x7F]
+}
+
+// DecodeQR1676 returns the 7-bit value of the nearest QR(16,7,6) codeword and the number of corrected bits.
+// Up to two bit errors are corrected; an error is returned for more.
+func DecodeQR1676(word uint16) (uint8, int, error) {
+	best, bestDist := 0, 17
+	for value, code := range qr1676Codewords {
+		if dist := bits.OnesCount16(word ^ code); dist < bestDist {
+			best, bestDist = value, dist
+		}
+	}
+	if bestDist > 2 {
+		return 0, bestDist, errors.New("uncorrectable QR(16,7,6) code")
+	}
+	return uint8(best), bestDist, nil
+}
+
+// EMB represents the embedded signalling field carried by voice bursts B to F.
+type EMB struct {
+	ColorCode uint8 // DMR color code
+	PI        bool  // Privacy indicator
+	LCSS      uint8 // Link control start/stop
+}
+
+// PutData decodes the EMB field from a 33-byte voice burst.
+func (e *EMB) PutData(data []byte) error {
+	if len(data) < 20 {
+		return errors.New("data array too short")
+	}
+
+	word := uint16((data[13]<<4)&0xF0|(data[14]>>4)&0x0F)<<8 |
+		uint16((data[18]<<4)&0xF0|(data[19]>>4)&0x0F)
+
+	value, _, err := DecodeQR1676(word)
+	if err != nil {
+		return err
+	}
+
+	e.ColorCode = (value >> 3) & 0x0F
+	e.PI = (value & 0x04) == 0x04
+	e.LCSS = value & 0x03
+	return nil
+}
+
+// GetData encodes the EMB field into a 33-byte voice burst.
+func (e *EMB) GetData(data []byte) error {
+	if len(data) < 20 {
+		return errors.New("data array too short")
+	}
+
+	value := (e.ColorCode&0x0F)<<3 | e.LCSS&0x03
+	if e.PI {
+		value |= 0x04
+	}
+	word := EncodeQR1676(value)
+
+	data[13] = (data[13] & 0xF0) | uint8(word>>12)&0x0F
+	data[14] = (data[14] & 0x0F) | uint8(word>>4)&0xF0
+	data[18] = (data[18] & 0xF0) | uint8(word>>4)&0x0F
+	data[19] = (data[19] & 0x0F) | uint8(word<<4)&0xF0
+	return nil
+}
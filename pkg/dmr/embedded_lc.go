@@ -0,0 +1,196 @@
+// Package dmr provides DMR protocol logic, including reassembly of the embedded link control of voice superframes.
+package dmr
+
+import (
+	"errors" // For error handling
+)
+
+// Link control start/stop values of the EMB.
+const (
+	LCSSSingle   = 0 // Single fragment, reverse channel or null embedded data
+	LCSSFirst    = 1 // First fragment of an embedded LC
+	LCSSLast     = 2 // Last fragment of an embedded LC
+	LCSSContinue = 3 // Continuation fragment of an embedded LC
+)
+
+// Full link control opcodes carried in embedded LCs.
+const (
+	FLCOGroupVoice = 0x00 // Group voice channel user
+	FLCOUnitVoice  = 0x03 // Unit to unit voice channel user
+	FLCOGPSInfo    = 0x08 // GPS position of the talker
+)
+
+// embeddedLCFragments is the number of 32-bit fragments making up an embedded LC.
+const embeddedLCFragments = 4
+
+// EmbeddedLC reassembles the 72-bit link control spread over the embedded data of voice bursts B to E.
+type EmbeddedLC struct {
+	raw   [embeddedLCFragments * 32]bool // Fragment bits in the order received
+	count int                            // Fragments collected, 0 when waiting for a first fragment
+}
+
+// NewEmbeddedLC creates a new EmbeddedLC.
+func NewEmbeddedLC() *EmbeddedLC {
+	return &EmbeddedLC{}
+}
+
+// Reset discards the fragments collected so far.
+func (e *EmbeddedLC) Reset() {
+	e.count = 0
+}
+
+// Add adds the 4 bytes of embedded data of a voice burst with the given LCSS. It returns the 9-byte LC
+// once the last fragment has been added, nil while fragments are still missing, and an error when the
+// reassembled LC fails its checks.
+func (e *EmbeddedLC) Add(lcss uint8, data []byte) ([]byte, error) {
+	if len(data) != 4 {
+		return nil, errors.New("embedded data must be 4 bytes")
+	}
+
+	switch lcss {
+	case LCSSFirst:
+		e.count = 0
+	case LCSSContinue:
+		if e.count == 0 || e.count == embeddedLCFragments-1 {
+			e.count = 0
+			return nil, nil
+		}
+	case LCSSLast:
+		if e.count != embeddedLCFragments-1 {
+			e.count = 0
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	for i := 0; i < 32; i++ {
+		e.raw[e.count*32+i] = data[i/8]&(0x80>>(i%8)) != 0
+	}
+	e.count++
+
+	if lcss != LCSSLast {
+		return nil, nil
+	}
+	e.count = 0
+	return decodeEmbeddedLC(e.raw)
+}
+
+// decodeEmbeddedLC deinterleaves the 128 received bits into the 8x16 BPTC matrix, checks the Hamming(16,11,4)
+// rows, the column parity and the 5-bit checksum, and returns the 9 LC bytes.
+func decodeEmbeddedLC(raw [embeddedLCFragments * 32]bool) ([]byte, error) {
+	var matrix [128]bool
+	b := 0
+	for a := 0; a < 128; a++ {
+		matrix[b] = raw[a]
+		b += 16
+		if b > 127 {
+			b -= 127
+		}
+	}
+
+	// Every row but the last is a Hamming(16,11,4) codeword, the last row holds the column parity
+	for row := 0; row < 112; row += 16 {
+		if !decodeHamming16114(matrix[row : row+16]) {
+			return nil, errors.New("uncorrectable embedded LC row")
+		}
+	}
+	for col := 0; col < 16; col++ {
+		parity := false
+		for row := 0; row < 128; row += 16 {
+			parity = parity != matrix[row+col]
+		}
+		if parity {
+			return nil, errors.New("embedded LC column parity error")
+		}
+	}
+
+	// The first three rows carry 11 data bits, the next four 10 data bits and one checksum bit
+	var bits []bool
+	bits = append(bits, matrix[0:11]...)
+	bits = append(bits, matrix[16:27]...)
+	for row := 32; row < 112; row += 16 {
+		bits = append(bits, matrix[row:row+10]...)
+	}
+	lc := make([]byte, 9)
+	for i, bit := range bits {
+		if bit {
+			lc[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+
+	checksum := 0
+	for _, bit := range []bool{matrix[42], matrix[58], matrix[74], matrix[90], matrix[106]} {
+		checksum <<= 1
+		if bit {
+			checksum |= 1
+		}
+	}
+	sum := 0
+	for _, v := range lc {
+		sum += int(v)
+	}
+	if sum%31 != checksum {
+		return nil, errors.New("embedded LC checksum error")
+	}
+	return lc, nil
+}
+
+// hamming16114Parity lists the data bits covered by each of the five parity bits of Hamming(16,11,4).
+var hamming16114Parity = [5][]int{
+	{0, 1, 2, 3, 5, 7, 8},
+	{1, 2, 3, 4, 6, 8, 9},
+	{2, 3, 4, 5, 7, 9, 10},
+	{0, 1, 2, 4, 6, 7, 10},
+	{0, 2, 5, 6, 8, 9, 10},
+}
+
+// hamming16114Syndrome computes the syndrome of a 16-bit Hamming(16,11,4) codeword.
+func hamming16114Syndrome(d []bool) int {
+	syndrome := 0
+	for p, covered := range hamming16114Parity {
+		parity := d[11+p]
+		for _, i := range covered {
+			parity = parity != d[i]
+		}
+		if parity {
+			syndrome |= 1 << p
+		}
+	}
+	return syndrome
+}
+
+// decodeHamming16114 corrects a single bit error in a Hamming(16,11,4) codeword in place, returning false
+// when the errors are uncorrectable.
+func decodeHamming16114(d []bool) bool {
+	syndrome := hamming16114Syndrome(d)
+	if syndrome == 0 {
+		return true
+	}
+	for i := range d {
+		d[i] = !d[i]
+		if hamming16114Syndrome(d) == 0 {
+			return true
+		}
+		d[i] = !d[i]
+	}
+	return false
+}
+
+// GPSPosition decodes the position of a GPS Info LC, returning false for other LCs.
+func GPSPosition(lc []byte) (latitude, longitude float64, ok bool) {
+	if len(lc) != 9 || lc[0]&0x3F != FLCOGPSInfo {
+		return 0, 0, false
+	}
+
+	// 25-bit longitude and 24-bit latitude in two's complement
+	lon := int32(uint32(lc[2]&0x01)<<31|uint32(lc[3])<<23|uint32(lc[4])<<15|uint32(lc[5])<<7) >> 7
+	lat := int32(uint32(lc[6])<<24|uint32(lc[7])<<16|uint32(lc[8])<<8) >> 8
+
+	longitude = float64(lon) * 360 / (1 << 25)
+	latitude = float64(lat) * 180 / (1 << 24)
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return 0, 0, false
+	}
+	return latitude, longitude, true
+}
@@ -2,7 +2,6 @@ package dmr
 
 import (
 	"errors"
-	"log"
 )
 
 const (
@@ -37,27 +36,65 @@ func Encode(msg []byte, nbytes int) ([]byte, error) {
 	}
 
 	parity := make([]byte, NPAR)
-	log.Printf("Encode: Initial parity state: %v", parity)
 	for i := 0; i < nbytes; i++ {
 		dbyte := msg[i] ^ parity[NPAR-1]
-		log.Printf("Encode: Processing byte %d: 0x%X, dbyte: 0x%X", i, msg[i], dbyte)
 		for j := NPAR - 1; j > 0; j-- {
 			parity[j] = parity[j-1] ^ gmult(POLY[j], dbyte)
-			log.Printf("Encode: Updated parity[%d]: 0x%X", j, parity[j])
 		}
 		parity[0] = gmult(POLY[0], dbyte)
-		log.Printf("Encode: Updated parity[0]: 0x%X", parity[0])
 	}
-	log.Printf("Encode: Final parity state: %v", parity)
-
-	// Add debug log to trace generated parity bytes
-	log.Printf("Encode: Generated parity bytes: %v", parity)
-	log.Printf("Encode: Parity byte order: %v", parity)
 
 	return parity, nil
 }
 
-// Check validates the input data using Reed-Solomon error correction.
+// RS(12,9) parity masks applied to full LC codewords, identifying the burst type that carries them.
+const (
+	RSMaskNone          = 0x00 // No mask (raw RS(12,9) codeword)
+	RSMaskPIHeader      = 0x69 // Privacy indicator header
+	RSMaskVoiceLCHeader = 0x96 // Voice LC header
+	RSMaskTerminator    = 0x99 // Terminator with LC
+)
+
+// gexp and glog are the antilog and log tables for GF(2^8), built from the generator alpha = 2.
+var gexp, glog = buildGaloisTables()
+
+// buildGaloisTables builds the antilog and log tables for GF(2^8).
+func buildGaloisTables() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var lg [256]byte
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		lg[x] = byte(i)
+		x = gmult(x, 2)
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, lg
+}
+
+// gdiv performs division in Galois Field (2^8). b must be non-zero.
+func gdiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gexp[int(glog[a])+255-int(glog[b])]
+}
+
+// syndromes evaluates the RS(12,9) codeword at the generator roots alpha^1 to alpha^3.
+func syndromes(data []byte) [NPAR]byte {
+	var s [NPAR]byte
+	for j := 0; j < NPAR; j++ {
+		root := gexp[j+1]
+		for i := 0; i < 12; i++ {
+			s[j] = gmult(s[j], root) ^ data[i]
+		}
+	}
+	return s
+}
+
+// Check validates the input data using Reed-Solomon error detection, without correcting it.
 func Check(data []byte) (bool, error) {
 	if len(data) < 12 {
 		return false, errors.New("data too short")
@@ -68,11 +105,41 @@ func Check(data []byte) (bool, error) {
 		return false, err
 	}
 
-	// Add debug log to trace comparison in Check function
-	log.Printf("Check: Data parity bytes: %v, Generated parity bytes: %v", data[9:], parity)
-	log.Printf("Check: Expected parity bytes: %v, Actual parity bytes: %v", data[9:], parity)
-	log.Printf("Check: Comparison results: %v, %v, %v", data[9] == parity[2], data[10] == parity[1], data[11] == parity[0])
-	log.Printf("Check: Comparing data parity bytes: %v with generated parity bytes: %v", data[9:], parity)
+	// Parity bytes are transmitted highest order first
+	return data[9] == parity[2] && data[10] == parity[1] && data[11] == parity[0], nil
+}
+
+// DecodeRS129 checks a 12-byte RS(12,9) codeword in place, correcting up to one byte error.
+// The mask identifies the burst type and is removed from the parity bytes before decoding and restored afterwards.
+// It returns true when a byte was corrected, and an error when the codeword is uncorrectable.
+func DecodeRS129(data []byte, mask byte) (bool, error) {
+	if len(data) < 12 {
+		return false, errors.New("data too short")
+	}
+
+	codeword := make([]byte, 12)
+	copy(codeword, data[:12])
+	for i := 9; i < 12; i++ {
+		codeword[i] ^= mask
+	}
+
+	s := syndromes(codeword)
+	if s[0] == 0 && s[1] == 0 && s[2] == 0 {
+		return false, nil
+	}
+
+	// A single error of value e at power k gives S1 = e.a^k, S2 = e.a^2k and S3 = e.a^3k
+	if s[0] == 0 || s[1] == 0 {
+		return false, errors.New("uncorrectable RS(12,9) codeword")
+	}
+	locator := gdiv(s[1], s[0])
+	value := gdiv(gmult(s[0], s[0]), s[1])
+	power := int(glog[locator])
+	if power > 11 || gmult(s[1], locator) != s[2] {
+		return false, errors.New("uncorrectable RS(12,9) codeword")
+	}
 
-	return data[9] == parity[0] && data[10] == parity[1] && data[11] == parity[2], nil
+	index := 11 - power
+	data[index] ^= value
+	return true, nil
 }
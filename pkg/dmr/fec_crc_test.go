@@ -0,0 +1,61 @@
+package dmr
+
+import (
+	"bytes"
+	"testing"
+)
+
+// voiceLCCodeword is the RS(12,9) protected LC of a voice LC header from a capture: group call to TG 91
+// from 2930995, parity bytes masked for a voice LC header.
+var voiceLCCodeword = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x5B, 0x2C, 0xB9, 0x33, 0x51, 0x18, 0xDC}
+
+func TestCheckRS129(t *testing.T) {
+	codeword := append([]byte{}, voiceLCCodeword...)
+	for i := 9; i < 12; i++ {
+		codeword[i] ^= RSMaskVoiceLCHeader
+	}
+
+	ok, err := Check(codeword)
+	if err != nil || !ok {
+		t.Errorf("Check of an unmasked codeword = %v, %v", ok, err)
+	}
+	codeword[4] ^= 0x01
+	if ok, _ := Check(codeword); ok {
+		t.Error("Check accepted a corrupted codeword")
+	}
+}
+
+func TestDecodeRS129(t *testing.T) {
+	codeword := append([]byte{}, voiceLCCodeword...)
+	corrected, err := DecodeRS129(codeword, RSMaskVoiceLCHeader)
+	if err != nil || corrected {
+		t.Fatalf("DecodeRS129 of a valid codeword = %v, %v", corrected, err)
+	}
+
+	// A wrong mask identifies another burst type
+	if _, err := DecodeRS129(append([]byte{}, voiceLCCodeword...), RSMaskTerminator); err == nil {
+		t.Error("DecodeRS129 accepted the terminator mask")
+	}
+
+	// Any single byte error is corrected, including in the parity
+	for i := 0; i < 12; i++ {
+		codeword := append([]byte{}, voiceLCCodeword...)
+		codeword[i] ^= 0xA5
+		corrected, err := DecodeRS129(codeword, RSMaskVoiceLCHeader)
+		if err != nil || !corrected {
+			t.Errorf("byte %d: DecodeRS129 = %v, %v", i, corrected, err)
+			continue
+		}
+		if !bytes.Equal(codeword, voiceLCCodeword) {
+			t.Errorf("byte %d: corrected to % X", i, codeword)
+		}
+	}
+
+	// Two byte errors are beyond the code
+	codeword = append([]byte{}, voiceLCCodeword...)
+	codeword[2] ^= 0x11
+	codeword[7] ^= 0x40
+	if _, err := DecodeRS129(codeword, RSMaskVoiceLCHeader); err == nil {
+		t.Error("DecodeRS129 accepted two byte errors")
+	}
+}
@@ -1,6 +1,34 @@
 // Package dmr provides DMR protocol logic, including Full LC (Link Control) handling.
 package dmr
 
+// FullLC holds the fields of a full link control that identify a call.
+type FullLC struct {
+	FLCO  uint8  // Full link control opcode, FLCOGroupVoice or FLCOUnitVoice for voice calls
+	FID   uint8  // Feature set ID
+	DstID uint32 // Destination talkgroup or unit
+	SrcID uint32 // Source unit
+}
+
+// DecodeVoiceLCHeader decodes the full LC of a 33-byte voice LC header burst. The BPTC(196,96) code is
+// corrected first, then the RS(12,9) parity masked for a voice LC header must check out, correcting up to
+// one byte.
+func DecodeVoiceLCHeader(burst []byte) (*FullLC, error) {
+	lc, err := DecodeBPTC19696(burst)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := DecodeRS129(lc, RSMaskVoiceLCHeader); err != nil {
+		return nil, err
+	}
+
+	return &FullLC{
+		FLCO:  lc[0] & 0x3F,
+		FID:   lc[1],
+		DstID: uint32(lc[3])<<16 | uint32(lc[4])<<8 | uint32(lc[5]),
+		SrcID: uint32(lc[6])<<16 | uint32(lc[7])<<8 | uint32(lc[8]),
+	}, nil
+}
+
 // HandleFullLC processes a DMR Full Link Control (LC) message.
 // This is a placeholder for the actual logic to handle full LC data.
 func HandleFullLC(data []byte) {
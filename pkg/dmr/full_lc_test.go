@@ -0,0 +1,43 @@
+package dmr
+
+import "testing"
+
+// capturedLCHeader is a voice LC header burst from a capture: group call to TG 91 from 2930995.
+var capturedLCHeader = []byte{
+	0x01, 0x07, 0x0F, 0xF2, 0x19, 0x80, 0x15, 0x68, 0x41, 0xC0, 0x14, 0xE0, 0x04, 0x6D, 0xFF, 0x57, 0xD7,
+	0x5D, 0xF5, 0xDE, 0x33, 0xF8, 0x08, 0x10, 0x31, 0x90, 0x32, 0x20, 0x33, 0x01, 0x9B, 0x02, 0x05,
+}
+
+func TestDecodeVoiceLCHeader(t *testing.T) {
+	want := FullLC{FLCO: FLCOGroupVoice, FID: 0, DstID: 91, SrcID: 2930995}
+
+	lc, err := DecodeVoiceLCHeader(capturedLCHeader)
+	if err != nil {
+		t.Fatalf("DecodeVoiceLCHeader: %v", err)
+	}
+	if *lc != want {
+		t.Errorf("LC = %+v, want %+v", *lc, want)
+	}
+
+	// Scattered bit errors in the payload halves are corrected by the BPTC
+	burst := append([]byte{}, capturedLCHeader...)
+	burst[0] ^= 0x40
+	burst[7] ^= 0x02
+	burst[25] ^= 0x10
+	lc, err = DecodeVoiceLCHeader(burst)
+	if err != nil {
+		t.Fatalf("DecodeVoiceLCHeader with bit errors: %v", err)
+	}
+	if *lc != want {
+		t.Errorf("LC with bit errors = %+v, want %+v", *lc, want)
+	}
+
+	// A burst of errors beyond the BPTC is caught by the RS(12,9) check
+	burst = append([]byte{}, capturedLCHeader...)
+	for i := 2; i < 10; i++ {
+		burst[i] ^= 0xFF
+	}
+	if lc, err := DecodeVoiceLCHeader(burst); err == nil {
+		t.Errorf("DecodeVoiceLCHeader of a corrupted burst = %+v", *lc)
+	}
+}
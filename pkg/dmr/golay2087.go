@@ -0,0 +1,74 @@
+// Package dmr provides DMR protocol logic, including the Golay(20,8) code protecting the slot type.
+package dmr
+
+import (
+	"errors"    // For error handling
+	"math/bits" // For parity calculation
+)
+
+// golay2087GenPoly is the Golay(23,12) generator polynomial the (20,8) code is shortened from.
+const golay2087GenPoly = 0xC75
+
+// golay2087Errors maps each 11-bit syndrome to the correctable error pattern (up to 3 bits) in the 19-bit word.
+var golay2087Errors = buildGolay2087Errors()
+
+// golay2087Syndrome returns the remainder of the 19-bit word divided by the generator polynomial.
+func golay2087Syndrome(word uint32) uint32 {
+	for i := 18; i >= 11; i-- {
+		if word&(1<<uint(i)) != 0 {
+			word ^= golay2087GenPoly << uint(i-11)
+		}
+	}
+	return word & 0x7FF
+}
+
+// buildGolay2087Errors builds the syndrome table for all error patterns of weight 0 to 3.
+func buildGolay2087Errors() map[uint32]uint32 {
+	table := make(map[uint32]uint32)
+	table[0] = 0
+	for a := 0; a < 19; a++ {
+		pattern := uint32(1) << uint(a)
+		table[golay2087Syndrome(pattern)] = pattern
+		for b := a + 1; b < 19; b++ {
+			pattern := uint32(1)<<uint(a) | uint32(1)<<uint(b)
+			table[golay2087Syndrome(pattern)] = pattern
+			for c := b + 1; c < 19; c++ {
+				pattern := uint32(1)<<uint(a) | uint32(1)<<uint(b) | uint32(1)<<uint(c)
+				table[golay2087Syndrome(pattern)] = pattern
+			}
+		}
+	}
+	return table
+}
+
+// DecodeGolay2087 decodes a Golay(20,8) code held in the top 20 bits of a 3-byte array.
+// Up to three bit errors are corrected; an error is returned when the code is uncorrectable.
+func DecodeGolay2087(data []uint8) (uint8, error) {
+	if len(data) < 3 {
+		return 0, errors.New("data array too short")
+	}
+
+	word := uint32(data[0])<<11 | uint32(data[1])<<3 | uint32(data[2])>>5
+	pattern, ok := golay2087Errors[golay2087Syndrome(word)]
+	if !ok {
+		return 0, errors.New("uncorrectable Golay(20,8) code")
+	}
+
+	return uint8((word ^ pattern) >> 11), nil
+}
+
+// EncodeGolay2087 encodes the byte in data[0] into a Golay(20,8) code, writing the 12 parity bits into data[1] and data[2].
+func EncodeGolay2087(data []uint8) {
+	if len(data) < 3 {
+		return
+	}
+
+	word := uint32(data[0]) << 11
+	parity := golay2087Syndrome(word) << 1 // 11 cyclic parity bits
+	if bits.OnesCount32(word|parity>>1)%2 != 0 {
+		parity |= 1 // Overall even parity bit
+	}
+
+	data[1] = uint8(parity >> 4)
+	data[2] = uint8(parity<<4) & 0xF0
+}
@@ -0,0 +1,60 @@
+// Package dmr provides DMR protocol logic, including the DMR ID to callsign lookup.
+package dmr
+
+import (
+	"bufio"   // For reading the ID file line by line
+	"fmt"     // For error wrapping
+	"os"      // For opening the ID file
+	"strconv" // For parsing IDs
+	"strings" // For splitting lines
+)
+
+// IDLookup maps DMR IDs to callsigns, loaded from a DMRIds.dat style file: one ID per line followed by its
+// callsign and optionally a name, separated by tabs or spaces. Lines starting with # are comments.
+type IDLookup struct {
+	callsigns map[uint32]string
+}
+
+// NewIDLookup loads an IDLookup from the given file.
+func NewIDLookup(path string) (*IDLookup, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DMR ID file: %w", err)
+	}
+	defer file.Close()
+
+	l := &IDLookup{callsigns: make(map[uint32]string)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		l.callsigns[uint32(id)] = strings.ToUpper(fields[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read DMR ID file: %w", err)
+	}
+	return l, nil
+}
+
+// Len returns the number of IDs loaded.
+func (l *IDLookup) Len() int {
+	return len(l.callsigns)
+}
+
+// Find returns the callsign of an ID, or the ID itself when it is unknown.
+func (l *IDLookup) Find(id uint32) string {
+	if callsign, ok := l.callsigns[id]; ok {
+		return callsign
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
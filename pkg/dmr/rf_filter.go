@@ -0,0 +1,113 @@
+// Package dmr provides DMR protocol logic, including filtering of bursts received over RF.
+package dmr
+
+import (
+	"log" // For logging debug/info messages
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config" // For DMR configuration
+)
+
+// RFFilter applies the color code, SelfOnly and EmbeddedLCOnly rules to bursts received from the modem,
+// so the hotspot does not relay neighbouring repeaters on another color code.
+type RFFilter struct {
+	ColorCode      uint8          // Color code bursts must carry to be accepted
+	EmbeddedLCOnly bool           // Only relay embedded LC, dropping talker alias and GPS embedded data
+	Access         *AccessControl // Access rules (including SelfOnly) applied to the source ID
+
+	ColorCodeMismatches uint32 // Bursts rejected for carrying another color code
+	DecodeErrors        uint32 // Bursts rejected because the slot type or EMB could not be decoded
+	AccessRejects       uint32 // Transmissions rejected by the access rules
+}
+
+// NewRFFilter creates a new RFFilter from the DMR configuration.
+func NewRFFilter(cfg config.DMRConfig, access *AccessControl) *RFFilter {
+	return &RFFilter{
+		ColorCode:      uint8(cfg.ColorCode),
+		EmbeddedLCOnly: cfg.EmbeddedLCOnly,
+		Access:         access,
+	}
+}
+
+// Accept reports whether a burst received from the modem should be processed.
+// The data is an MMDVM frame: a tag byte, a flags byte and the 33-byte burst.
+func (f *RFFilter) Accept(data []byte) bool {
+	if len(data) < 35 {
+		log.Printf("RFFilter: Frame too short: %d bytes", len(data))
+		return false
+	}
+
+	flags := data[1]
+	burst := data[2:35]
+
+	switch {
+	case flags&DmrSyncData == DmrSyncData:
+		slotType := &SlotType{}
+		if err := slotType.PutData(burst); err != nil {
+			f.DecodeErrors++
+			log.Printf("RFFilter: Unable to decode slot type: %v", err)
+			return false
+		}
+
+		if slotType.ColorCode != f.ColorCode {
+			f.ColorCodeMismatches++
+			log.Printf("RFFilter: Color code mismatch, received %d expected %d (%d mismatches)", slotType.ColorCode, f.ColorCode, f.ColorCodeMismatches)
+			return false
+		}
+
+		if slotType.DataType == DtVoiceLCHeader {
+			return f.acceptLCHeader(burst)
+		}
+
+	case flags&DmrSyncAudio == DmrSyncAudio:
+		// Voice burst A carries the sync pattern instead of an EMB, nothing to check
+
+	default:
+		emb := &EMB{}
+		if err := emb.PutData(burst); err != nil {
+			f.DecodeErrors++
+			log.Printf("RFFilter: Unable to decode EMB: %v", err)
+			return false
+		}
+
+		if emb.ColorCode != f.ColorCode {
+			f.ColorCodeMismatches++
+			log.Printf("RFFilter: Color code mismatch, received %d expected %d (%d mismatches)", emb.ColorCode, f.ColorCode, f.ColorCodeMismatches)
+			return false
+		}
+	}
+
+	return true
+}
+
+// acceptLCHeader validates the source ID of a voice LC header against the access rules. The LC is only
+// trusted once its RS(12,9) parity, masked for a voice LC header, checks out.
+func (f *RFFilter) acceptLCHeader(burst []byte) bool {
+	if f.Access == nil {
+		return true
+	}
+
+	lc, err := DecodeVoiceLCHeader(burst)
+	if err != nil {
+		f.DecodeErrors++
+		log.Printf("RFFilter: Unable to decode voice LC header: %v", err)
+		return false
+	}
+	return f.AcceptSrcID(lc.SrcID)
+}
+
+// AcceptSrcID reports whether a transmission from the source ID is allowed by the access rules.
+func (f *RFFilter) AcceptSrcID(srcID uint32) bool {
+	if f.Access == nil || f.Access.ValidateSrcID(srcID) {
+		return true
+	}
+
+	f.AccessRejects++
+	log.Printf("RFFilter: Transmission from %d rejected by access rules (SelfOnly: %v)", srcID, f.Access.SelfOnly)
+	return false
+}
+
+// KeepEmbeddedData reports whether embedded data with the given FLCO should be relayed.
+// With EmbeddedLCOnly set, only group and unit to unit voice LC is kept, talker alias and GPS are replaced by the LC.
+func (f *RFFilter) KeepEmbeddedData(flco uint8) bool {
+	return !f.EmbeddedLCOnly || flco == FLCOGroupVoice || flco == FLCOUnitVoice
+}
@@ -0,0 +1,47 @@
+package dmr
+
+import (
+	"testing"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+)
+
+// lcHeaderFrame returns the captured voice LC header as received from the modem.
+func lcHeaderFrame() []byte {
+	return append([]byte{TagData, DmrSyncData | DtVoiceLCHeader}, capturedLCHeader...)
+}
+
+func TestRFFilterSelfOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     uint32
+		accept bool
+	}{
+		{"own ID", 2930995, true},
+		{"own ID with an ESSID suffix", 293099501, true},
+		{"another ID", 3141592, false},
+	}
+
+	for _, test := range tests {
+		access := &AccessControl{}
+		access.Init(nil, nil, nil, nil, nil, true, test.id)
+		filter := NewRFFilter(config.DMRConfig{ColorCode: 1, SelfOnly: true}, access)
+
+		if got := filter.Accept(lcHeaderFrame()); got != test.accept {
+			t.Errorf("%s: Accept = %v, want %v", test.name, got, test.accept)
+		}
+		if filter.DecodeErrors != 0 {
+			t.Errorf("%s: %d decode errors", test.name, filter.DecodeErrors)
+		}
+	}
+}
+
+func TestRFFilterColorCode(t *testing.T) {
+	filter := NewRFFilter(config.DMRConfig{ColorCode: 2}, nil)
+	if filter.Accept(lcHeaderFrame()) {
+		t.Error("Accept passed a header on color code 1 with color code 2 configured")
+	}
+	if filter.ColorCodeMismatches != 1 {
+		t.Errorf("ColorCodeMismatches = %d, want 1", filter.ColorCodeMismatches)
+	}
+}
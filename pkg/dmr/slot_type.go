@@ -56,14 +56,3 @@ func (s *SlotType) GetData(data []byte) error {
 	data[20] = (data[20] & 0x03) | ((DMRSlotType[1] << 6) & 0xC0) | ((DMRSlotType[2] >> 2) & 0x3C)
 	return nil
 }
-
-// DecodeGolay2087 decodes a Golay2087 code.
-func DecodeGolay2087(data []uint8) (uint8, error) {
-	// TODO: Implement Golay2087 decoding logic
-	return 0, nil
-}
-
-// EncodeGolay2087 encodes a Golay2087 code.
-func EncodeGolay2087(data []uint8) {
-	// TODO: Implement Golay2087 encoding logic
-}
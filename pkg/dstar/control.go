@@ -0,0 +1,368 @@
+// Package dstar provides D-Star protocol logic, including the RF and network state machine.
+package dstar
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/ambe"
+)
+
+// MMDVM modem frame tags used by D-Star.
+const (
+	TagHeader = 0x00 // Frame carries a 41-byte radio header, or its 83-byte on-air coding
+	TagData   = 0x01 // Frame carries a 12-byte voice and data frame
+	TagLost   = 0x02 // Modem lost the signal
+	TagEOT    = 0x03 // Modem detected the end of transmission
+)
+
+// FrameLength is the length of a D-Star voice frame: 9 bytes of AMBE and 3 bytes of slow data.
+const FrameLength = 12
+
+// framesPerSuperframe is the number of voice frames between slow data sync frames.
+const framesPerSuperframe = 21
+
+// ambeProtectedBits is the number of FEC protected bits in each AMBE 3600x2400 frame, used for BER.
+const ambeProtectedBits = 48
+
+// frameTimeout is how long a stream may go without frames before it is treated as lost.
+const frameTimeout = 1500 * time.Millisecond
+
+// SyncBytes is the slow data pattern carried by the first frame of every superframe.
+var SyncBytes = []byte{0x55, 0x2D, 0x16}
+
+// EndPatternBytes marks the end of a transmission in place of a voice frame.
+var EndPatternBytes = []byte{0x55, 0x55, 0x55, 0x55, 0xC8, 0x7A}
+
+// NullFrame is a silent AMBE frame followed by null slow data, used to fill lost frames.
+var NullFrame = []byte{0x9E, 0x8D, 0x32, 0x88, 0x26, 0x1A, 0x3F, 0x61, 0xE8, 0x16, 0x29, 0xF5}
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a header
+	StateAudio     = "AUDIO"     // Voice stream in progress
+	StateRejected  = "REJECTED"  // RF stream rejected, frames are ignored until it ends
+	StateIdle      = "IDLE"      // Network idle
+)
+
+// NetworkWriter receives RF streams to forward to the network.
+type NetworkWriter interface {
+	WriteHeader(header *Header) error
+	WriteData(data []byte, errors int, end bool) error
+}
+
+// Control manages the D-Star RF and network streams for one module.
+type Control struct {
+	Module   byte                               // Module letter RF headers must address
+	RFState  string                             // Current RF state
+	NetState string                             // Current network state
+	Network  NetworkWriter                      // Network the RF streams are forwarded to, may be nil
+	Output   func(data []byte)                  // Receives modem frames (tag plus payload) for transmission, may be nil
+	Events   func(event string, header *Header) // Receives stream start and end notifications, may be nil
+	SlowData func(event *SlowDataEvent)         // Receives text, GPS, header and squelch items from RF streams, may be nil
+
+	StatusText string // Text encoded into the slow data of network streams sent on RF, empty to pass slow data through
+
+	RFHeader  *Header // Header of the current RF stream
+	NetHeader *Header // Header of the current network stream
+
+	mu        sync.Mutex
+	rfSlow    *SlowDataDecoder
+	netSlow   *SlowDataEncoder
+	rfFrames  int
+	rfErrors  int
+	rfCoded   bool // The modem passes radio headers with their on-air FEC, so they are sent the same way
+	netFrames int
+	lastRF    time.Time
+	lastNet   time.Time
+}
+
+// NewControl creates a new Control for the given module.
+func NewControl(module string) *Control {
+	m := byte(' ')
+	if module = strings.TrimSpace(module); module != "" {
+		m = strings.ToUpper(module)[0]
+	}
+
+	return &Control{
+		Module:   m,
+		RFState:  StateListening,
+		NetState: StateIdle,
+		rfSlow:   NewSlowDataDecoder(),
+	}
+}
+
+// WriteModem handles a frame received from the modem: a tag byte followed by the payload.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case TagHeader:
+		return c.writeRFHeader(data[1:])
+	case TagData:
+		return c.writeRFData(data[1:])
+	case TagLost:
+		if c.RFState == StateAudio {
+			log.Printf("D-Star: RF transmission lost from %s", c.RFHeader)
+		}
+		c.endOfRF()
+		return false
+	case TagEOT:
+		if c.RFState == StateAudio && c.Network != nil {
+			c.Network.WriteData(EndPatternBytes, 0, true)
+		}
+		c.endOfRF()
+		return true
+	default:
+		log.Printf("D-Star: Unknown modem tag 0x%02X", data[0])
+		return false
+	}
+}
+
+// writeRFHeader validates an RF header and starts a new RF stream. The header is either the decoded 41 bytes
+// or, from modems passing the raw header, the 660 coded bits, which are decoded first.
+func (c *Control) writeRFHeader(data []byte) bool {
+	if len(data) >= RFHeaderLength {
+		decoded, errs, err := DecodeRFHeader(data)
+		if err != nil {
+			log.Printf("D-Star: Invalid RF header after correcting %d bits: %v", errs, err)
+			return false
+		}
+		if errs > 0 {
+			log.Printf("D-Star: Corrected %d bits of the RF header", errs)
+		}
+		c.rfCoded = true
+		data = decoded
+	}
+
+	header, err := ParseHeader(data)
+	if err != nil {
+		log.Printf("D-Star: Invalid RF header: %v", err)
+		return false
+	}
+
+	if c.NetState != StateIdle {
+		log.Printf("D-Star: RF header from %s ignored, network stream in progress", header)
+		return false
+	}
+
+	if !header.IsRepeater() {
+		log.Printf("D-Star: Non-repeater RF header from %s rejected", header)
+		c.RFState = StateRejected
+		return false
+	}
+
+	if header.Module() != c.Module {
+		log.Printf("D-Star: RF header from %s for module %c rejected, this is module %c", header, header.Module(), c.Module)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFHeader = header
+	c.RFState = StateAudio
+	c.rfFrames = 0
+	c.rfErrors = 0
+	c.rfSlow.Reset()
+	c.lastRF = time.Now()
+	log.Printf("D-Star: RF header from %s", header)
+	c.notify("rf_start", header)
+
+	if c.Network != nil {
+		if err := c.Network.WriteHeader(header); err != nil {
+			log.Printf("D-Star: Unable to forward RF header: %v", err)
+		}
+	}
+	return true
+}
+
+// writeRFData regenerates and forwards a voice frame of the current RF stream.
+func (c *Control) writeRFData(data []byte) bool {
+	if c.RFState != StateAudio {
+		return false
+	}
+	if len(data) < FrameLength {
+		log.Printf("D-Star: RF frame too short: %d bytes", len(data))
+		return false
+	}
+
+	frame := append([]byte{}, data[:FrameLength]...)
+	c.lastRF = time.Now()
+
+	if bytes.HasPrefix(frame, EndPatternBytes) {
+		if c.Network != nil {
+			c.Network.WriteData(EndPatternBytes, 0, true)
+		}
+		c.endOfRF()
+		return true
+	}
+
+	errs, err := ambe.Regenerate2400(frame[:9])
+	if err != nil {
+		log.Printf("D-Star: Unable to regenerate AMBE frame: %v", err)
+		return false
+	}
+
+	c.rfFrames++
+	c.rfErrors += errs
+
+	if event := c.rfSlow.Add(frame[9:]); event != nil {
+		c.logSlowData(event)
+		if c.SlowData != nil {
+			c.SlowData(event)
+		}
+	}
+
+	if c.Network != nil {
+		if err := c.Network.WriteData(frame, errs, false); err != nil {
+			log.Printf("D-Star: Unable to forward RF frame: %v", err)
+		}
+	}
+	return true
+}
+
+// endOfRF ends the current RF stream and logs its statistics.
+func (c *Control) endOfRF() {
+	if c.RFState == StateAudio {
+		ber := 0.0
+		if c.rfFrames > 0 {
+			ber = float64(c.rfErrors) * 100.0 / float64(c.rfFrames*ambeProtectedBits)
+		}
+		log.Printf("D-Star: RF end of transmission from %s, %.1f seconds, BER: %.1f%%", c.RFHeader, float64(c.rfFrames)*0.02, ber)
+		c.notify("rf_end", c.RFHeader)
+	}
+	c.RFState = StateListening
+	c.RFHeader = nil
+}
+
+// WriteNetworkHeader starts a network stream, transmitting its header on RF.
+// It returns false when RF is busy.
+func (c *Control) WriteNetworkHeader(header *Header) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio {
+		log.Printf("D-Star: Network header from %s ignored, RF stream in progress", header)
+		return false
+	}
+
+	c.NetHeader = header
+	c.NetState = StateAudio
+	c.netFrames = 0
+	c.netSlow = nil
+	if c.StatusText != "" {
+		c.netSlow = NewSlowDataEncoder(c.StatusText, header)
+	}
+	c.lastNet = time.Now()
+	log.Printf("D-Star: Network header from %s", header)
+	c.notify("net_start", header)
+
+	data := header.Bytes()
+	if c.rfCoded {
+		data, _ = EncodeRFHeader(data)
+	}
+	c.output(append([]byte{TagHeader}, data...))
+	return true
+}
+
+// WriteNetworkData transmits a voice frame of the current network stream on RF.
+// The end flag closes the stream.
+func (c *Control) WriteNetworkData(data []byte, end bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.NetState != StateAudio {
+		return false
+	}
+	c.lastNet = time.Now()
+
+	if end || bytes.HasPrefix(data, EndPatternBytes) {
+		c.output([]byte{TagEOT})
+		c.endOfNetwork()
+		return true
+	}
+
+	if len(data) < FrameLength {
+		log.Printf("D-Star: Network frame too short: %d bytes", len(data))
+		return false
+	}
+
+	frame := append([]byte{}, data[:FrameLength]...)
+	if c.netSlow != nil {
+		if bytes.Equal(frame[9:], SyncBytes) {
+			c.netSlow.Sync()
+		} else {
+			copy(frame[9:], c.netSlow.Next())
+		}
+	}
+
+	c.netFrames++
+	c.output(append([]byte{TagData}, frame...))
+	return true
+}
+
+// endOfNetwork ends the current network stream.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("D-Star: Network end of transmission from %s, %.1f seconds", c.NetHeader, float64(c.netFrames)*0.02)
+		c.notify("net_end", c.NetHeader)
+	}
+	c.NetState = StateIdle
+	c.NetHeader = nil
+}
+
+// CheckTimeouts ends RF or network streams that have stopped sending frames.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("D-Star: RF stream from %s timed out", c.RFHeader)
+		if c.Network != nil {
+			c.Network.WriteData(EndPatternBytes, 0, true)
+		}
+		c.endOfRF()
+	}
+
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		log.Printf("D-Star: Network stream from %s timed out", c.NetHeader)
+		c.output([]byte{TagEOT})
+		c.endOfNetwork()
+	}
+}
+
+// logSlowData logs a decoded slow data item.
+func (c *Control) logSlowData(event *SlowDataEvent) {
+	switch event.Type {
+	case SlowDataText:
+		log.Printf("D-Star: Slow data text: %q", event.Text)
+	case SlowDataHeader:
+		log.Printf("D-Star: Slow data header: %s", event.Header)
+	case SlowDataGPS:
+		log.Printf("D-Star: Slow data GPS: %s", event.Text)
+	case SlowDataSquelch:
+		log.Printf("D-Star: Slow data squelch code: %d", event.Code)
+	}
+}
+
+// output passes a modem frame to the Output callback.
+func (c *Control) output(data []byte) {
+	if c.Output != nil {
+		c.Output(data)
+	}
+}
+
+// notify passes a stream event to the Events callback.
+func (c *Control) notify(event string, header *Header) {
+	if c.Events != nil {
+		c.Events(event, header)
+	}
+}
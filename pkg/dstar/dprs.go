@@ -0,0 +1,80 @@
+// Package dstar provides D-Star protocol logic, including DPRS position reports.
+package dstar
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// dprsPrefix starts a DPRS sentence, followed by four hex digits of CRC and a comma.
+const dprsPrefix = "$$CRC"
+
+// dprsHeaderLength is the length of the "$$CRCxxxx," prefix, after which the CRC is calculated.
+const dprsHeaderLength = 10
+
+// DPRS is a position report decoded from the slow data GPS channel.
+type DPRS struct {
+	Source      string // Callsign and SSID of the station
+	Destination string // APRS destination, normally the software identifier
+	Path        string // Path given by the radio, normally DSTAR*
+	Body        string // APRS information field, starting with the data type identifier
+}
+
+// IsDPRS reports whether a slow data GPS sentence is a DPRS sentence.
+func IsDPRS(sentence string) bool {
+	return strings.HasPrefix(sentence, dprsPrefix)
+}
+
+// ParseDPRS validates the CRC of a DPRS sentence ($$CRCxxxx,SRC>DEST,PATH:body) and splits it into its fields.
+// Only position reports are accepted.
+func ParseDPRS(sentence string) (*DPRS, error) {
+	sentence = strings.TrimRight(sentence, "\r\n")
+	if !IsDPRS(sentence) || len(sentence) <= dprsHeaderLength || sentence[dprsHeaderLength-1] != ',' {
+		return nil, errors.New("not a DPRS sentence")
+	}
+
+	expected, err := strconv.ParseUint(sentence[len(dprsPrefix):dprsHeaderLength-1], 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DPRS CRC field: %w", err)
+	}
+
+	// Radios differ in whether the terminating CR is covered by the CRC, so accept either
+	text := sentence[dprsHeaderLength:]
+	crc := crcCCITT([]byte(text))
+	if crc != uint16(expected) && crcCCITT([]byte(text+"\r")) != uint16(expected) {
+		return nil, fmt.Errorf("DPRS CRC mismatch: got %04X, expected %04X", crc, expected)
+	}
+
+	colon := strings.IndexByte(text, ':')
+	if colon < 0 || colon == len(text)-1 {
+		return nil, errors.New("DPRS sentence has no information field")
+	}
+	address, body := text[:colon], text[colon+1:]
+
+	gt := strings.IndexByte(address, '>')
+	if gt <= 0 {
+		return nil, errors.New("DPRS sentence has no source callsign")
+	}
+
+	d := &DPRS{Source: strings.TrimSpace(address[:gt]), Body: body}
+	d.Destination = address[gt+1:]
+	if comma := strings.IndexByte(d.Destination, ','); comma >= 0 {
+		d.Path = d.Destination[comma+1:]
+		d.Destination = d.Destination[:comma]
+	}
+
+	switch body[0] {
+	case '!', '=', '/', '@':
+	default:
+		return nil, fmt.Errorf("DPRS data type %q is not a position", body[0])
+	}
+
+	return d, nil
+}
+
+// APRS formats the report as an APRS-IS packet gated by the given station, such as the repeater callsign.
+func (d *DPRS) APRS(gateway string) string {
+	return fmt.Sprintf("%s>%s,DSTAR*,qAR,%s:%s", d.Source, d.Destination, gateway, d.Body)
+}
@@ -2,16 +2,134 @@ package dstar
 
 import (
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control handles the D-Star streams for the configured module.
+var control *Control
+
+// network links the controller to an ircDDBGateway, nil when disabled.
+var network *Network
+
+// HandleDStarPacket passes a modem frame (tag byte plus payload) to the D-Star controller.
 func HandleDStarPacket(packet []byte) {
-	// TODO: Add D-Star packet handling logic
+	if control == nil {
+		log.Printf("D-Star: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// modemCommands maps the tags of controller frames to the modem commands transmitting them.
+var modemCommands = map[byte]byte{
+	TagHeader: modem.CmdDStarHeader,
+	TagData:   modem.CmdDStarData,
+	TagEOT:    modem.CmdDStarEOT,
+}
+
+// writeModem transmits a controller frame (tag plus payload) on the modem.
+func writeModem(data []byte) {
+	command, ok := modemCommands[data[0]]
+	if !ok {
+		log.Printf("D-Star: No modem command for tag 0x%02X", data[0])
+		return
+	}
+	if err := modem.Write(command, data[1:]); err != nil {
+		log.Printf("D-Star: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so RF streams end when the ircDDBGateway link is disabled or down.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
 }
 
 // Init initializes the D-Star protocol handler with the given configuration.
 func Init(cfg config.DStarConfig) {
-	// TODO: Add initialization logic for D-Star
+	control = NewControl(cfg.Module)
+	control.Events = func(event string, _ *Header) { cwid.Event(event) }
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+	control.StatusText = cfg.StatusText
+	control.SlowData = gateDPRS
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if cfg.Enable && cfg.NetworkEnable {
+		initNetwork(cfg)
+	}
+	startClock(control)
 	fmt.Printf("D-Star protocol handler initialized with Module: %s\n", cfg.Module)
 }
+
+// initNetwork opens the ircDDBGateway link and connects it to the controller.
+func initNetwork(cfg config.DStarConfig) {
+	n, err := NewNetwork(cfg.GatewayAddress, cfg.GatewayPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("D-Star: %v", err)
+		return
+	}
+
+	c := control
+	n.Header = func(header *Header) { c.WriteNetworkHeader(header) }
+	n.Data = func(data []byte, end bool) { c.WriteNetworkData(data, end) }
+	n.Text = func(text string) { log.Printf("D-Star: Gateway status: %s", text) }
+
+	if err := n.Open(); err != nil {
+		log.Printf("D-Star: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
+}
+
+// gateDPRS passes DPRS positions heard on RF to APRS, gated by the APRS-IS login callsign.
+func gateDPRS(event *SlowDataEvent) {
+	if event.Type != SlowDataGPS || !IsDPRS(event.Text) {
+		return
+	}
+	gateway := ax25.GatewayCallsign()
+	if gateway == "" {
+		return
+	}
+
+	position, err := ParseDPRS(event.Text)
+	if err != nil {
+		log.Printf("D-Star: Invalid DPRS sentence: %v", err)
+		return
+	}
+	ax25.SendAPRS(position.APRS(gateway))
+}
@@ -0,0 +1,133 @@
+// Package dstar provides D-Star protocol logic, including the 41-byte radio header.
+package dstar
+
+import (
+	"errors"
+	"strings"
+)
+
+// HeaderLength is the length of a D-Star radio header including its CRC.
+const HeaderLength = 41
+
+// Flag 1 bits of the radio header.
+const (
+	FlagData       = 0x80 // Data transmission rather than voice
+	FlagRepeater   = 0x40 // Transmission is via a repeater
+	FlagInterrupt  = 0x20 // Communication interrupted
+	FlagControl    = 0x10 // Control signal
+	FlagUrgent     = 0x08 // Urgent priority
+	FlagSwitchMask = 0x07 // Repeater control switch
+)
+
+// Header is a decoded D-Star radio header.
+type Header struct {
+	Flag1    byte   // Control flags
+	Flag2    byte   // Reserved flags
+	Flag3    byte   // Reserved flags
+	RPT2     string // Destination repeater callsign (8 characters)
+	RPT1     string // Departure repeater callsign (8 characters)
+	YourCall string // Companion callsign (8 characters)
+	MyCall   string // Own callsign (8 characters)
+	MySuffix string // Own callsign suffix (4 characters)
+}
+
+// ParseHeader decodes a 41-byte radio header, validating its CRC-CCITT.
+func ParseHeader(data []byte) (*Header, error) {
+	if len(data) < HeaderLength {
+		return nil, errors.New("header too short")
+	}
+	if !CheckCRC(data[:HeaderLength]) {
+		return nil, errors.New("invalid header CRC")
+	}
+
+	return &Header{
+		Flag1:    data[0],
+		Flag2:    data[1],
+		Flag3:    data[2],
+		RPT2:     string(data[3:11]),
+		RPT1:     string(data[11:19]),
+		YourCall: string(data[19:27]),
+		MyCall:   string(data[27:35]),
+		MySuffix: string(data[35:39]),
+	}, nil
+}
+
+// Bytes encodes the header into 41 bytes with the CRC appended.
+func (h *Header) Bytes() []byte {
+	data := make([]byte, HeaderLength)
+	data[0] = h.Flag1
+	data[1] = h.Flag2
+	data[2] = h.Flag3
+	copy(data[3:11], padCallsign(h.RPT2, 8))
+	copy(data[11:19], padCallsign(h.RPT1, 8))
+	copy(data[19:27], padCallsign(h.YourCall, 8))
+	copy(data[27:35], padCallsign(h.MyCall, 8))
+	copy(data[35:39], padCallsign(h.MySuffix, 4))
+	AddCRC(data)
+	return data
+}
+
+// IsRepeater reports whether the transmission is addressed via a repeater.
+func (h *Header) IsRepeater() bool {
+	return h.Flag1&FlagRepeater == FlagRepeater
+}
+
+// IsData reports whether the header announces a data rather than a voice transmission.
+func (h *Header) IsData() bool {
+	return h.Flag1&FlagData == FlagData
+}
+
+// Module returns the module letter of the departure repeater.
+func (h *Header) Module() byte {
+	if len(h.RPT1) < 8 {
+		return ' '
+	}
+	return h.RPT1[7]
+}
+
+// String returns a summary of the header callsigns.
+func (h *Header) String() string {
+	return strings.TrimSpace(h.MyCall) + "/" + strings.TrimSpace(h.MySuffix) + " to " + strings.TrimSpace(h.YourCall) +
+		" via " + strings.TrimSpace(h.RPT1) + " " + strings.TrimSpace(h.RPT2)
+}
+
+// padCallsign returns the callsign truncated or space padded to the given length.
+func padCallsign(callsign string, length int) []byte {
+	padded := []byte(callsign + strings.Repeat(" ", length))
+	return padded[:length]
+}
+
+// crcCCITT computes the CRC-CCITT (X.25) used by D-Star: reflected polynomial 0x8408, initial value 0xFFFF, inverted result.
+func crcCCITT(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+// AddCRC writes the CRC of all but the last two bytes into the last two bytes, low byte first.
+func AddCRC(data []byte) {
+	if len(data) < 2 {
+		return
+	}
+	crc := crcCCITT(data[:len(data)-2])
+	data[len(data)-2] = byte(crc)
+	data[len(data)-1] = byte(crc >> 8)
+}
+
+// CheckCRC validates the CRC held in the last two bytes, low byte first.
+func CheckCRC(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	crc := crcCCITT(data[:len(data)-2])
+	return data[len(data)-2] == byte(crc) && data[len(data)-1] == byte(crc>>8)
+}
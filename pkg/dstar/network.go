@@ -0,0 +1,391 @@
+// Package dstar provides D-Star protocol logic, including the ircDDBGateway network link.
+package dstar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DSRP packet types exchanged with ircDDBGateway.
+const (
+	dsrpText     = 0x00 // Gateway status text
+	dsrpTempText = 0x01 // Temporary text
+	dsrpStatus   = 0x04 // Link status
+	dsrpPoll     = 0x0A // Poll, sent by the repeater to keep the link alive
+	dsrpHeader   = 0x20 // Radio header
+	dsrpData     = 0x21 // Voice and slow data frame
+	dsrpDDData   = 0x24 // DD mode data, not supported
+)
+
+// Network timing.
+const (
+	pollInterval     = 60 * time.Second        // Interval between polls sent to the gateway
+	streamTimeout    = 1500 * time.Millisecond // Incoming stream watchdog
+	reconnectDelay   = 5 * time.Second         // Delay before reopening a failed socket
+	readPollInterval = 100 * time.Millisecond  // Read deadline used to run the timers
+)
+
+// maxNetworkGap is the largest number of frames treated as lost between two received frames. A sequence
+// number further ahead is taken to be behind the current one, from a late or repeated packet.
+const maxNetworkGap = framesPerSuperframe / 2
+
+// networkVersion identifies this software to the gateway in polls.
+const networkVersion = "mmdvm_ghost"
+
+// dsrpSignature starts every DSRP packet.
+var dsrpSignature = []byte("DSRP")
+
+// Network is a UDP client speaking the DSRP protocol to an ircDDBGateway on the same host or LAN.
+type Network struct {
+	Header func(header *Header)        // Receives headers of incoming streams, may be nil
+	Data   func(data []byte, end bool) // Receives frames of incoming streams, may be nil
+	Text   func(text string)           // Receives gateway status text, may be nil
+
+	gateway   *net.UDPAddr
+	localPort int
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	stop     chan struct{}
+	outID    uint16
+	outSeq   byte
+	inID     uint16
+	inSeq    byte
+	lastIn   time.Time
+	lastPoll time.Time
+}
+
+// NewNetwork creates a new Network for the gateway at the given address and port,
+// listening on the given local port.
+func NewNetwork(gatewayAddress string, gatewayPort, localPort int) (*Network, error) {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", gatewayAddress, gatewayPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gateway address: %w", err)
+	}
+
+	return &Network{
+		gateway:   addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts the receive loop.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("D-Star: Network opened to gateway %s", n.gateway)
+	return nil
+}
+
+// Close stops the receive loop and closes the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("D-Star: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open D-Star network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// WriteHeader starts a new outgoing stream and sends its header to the gateway.
+func (n *Network) WriteHeader(header *Header) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.outID = uint16(rand.Intn(65535) + 1)
+	n.outSeq = 0
+
+	buffer := make([]byte, 0, 8+HeaderLength)
+	buffer = append(buffer, dsrpSignature...)
+	buffer = append(buffer, dsrpHeader, byte(n.outID>>8), byte(n.outID), 0)
+	buffer = append(buffer, header.Bytes()...)
+
+	// The header is sent twice to improve the odds of it getting through
+	if err := n.write(buffer); err != nil {
+		return err
+	}
+	return n.write(buffer)
+}
+
+// WriteData sends a 12-byte frame of the current outgoing stream to the gateway,
+// with the number of bit errors found in it. The end flag closes the stream.
+func (n *Network) WriteData(data []byte, errs int, end bool) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	frame := make([]byte, FrameLength)
+	copy(frame, data)
+
+	// A data sync resets the sequence to zero
+	if bytes.Equal(frame[9:12], SyncBytes) {
+		n.outSeq = 0
+	}
+
+	seq := n.outSeq
+	if end {
+		seq |= 0x40
+	}
+	if errs > 255 {
+		errs = 255
+	}
+
+	buffer := make([]byte, 0, 9+FrameLength)
+	buffer = append(buffer, dsrpSignature...)
+	buffer = append(buffer, dsrpData, byte(n.outID>>8), byte(n.outID), seq, byte(errs))
+	buffer = append(buffer, frame...)
+
+	n.outSeq++
+	if n.outSeq >= framesPerSuperframe {
+		n.outSeq = 0
+	}
+
+	if end {
+		// The end of stream is sent twice like the header
+		if err := n.write(buffer); err != nil {
+			return err
+		}
+	}
+	return n.write(buffer)
+}
+
+// WritePoll sends a poll carrying the software version to the gateway.
+func (n *Network) WritePoll() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	buffer := append(append([]byte{}, dsrpSignature...), dsrpPoll)
+	buffer = append(buffer, networkVersion...)
+	buffer = append(buffer, 0)
+
+	n.lastPoll = time.Now()
+	return n.write(buffer)
+}
+
+// write sends a packet to the gateway. The caller must hold the lock.
+func (n *Network) write(buffer []byte) error {
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.gateway)
+	return err
+}
+
+// run receives packets, sends polls and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	if err := n.WritePoll(); err != nil {
+		log.Printf("D-Star: Unable to poll gateway: %v", err)
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(readPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+		now := time.Now()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("D-Star: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.gateway.IP) && addr.Port == n.gateway.Port {
+			n.receive(buffer[:length], now)
+		} else {
+			log.Printf("D-Star: Packet received from unknown address %s", addr)
+		}
+
+		n.clock(now)
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(reconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("D-Star: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("D-Star: Network reconnected to gateway %s", n.gateway)
+	return true
+}
+
+// clock runs the poll timer and the incoming stream watchdog.
+func (n *Network) clock(now time.Time) {
+	n.mu.Lock()
+	poll := now.Sub(n.lastPoll) >= pollInterval
+	expired := n.inID != 0 && now.Sub(n.lastIn) > streamTimeout
+	if expired {
+		log.Printf("D-Star: Network stream 0x%04X timed out", n.inID)
+		n.inID = 0
+	}
+	n.mu.Unlock()
+
+	if poll {
+		if err := n.WritePoll(); err != nil {
+			log.Printf("D-Star: Unable to poll gateway: %v", err)
+		}
+	}
+	if expired && n.Data != nil {
+		n.Data(EndPatternBytes, true)
+	}
+}
+
+// receive dispatches a DSRP packet from the gateway.
+func (n *Network) receive(buffer []byte, now time.Time) {
+	if len(buffer) < 5 || !bytes.Equal(buffer[:4], dsrpSignature) {
+		log.Printf("D-Star: Invalid packet from gateway: %x", buffer)
+		return
+	}
+
+	switch buffer[4] {
+	case dsrpText:
+		if len(buffer) >= 5+textLength && n.Text != nil {
+			n.Text(strings.TrimRight(string(buffer[5:5+textLength]), " \x00"))
+		}
+
+	case dsrpHeader:
+		if len(buffer) < 8+HeaderLength {
+			return
+		}
+		header, err := ParseHeader(buffer[8 : 8+HeaderLength])
+		if err != nil {
+			log.Printf("D-Star: Invalid network header: %v", err)
+			return
+		}
+
+		id := uint16(buffer[5])<<8 | uint16(buffer[6])
+		n.mu.Lock()
+		if n.inID != 0 {
+			// Either a repeat of the current header or another stream while busy
+			n.mu.Unlock()
+			return
+		}
+		n.inID = id
+		n.inSeq = 0
+		n.lastIn = now
+		n.mu.Unlock()
+
+		if n.Header != nil {
+			n.Header(header)
+		}
+
+	case dsrpData:
+		if len(buffer) < 9+FrameLength {
+			return
+		}
+
+		id := uint16(buffer[5])<<8 | uint16(buffer[6])
+		seq := buffer[7] & 0x1F
+		end := buffer[7]&0x40 == 0x40
+		frame := buffer[9 : 9+FrameLength]
+
+		n.mu.Lock()
+		if n.inID != id {
+			n.mu.Unlock()
+			return
+		}
+		missing := 0
+		if !end {
+			missing = (int(seq) - int(n.inSeq) + framesPerSuperframe) % framesPerSuperframe
+			if missing > maxNetworkGap {
+				// Late or duplicate, the frame has been played or replaced by silence already
+				n.mu.Unlock()
+				return
+			}
+			n.inSeq = (seq + 1) % framesPerSuperframe
+		}
+		n.lastIn = now
+		if end {
+			n.inID = 0
+		}
+		n.mu.Unlock()
+
+		if n.Data == nil {
+			return
+		}
+		// Fill frames lost on the network with silence
+		for i := 0; i < missing; i++ {
+			n.Data(NullFrame, false)
+		}
+		n.Data(frame, end)
+
+	case dsrpTempText, dsrpStatus, dsrpDDData, dsrpPoll:
+		// Not used by the repeater
+
+	default:
+		log.Printf("D-Star: Unknown network packet type 0x%02X", buffer[4])
+	}
+}
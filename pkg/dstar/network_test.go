@@ -0,0 +1,56 @@
+package dstar
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// dsrpHeaderPacket builds a DSRP header packet for stream 0x1234.
+func dsrpHeaderPacket(header *Header) []byte {
+	packet := append([]byte("DSRP"), dsrpHeader, 0x12, 0x34, 0)
+	return append(packet, header.Bytes()...)
+}
+
+// dsrpDataPacket builds a DSRP data packet for stream 0x1234.
+func dsrpDataPacket(seq byte, frame []byte) []byte {
+	packet := append([]byte("DSRP"), dsrpData, 0x12, 0x34, seq, 0)
+	return append(packet, frame...)
+}
+
+func TestNetworkSequence(t *testing.T) {
+	n, err := NewNetwork("127.0.0.1", 20010, 20011)
+	if err != nil {
+		t.Fatalf("NewNetwork: %v", err)
+	}
+
+	var frames []byte
+	n.Header = func(*Header) {}
+	n.Data = func(data []byte, end bool) {
+		switch {
+		case end:
+			frames = append(frames, 'E')
+		case bytes.Equal(data, NullFrame):
+			frames = append(frames, '-')
+		default:
+			frames = append(frames, data[0])
+		}
+	}
+
+	now := time.Now()
+	frame := func(seq byte) []byte {
+		data := make([]byte, FrameLength)
+		data[0] = '0' + seq
+		return dsrpDataPacket(seq, data)
+	}
+	n.receive(dsrpHeaderPacket(testHeader), now)
+	for _, seq := range []byte{0, 1, 1, 0, 4, 2, 5} {
+		n.receive(frame(seq), now)
+	}
+	n.receive(dsrpDataPacket(0x40|6, make([]byte, FrameLength)), now)
+
+	// The repeated 1, the late 0 and the late 2 are dropped; 2 and 3 are filled with silence
+	if got, want := string(frames), "01--45E"; got != want {
+		t.Errorf("network frames = %q, want %q", got, want)
+	}
+}
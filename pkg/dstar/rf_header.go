@@ -0,0 +1,163 @@
+// Package dstar provides D-Star protocol logic, including the FEC applied to the radio header on air.
+package dstar
+
+import (
+	"errors"
+)
+
+// The radio header is sent on air as 41 bytes plus 2 flush bits, convolutionally coded at rate 1/2,
+// interleaved and scrambled, giving 660 bits.
+const rfHeaderBits = 660
+
+// RFHeaderLength is the length in bytes of the encoded radio header (660 bits, LSB first).
+const RFHeaderLength = (rfHeaderBits + 7) / 8
+
+// scrambleSequence is the x^7 + x^4 + 1 PN sequence, seeded with all ones, XORed with the encoded header.
+var scrambleSequence = buildScrambleSequence()
+
+// interleaveOrder maps each on-air bit position to its position in the convolutionally coded stream.
+var interleaveOrder = buildInterleaveOrder()
+
+// buildScrambleSequence generates the 660-bit scrambling sequence.
+func buildScrambleSequence() []byte {
+	seq := make([]byte, rfHeaderBits)
+	state := byte(0x7F)
+	for i := range seq {
+		feedback := (state>>3 ^ state>>6) & 1
+		seq[i] = feedback
+		state = (state<<1 | feedback) & 0x7F
+	}
+	return seq
+}
+
+// buildInterleaveOrder generates the interleaver, which writes the coded bits into 24 rows by 28 columns
+// (the last 12 columns one row short) and reads them out column by column.
+func buildInterleaveOrder() []int {
+	order := make([]int, rfHeaderBits)
+	k := 0
+	for i := range order {
+		order[i] = k
+		k += 24
+		if k >= 672 {
+			k -= 671
+		} else if k >= 660 {
+			k -= 647
+		}
+	}
+	return order
+}
+
+// unpackBits converts bytes to bits, LSB first as transmitted by D-Star.
+func unpackBits(data []byte, count int) []byte {
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = data[i/8] >> uint(i%8) & 1
+	}
+	return out
+}
+
+// packBits converts bits to bytes, LSB first as transmitted by D-Star.
+func packBits(in []byte) []byte {
+	out := make([]byte, (len(in)+7)/8)
+	for i, b := range in {
+		out[i/8] |= (b & 1) << uint(i%8)
+	}
+	return out
+}
+
+// convolve encodes bits with the K=3 rate 1/2 code, G1 = 1 + D + D^2 and G2 = 1 + D^2.
+func convolve(in []byte) []byte {
+	out := make([]byte, 0, len(in)*2)
+	var d1, d2 byte
+	for _, b := range in {
+		out = append(out, b^d1^d2, b^d2)
+		d2, d1 = d1, b
+	}
+	return out
+}
+
+// viterbi decodes the K=3 rate 1/2 code with hard decisions, returning the decoded bits and the path error count.
+func viterbi(in []byte) ([]byte, int) {
+	steps := len(in) / 2
+	const inf = 1 << 30
+
+	// The state holds the previous two input bits as d1<<1 | d2
+	metrics := [4]int{0, inf, inf, inf}
+	history := make([][4]byte, steps)
+
+	for n := 0; n < steps; n++ {
+		next := [4]int{inf, inf, inf, inf}
+		var from [4]byte
+		for state := 0; state < 4; state++ {
+			if metrics[state] >= inf {
+				continue
+			}
+			d1, d2 := byte(state>>1), byte(state&1)
+			for b := byte(0); b < 2; b++ {
+				g1, g2 := b^d1^d2, b^d2
+				cost := metrics[state]
+				if g1 != in[2*n] {
+					cost++
+				}
+				if g2 != in[2*n+1] {
+					cost++
+				}
+				ns := int(b<<1 | d1)
+				if cost < next[ns] {
+					next[ns] = cost
+					from[ns] = byte(state)
+				}
+			}
+		}
+		metrics = next
+		history[n] = from
+	}
+
+	// The flush bits leave the encoder in state 0
+	state := 0
+	errs := metrics[0]
+	out := make([]byte, steps)
+	for n := steps - 1; n >= 0; n-- {
+		out[n] = byte(state >> 1)
+		state = int(history[n][state])
+	}
+	return out, errs
+}
+
+// DecodeRFHeader descrambles, deinterleaves and Viterbi decodes a 660-bit radio header (83 bytes, LSB first).
+// It returns the 41-byte header and the number of bit errors corrected, or an error when the CRC fails.
+func DecodeRFHeader(data []byte) ([]byte, int, error) {
+	if len(data) < RFHeaderLength {
+		return nil, 0, errors.New("RF header too short")
+	}
+
+	received := unpackBits(data, rfHeaderBits)
+	coded := make([]byte, rfHeaderBits)
+	for i, b := range received {
+		coded[interleaveOrder[i]] = b ^ scrambleSequence[i]
+	}
+
+	decoded, errs := viterbi(coded)
+	header := packBits(decoded[:HeaderLength*8])
+	if !CheckCRC(header) {
+		return nil, errs, errors.New("invalid header CRC")
+	}
+
+	return header, errs, nil
+}
+
+// EncodeRFHeader convolutionally encodes, interleaves and scrambles a 41-byte radio header into 660 bits (83 bytes, LSB first).
+func EncodeRFHeader(header []byte) ([]byte, error) {
+	if len(header) < HeaderLength {
+		return nil, errors.New("header too short")
+	}
+
+	in := append(unpackBits(header, HeaderLength*8), 0, 0)
+	coded := convolve(in)
+
+	out := make([]byte, rfHeaderBits)
+	for i := range out {
+		out[i] = coded[interleaveOrder[i]] ^ scrambleSequence[i]
+	}
+	return packBits(out), nil
+}
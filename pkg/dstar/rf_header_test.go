@@ -0,0 +1,88 @@
+package dstar
+
+import (
+	"bytes"
+	"testing"
+)
+
+// scramblePrefix is the start of the published D-Star scrambling sequence, MSB first.
+var scramblePrefix = []byte{0x0E, 0xF2, 0xC9, 0x02, 0x26, 0x2E, 0xB6, 0x0C}
+
+// testHeader is a repeater header for module B.
+var testHeader = &Header{
+	Flag1:    FlagRepeater,
+	RPT2:     "GB7XX  G",
+	RPT1:     "GB7XX  B",
+	YourCall: "CQCQCQ",
+	MyCall:   "G4XYZ",
+	MySuffix: "ID51",
+}
+
+func TestScrambleSequence(t *testing.T) {
+	for i, want := range scramblePrefix {
+		var got byte
+		for _, bit := range scrambleSequence[i*8 : i*8+8] {
+			got = got<<1 | bit
+		}
+		if got != want {
+			t.Errorf("scrambling byte %d = 0x%02X, want 0x%02X", i, got, want)
+		}
+	}
+}
+
+func TestRFHeader(t *testing.T) {
+	header := testHeader.Bytes()
+	coded, err := EncodeRFHeader(header)
+	if err != nil {
+		t.Fatalf("EncodeRFHeader: %v", err)
+	}
+	if len(coded) != RFHeaderLength {
+		t.Fatalf("coded header is %d bytes, want %d", len(coded), RFHeaderLength)
+	}
+
+	decoded, errs, err := DecodeRFHeader(coded)
+	if err != nil || errs != 0 || !bytes.Equal(decoded, header) {
+		t.Fatalf("DecodeRFHeader = % X, %d, %v", decoded, errs, err)
+	}
+
+	// Scattered bit errors are corrected
+	for _, bit := range []int{3, 100, 250, 401, 599} {
+		coded[bit/8] ^= 1 << uint(bit%8)
+	}
+	decoded, errs, err = DecodeRFHeader(coded)
+	if err != nil || errs != 5 || !bytes.Equal(decoded, header) {
+		t.Errorf("DecodeRFHeader with 5 bit errors = % X, %d, %v", decoded, errs, err)
+	}
+}
+
+func TestControlCodedRFHeader(t *testing.T) {
+	c := NewControl("B")
+	var sent [][]byte
+	c.Output = func(data []byte) { sent = append(sent, data) }
+
+	// A header decoded by the modem is passed on as it is
+	c.WriteNetworkHeader(testHeader)
+	c.WriteNetworkData(EndPatternBytes, true)
+	if len(sent) == 0 {
+		t.Fatal("network header not sent")
+	}
+	if len(sent[0]) != 1+HeaderLength {
+		t.Fatalf("network header sent as %d bytes, want %d", len(sent[0]), 1+HeaderLength)
+	}
+
+	// A modem passing coded headers gets coded headers back
+	coded, _ := EncodeRFHeader(testHeader.Bytes())
+	if !c.WriteModem(append([]byte{TagHeader}, coded...)) {
+		t.Fatal("coded RF header rejected")
+	}
+	if c.RFHeader == nil || c.RFHeader.MyCall != "G4XYZ   " {
+		t.Errorf("RF header = %v", c.RFHeader)
+	}
+	c.WriteModem([]byte{TagEOT})
+
+	sent = nil
+	c.WriteNetworkHeader(testHeader)
+	if len(sent) == 0 || !bytes.Equal(sent[0], append([]byte{TagHeader}, coded...)) {
+		t.Errorf("network header not sent coded after a coded RF header")
+	}
+}
@@ -0,0 +1,213 @@
+// Package dstar provides D-Star protocol logic, including the slow data channel.
+package dstar
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Slow data block types, held in the high nibble of the first byte of each 6-byte block.
+const (
+	SlowDataGPS     = 0x30 // GPS/DPRS sentence data, low nibble is the byte count
+	SlowDataText    = 0x40 // Text message, low nibble is the block index 0 to 3
+	SlowDataHeader  = 0x50 // Header retransmission, low nibble is the byte count
+	SlowDataFiller  = 0x60 // Filler
+	SlowDataSquelch = 0xC0 // Code squelch, low nibble is the byte count
+	slowDataMask    = 0xF0
+	slowDataLength  = 0x0F
+)
+
+// slowDataScrambler is XORed with the three slow data bytes of every non-sync frame.
+var slowDataScrambler = []byte{0x70, 0x4F, 0x93}
+
+// textLength is the length of a slow data text message.
+const textLength = 20
+
+// SlowDataEvent is a complete item decoded from the slow data channel.
+type SlowDataEvent struct {
+	Type   byte    // SlowDataText, SlowDataHeader, SlowDataGPS or SlowDataSquelch
+	Text   string  // Text message or GPS/DPRS sentence
+	Header *Header // Retransmitted header
+	Code   byte    // Squelch code
+}
+
+// SlowDataDecoder reassembles the slow data channel of an RF or network stream.
+type SlowDataDecoder struct {
+	block     [6]byte
+	half      bool
+	text      [textLength]byte
+	textMask  byte
+	header    []byte
+	gps       []byte
+	lastText  string
+	lastCode  byte
+	codeKnown bool
+}
+
+// NewSlowDataDecoder creates a new SlowDataDecoder.
+func NewSlowDataDecoder() *SlowDataDecoder {
+	d := &SlowDataDecoder{}
+	d.Reset()
+	return d
+}
+
+// Reset clears any partially received data, ready for a new stream.
+func (d *SlowDataDecoder) Reset() {
+	d.half = false
+	d.textMask = 0
+	d.header = d.header[:0]
+	d.gps = d.gps[:0]
+	d.lastText = ""
+	d.codeKnown = false
+	for i := range d.text {
+		d.text[i] = ' '
+	}
+}
+
+// Add processes the three slow data bytes of a voice frame. Sync frames restart block alignment.
+// It returns an event when a text message, header, GPS sentence or squelch code is complete, otherwise nil.
+func (d *SlowDataDecoder) Add(data []byte) *SlowDataEvent {
+	if len(data) < 3 {
+		return nil
+	}
+
+	if bytes.Equal(data[:3], SyncBytes) {
+		d.half = false
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		d.block[i+boolIndex(d.half)*3] = data[i] ^ slowDataScrambler[i]
+	}
+
+	if !d.half {
+		d.half = true
+		return nil
+	}
+	d.half = false
+	return d.decodeBlock()
+}
+
+// boolIndex returns 1 for true and 0 for false.
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// decodeBlock interprets a complete 6-byte block.
+func (d *SlowDataDecoder) decodeBlock() *SlowDataEvent {
+	kind := d.block[0] & slowDataMask
+	length := int(d.block[0] & slowDataLength)
+	if length > 5 {
+		length = 5
+	}
+	payload := d.block[1 : 1+length]
+
+	switch kind {
+	case SlowDataText:
+		index := int(d.block[0] & 0x03)
+		copy(d.text[index*5:], d.block[1:6])
+		d.textMask |= 1 << uint(index)
+		if d.textMask == 0x0F {
+			d.textMask = 0
+			text := strings.TrimRight(string(d.text[:]), " \x00")
+			if text != d.lastText {
+				d.lastText = text
+				return &SlowDataEvent{Type: SlowDataText, Text: text}
+			}
+		}
+
+	case SlowDataHeader:
+		d.header = append(d.header, payload...)
+		if len(d.header) >= HeaderLength {
+			raw := d.header[:HeaderLength]
+			d.header = d.header[:0]
+			if header, err := ParseHeader(raw); err == nil {
+				return &SlowDataEvent{Type: SlowDataHeader, Header: header}
+			}
+		}
+
+	case SlowDataGPS:
+		d.gps = append(d.gps, payload...)
+		if end := bytes.IndexAny(d.gps, "\r\n"); end >= 0 {
+			sentence := strings.TrimSpace(string(d.gps[:end]))
+			d.gps = append(d.gps[:0], d.gps[end+1:]...)
+			if sentence != "" {
+				return &SlowDataEvent{Type: SlowDataGPS, Text: sentence}
+			}
+		}
+		if len(d.gps) > 256 {
+			d.gps = d.gps[:0] // Discard runaway data without a line terminator
+		}
+
+	case SlowDataSquelch:
+		if length > 0 && (!d.codeKnown || payload[0] != d.lastCode) {
+			d.lastCode = payload[0]
+			d.codeKnown = true
+			return &SlowDataEvent{Type: SlowDataSquelch, Code: payload[0]}
+		}
+	}
+
+	return nil
+}
+
+// SlowDataEncoder generates the slow data channel for an outgoing stream, carrying a status text
+// and, optionally, header retransmissions.
+type SlowDataEncoder struct {
+	blocks [][6]byte
+	pos    int
+	half   bool
+}
+
+// NewSlowDataEncoder creates a new SlowDataEncoder carrying the given text (truncated to 20 characters)
+// and header, which may be nil.
+func NewSlowDataEncoder(text string, header *Header) *SlowDataEncoder {
+	e := &SlowDataEncoder{}
+
+	padded := padCallsign(text, textLength)
+	for i := 0; i < 4; i++ {
+		var block [6]byte
+		block[0] = SlowDataText | byte(i)
+		copy(block[1:], padded[i*5:i*5+5])
+		e.blocks = append(e.blocks, block)
+	}
+
+	if header != nil {
+		raw := header.Bytes()
+		for i := 0; i < len(raw); i += 5 {
+			var block [6]byte
+			n := copy(block[1:], raw[i:])
+			for j := 1 + n; j < 6; j++ {
+				block[j] = 0x66
+			}
+			block[0] = SlowDataHeader | byte(n)
+			e.blocks = append(e.blocks, block)
+		}
+	}
+
+	return e
+}
+
+// Sync realigns the encoder with the start of a superframe.
+func (e *SlowDataEncoder) Sync() {
+	e.half = false
+}
+
+// Next returns the next three scrambled slow data bytes.
+func (e *SlowDataEncoder) Next() []byte {
+	block := e.blocks[e.pos]
+	offset := boolIndex(e.half) * 3
+
+	out := make([]byte, 3)
+	for i := 0; i < 3; i++ {
+		out[i] = block[offset+i] ^ slowDataScrambler[i]
+	}
+
+	if e.half {
+		e.pos = (e.pos + 1) % len(e.blocks)
+	}
+	e.half = !e.half
+	return out
+}
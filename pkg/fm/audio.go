@@ -0,0 +1,37 @@
+// Package fm provides analog FM protocol logic, including conversion between modem and PCM samples.
+package fm
+
+// UnpackSamples converts the packed 12-bit samples of a modem data frame to signed 16-bit PCM. Each three
+// bytes, read little endian, hold the first sample in the upper 12 bits and the second in the lower 12.
+func UnpackSamples(data []byte) []int16 {
+	samples := make([]int16, 0, len(data)/packedPerPair*2)
+	for i := 0; i+packedPerPair <= len(data); i += packedPerPair {
+		pack := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16
+		samples = append(samples, toPCM(pack>>12), toPCM(pack&0xFFF))
+	}
+	return samples
+}
+
+// PackSamples converts signed 16-bit PCM to packed 12-bit modem samples, padding an odd count with silence.
+func PackSamples(samples []int16) []byte {
+	data := make([]byte, 0, (len(samples)+1)/2*packedPerPair)
+	for i := 0; i < len(samples); i += 2 {
+		second := uint32(sampleOffset)
+		if i+1 < len(samples) {
+			second = fromPCM(samples[i+1])
+		}
+		pack := fromPCM(samples[i])<<12 | second
+		data = append(data, byte(pack), byte(pack>>8), byte(pack>>16))
+	}
+	return data
+}
+
+// toPCM converts an unsigned 12-bit sample to signed 16-bit PCM.
+func toPCM(sample uint32) int16 {
+	return int16((int32(sample) - sampleOffset) << 4)
+}
+
+// fromPCM converts a signed 16-bit PCM sample to an unsigned 12-bit sample.
+func fromPCM(sample int16) uint32 {
+	return uint32(int32(sample>>4) + sampleOffset)
+}
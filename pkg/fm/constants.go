@@ -0,0 +1,29 @@
+// Package fm provides analog FM protocol logic.
+package fm
+
+import "time"
+
+// MMDVM modem frames used by FM, identified by the command byte that precedes the payload.
+const (
+	CmdData   = 0x65 // Audio samples, in either direction
+	CmdStatus = 0x66 // Modem FM state, ignored
+	CmdEOT    = 0x67 // End of transmission
+)
+
+// Audio format: 8 kHz samples, packed by the modem as 12-bit unsigned values, two per three bytes.
+const (
+	SampleRate    = 8000
+	sampleOffset  = 2048 // Unsigned value of silence
+	frameSamples  = 160  // Samples per frame sent to the modem, 20 ms
+	packedPerPair = 3    // Bytes holding two samples
+)
+
+// frameTimeout is how long a transmission may go without audio before it is treated as lost.
+const frameTimeout = 500 * time.Millisecond
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a transmission
+	StateAudio     = "AUDIO"     // Transmission in progress
+	StateIdle      = "IDLE"      // Network idle
+)
@@ -0,0 +1,157 @@
+// Package fm provides analog FM protocol logic, including the RF and network state machine.
+package fm
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// NetworkWriter receives RF audio to forward to the network.
+type NetworkWriter interface {
+	WriteAudio(samples []int16) error
+}
+
+// Control passes FM audio between the modem and the network. The modem handles squelch, CTCSS, the CW ID and
+// the courtesy tone itself; Control tracks the transmissions and converts the audio.
+type Control struct {
+	RFState  string             // Current RF state
+	NetState string             // Current network state
+	Network  NetworkWriter      // Network the RF audio is forwarded to, may be nil
+	Output   func(data []byte)  // Receives modem frames (command plus payload) for transmission, may be nil
+	Events   func(event string) // Receives transmission start and end notifications, may be nil
+
+	mu         sync.Mutex
+	rfSamples  int
+	netSamples int
+	lastRF     time.Time
+	lastNet    time.Time
+}
+
+// NewControl creates a new Control.
+func NewControl() *Control {
+	return &Control{
+		RFState:  StateListening,
+		NetState: StateIdle,
+	}
+}
+
+// WriteModem handles a frame received from the modem: a command byte followed by its payload.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case CmdData:
+		return c.writeRFAudio(data[1:])
+	case CmdEOT:
+		c.endOfRF()
+		return true
+	case CmdStatus:
+		return true
+	default:
+		log.Printf("FM: Unknown modem command 0x%02X", data[0])
+		return false
+	}
+}
+
+// writeRFAudio forwards RF audio to the network, starting a transmission on the first frame.
+func (c *Control) writeRFAudio(data []byte) bool {
+	if c.NetState == StateAudio {
+		return false
+	}
+	if c.RFState == StateListening {
+		c.RFState = StateAudio
+		c.rfSamples = 0
+		log.Printf("FM: RF transmission started")
+		c.notify("rf_start")
+	}
+
+	samples := UnpackSamples(data)
+	c.lastRF = time.Now()
+	c.rfSamples += len(samples)
+	if c.Network != nil {
+		if err := c.Network.WriteAudio(samples); err != nil {
+			log.Printf("FM: Unable to forward RF audio: %v", err)
+		}
+	}
+	return true
+}
+
+// endOfRF ends the current RF transmission.
+func (c *Control) endOfRF() {
+	if c.RFState == StateAudio {
+		log.Printf("FM: RF end of transmission, %.1f seconds", float64(c.rfSamples)/SampleRate)
+		c.notify("rf_end")
+	}
+	c.RFState = StateListening
+}
+
+// WriteNetworkAudio transmits PCM audio received from the network on RF. It returns false when RF is busy.
+func (c *Control) WriteNetworkAudio(samples []int16) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio {
+		return false
+	}
+	if c.NetState == StateIdle {
+		c.NetState = StateAudio
+		c.netSamples = 0
+		log.Printf("FM: Network transmission started")
+		c.notify("net_start")
+	}
+
+	c.lastNet = time.Now()
+	c.netSamples += len(samples)
+	for len(samples) > 0 {
+		n := min(len(samples), frameSamples)
+		c.output(append([]byte{CmdData}, PackSamples(samples[:n])...))
+		samples = samples[n:]
+	}
+	return true
+}
+
+// endOfNetwork ends the current network transmission, telling the modem to close the transmitter.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("FM: Network end of transmission, %.1f seconds", float64(c.netSamples)/SampleRate)
+		c.output([]byte{CmdEOT})
+		c.notify("net_end")
+	}
+	c.NetState = StateIdle
+}
+
+// CheckTimeouts ends RF or network transmissions that have stopped sending audio. The network protocol has no
+// end marker, so this is how network transmissions end.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("FM: RF transmission timed out")
+		c.endOfRF()
+	}
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		c.endOfNetwork()
+	}
+}
+
+// output passes a modem frame to the Output callback.
+func (c *Control) output(data []byte) {
+	if c.Output != nil {
+		c.Output(data)
+	}
+}
+
+// notify passes a transmission event to the Events callback.
+func (c *Control) notify(event string) {
+	if c.Events != nil {
+		c.Events(event)
+	}
+}
@@ -0,0 +1,101 @@
+package fm
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
+)
+
+// control handles the FM RF and network transmissions.
+var control *Control
+
+// network bridges audio to an FM gateway, nil when disabled.
+var network *Network
+
+// HandleFMPacket passes a modem frame (command byte plus payload) to the FM controller.
+func HandleFMPacket(packet []byte) {
+	if control == nil {
+		log.Printf("FM: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// writeModem transmits a controller frame (command plus payload) on the modem.
+func writeModem(data []byte) {
+	if err := modem.Write(data[0], data[1:]); err != nil {
+		log.Printf("FM: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 20 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so RF audio that stops is ended without the gateway audio bridge.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
+}
+
+// Init initializes the FM protocol handler with the given configuration. The CW ID, CTCSS and timers are
+// applied by the modem through SET_FM_PARAMS.
+func Init(config config.FMConfig) {
+	control = NewControl()
+	control.Events = cwid.Event
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if config.Enable && config.NetworkEnable {
+		initNetwork(config)
+	}
+	startClock(control)
+	fmt.Printf("FM protocol handler initialized with CTCSS: %.1f Hz\n", config.CTCSSFrequency)
+}
+
+// initNetwork opens the gateway audio bridge and connects it to the controller.
+func initNetwork(cfg config.FMConfig) {
+	n, err := NewNetwork(cfg.GatewayAddress, cfg.GatewayPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("FM: %v", err)
+		return
+	}
+
+	c := control
+	n.Audio = func(samples []int16) { c.WriteNetworkAudio(samples) }
+
+	if err := n.Open(); err != nil {
+		log.Printf("FM: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
+}
@@ -0,0 +1,207 @@
+// Package fm provides analog FM protocol logic, including the UDP audio bridge to an FM gateway.
+package fm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Network timing.
+const (
+	netReconnectDelay   = 5 * time.Second       // Delay before reopening a failed socket
+	netReadPollInterval = 20 * time.Millisecond // Read deadline bounding each receive
+	netMaxPacket        = SampleRate / 5 * 2    // Largest packet received, 200 ms of audio
+)
+
+// Network is a UDP audio bridge exchanging raw 8 kHz signed 16-bit little endian PCM with an FM gateway.
+// Each packet carries a run of samples; a transmission ends when the audio stops.
+type Network struct {
+	Audio func(samples []int16) // Receives audio from the gateway, may be nil
+
+	gateway   *net.UDPAddr
+	localPort int
+
+	mu   sync.Mutex
+	conn *net.UDPConn
+	stop chan struct{}
+}
+
+// NewNetwork creates a new Network sending to the gateway at the given address and port, listening on the
+// given local port.
+func NewNetwork(gatewayAddress string, gatewayPort, localPort int) (*Network, error) {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", gatewayAddress, gatewayPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gateway address: %w", err)
+	}
+
+	return &Network{
+		gateway:   addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts the receive loop.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("FM: Network opened to gateway %s", n.gateway)
+	return nil
+}
+
+// Close stops the receive loop and closes the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("FM: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open FM network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// LocalAddr returns the address of the UDP socket, nil when closed.
+func (n *Network) LocalAddr() net.Addr {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		return nil
+	}
+	return n.conn.LocalAddr()
+}
+
+// WriteAudio sends samples to the gateway.
+func (n *Network) WriteAudio(samples []int16) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	buffer := make([]byte, 0, len(samples)*2)
+	for _, s := range samples {
+		buffer = append(buffer, byte(s), byte(uint16(s)>>8))
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.gateway)
+	return err
+}
+
+// run receives packets and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	buffer := make([]byte, netMaxPacket)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("FM: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.gateway.IP) && addr.Port == n.gateway.Port {
+			n.receive(buffer[:length])
+		} else {
+			log.Printf("FM: Packet received from unknown address %s", addr)
+		}
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(netReconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("FM: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("FM: Network reconnected to gateway %s", n.gateway)
+	return true
+}
+
+// receive decodes a packet of samples from the gateway.
+func (n *Network) receive(buffer []byte) {
+	if len(buffer) < 2 {
+		log.Printf("FM: Invalid network packet of %d bytes", len(buffer))
+		return
+	}
+
+	samples := make([]int16, len(buffer)/2)
+	for i := range samples {
+		samples[i] = int16(uint16(buffer[2*i]) | uint16(buffer[2*i+1])<<8)
+	}
+	if n.Audio != nil {
+		n.Audio(samples)
+	}
+}
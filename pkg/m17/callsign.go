@@ -0,0 +1,89 @@
+// Package m17 provides M17 protocol logic, including base-40 callsign addressing.
+package m17
+
+import (
+	"errors"
+	"strings"
+)
+
+// callsignCharset maps base-40 digits to characters.
+const callsignCharset = " ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-/."
+
+// Special addresses.
+const (
+	BroadcastAddress = 0xFFFFFFFFFFFF // Destination of transmissions to everyone
+	BroadcastName    = "@ALL"
+	maxCallsignValue = 262144000000000 // 40^9, addresses at or above this are reserved
+	maxCallsignChars = 9
+)
+
+// EncodeCallsign encodes a callsign of up to 9 characters into a 48-bit base-40 address.
+// "@ALL" encodes to the broadcast address.
+func EncodeCallsign(callsign string) (uint64, error) {
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if callsign == BroadcastName || callsign == "ALL" {
+		return BroadcastAddress, nil
+	}
+	if len(callsign) > maxCallsignChars {
+		return 0, errors.New("callsign longer than 9 characters")
+	}
+
+	var address uint64
+	for i := len(callsign) - 1; i >= 0; i-- {
+		digit := strings.IndexByte(callsignCharset, callsign[i])
+		if digit < 0 {
+			return 0, errors.New("invalid character in callsign")
+		}
+		address = address*40 + uint64(digit)
+	}
+	return address, nil
+}
+
+// DecodeCallsign decodes a 48-bit base-40 address into a callsign.
+func DecodeCallsign(address uint64) string {
+	if address == BroadcastAddress {
+		return BroadcastName
+	}
+	if address >= maxCallsignValue {
+		return ""
+	}
+
+	var sb strings.Builder
+	for address > 0 {
+		sb.WriteByte(callsignCharset[address%40])
+		address /= 40
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// putAddress writes a 48-bit address as 6 bytes, big endian.
+func putAddress(data []byte, address uint64) {
+	for i := 0; i < 6; i++ {
+		data[i] = byte(address >> uint(8*(5-i)))
+	}
+}
+
+// getAddress reads a 48-bit address from 6 bytes, big endian.
+func getAddress(data []byte) uint64 {
+	var address uint64
+	for i := 0; i < 6; i++ {
+		address = address<<8 | uint64(data[i])
+	}
+	return address
+}
+
+// EncodeCallsignBytes encodes a callsign into a 6-byte address.
+func EncodeCallsignBytes(callsign string) ([]byte, error) {
+	address, err := EncodeCallsign(callsign)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 6)
+	putAddress(out, address)
+	return out, nil
+}
+
+// DecodeCallsignBytes decodes a 6-byte address into a callsign.
+func DecodeCallsignBytes(data []byte) string {
+	return DecodeCallsign(getAddress(data))
+}
@@ -0,0 +1,40 @@
+// Package m17 provides M17 protocol logic.
+package m17
+
+// MMDVM modem frame tags used by M17.
+const (
+	TagHeader = 0x00 // Frame carries a link setup frame
+	TagData   = 0x01 // Frame carries a stream or packet frame
+	TagLost   = 0x02 // Modem lost the signal
+	TagEOT    = 0x03 // Modem detected the end of transmission
+)
+
+// Frame layout: a 16-bit sync word followed by 368 bits of payload.
+const (
+	SyncLength    = 2
+	PayloadLength = 46
+	FrameLength   = SyncLength + PayloadLength
+)
+
+// Sync words.
+var (
+	LSFSync    = []byte{0x55, 0xF7} // Link setup frame
+	StreamSync = []byte{0xFF, 0x5D} // Stream frame
+	PacketSync = []byte{0x75, 0xFF} // Packet frame
+	EOTSync    = []byte{0x55, 0x5D} // End of transmission marker
+)
+
+// Data types in the LSF TYPE field.
+const (
+	DataTypeData      = 1 // Data only
+	DataTypeVoice     = 2 // Codec2 3200 voice
+	DataTypeVoiceData = 3 // Codec2 1600 voice and data
+)
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a transmission
+	StateAudio     = "AUDIO"     // Stream in progress
+	StateRejected  = "REJECTED"  // RF transmission rejected, frames are ignored until it ends
+	StateIdle      = "IDLE"      // Network idle
+)
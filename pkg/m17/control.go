@@ -0,0 +1,330 @@
+// Package m17 provides M17 protocol logic, including the RF and network state machine.
+package m17
+
+import (
+	"bytes"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// frameTimeout is how long a stream may go without frames before it is treated as lost.
+const frameTimeout = 1500 * time.Millisecond
+
+// frameDuration is the air time of one stream frame.
+const frameDuration = 40 * time.Millisecond
+
+// streamProtectedBits is the number of convolutionally coded bits in a stream frame, used for BER.
+const streamProtectedBits = 272
+
+// NetworkWriter receives RF streams to forward to the network.
+type NetworkWriter interface {
+	WriteStream(streamID uint16, lsf *LSF, frame *StreamFrame) error
+}
+
+// Control manages the M17 RF and network streams.
+type Control struct {
+	CAN      uint8                        // Channel access number RF traffic must use
+	RFState  string                       // Current RF state
+	NetState string                       // Current network state
+	Network  NetworkWriter                // Network the RF streams are forwarded to, may be nil
+	Output   func(data []byte)            // Receives modem frames (tag plus frame) for transmission, may be nil
+	Events   func(event string, lsf *LSF) // Receives stream start and end notifications, may be nil
+	Data     func(event *DataEvent)       // Receives packets and positions heard on RF or the network, may be nil
+
+	RFLSF  *LSF // LSF of the current RF stream
+	NetLSF *LSF // LSF of the current network stream
+
+	mu          sync.Mutex
+	lich        LICHCollector
+	packets     PacketAssembler
+	rfStreamID  uint16
+	rfFrames    int
+	rfErrors    int
+	netStreamID uint16
+	netFrames   int
+	lastRF      time.Time
+	lastNet     time.Time
+}
+
+// NewControl creates a new Control for the given channel access number.
+func NewControl(can uint8) *Control {
+	return &Control{
+		CAN:      can & 0x0F,
+		RFState:  StateListening,
+		NetState: StateIdle,
+	}
+}
+
+// WriteModem handles a frame received from the modem: a tag byte followed by the 48-byte frame.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case TagLost:
+		if c.RFState == StateAudio {
+			log.Printf("M17: RF transmission lost from %s", c.RFLSF.Source)
+		}
+		c.endOfRF(true)
+		return false
+	case TagEOT:
+		c.endOfRF(true)
+		return true
+	case TagHeader, TagData:
+	default:
+		log.Printf("M17: Unknown modem tag 0x%02X", data[0])
+		return false
+	}
+
+	frame := data[1:]
+	switch sync := FrameType(frame); {
+	case bytes.Equal(sync, LSFSync):
+		return c.writeRFLSF(frame)
+	case bytes.Equal(sync, StreamSync):
+		return c.writeRFStream(frame)
+	case bytes.Equal(sync, PacketSync):
+		return c.writeRFPacket(frame)
+	case bytes.Equal(sync, EOTSync):
+		c.endOfRF(true)
+		return true
+	default:
+		return false
+	}
+}
+
+// writeRFLSF decodes a link setup frame and starts a new RF transmission.
+func (c *Control) writeRFLSF(frame []byte) bool {
+	if c.RFState != StateListening {
+		return false
+	}
+
+	lsf, _, err := DecodeLSFFrame(frame)
+	if err != nil {
+		log.Printf("M17: Invalid RF link setup frame: %v", err)
+		return false
+	}
+	return c.startRF(lsf)
+}
+
+// startRF validates an LSF and starts a new RF transmission.
+func (c *Control) startRF(lsf *LSF) bool {
+	if c.NetState != StateIdle {
+		log.Printf("M17: RF transmission from %s ignored, network stream in progress", lsf.Source)
+		c.RFState = StateRejected
+		return false
+	}
+	if lsf.CAN != c.CAN {
+		log.Printf("M17: RF transmission from %s on CAN %d rejected, this is CAN %d", lsf.Source, lsf.CAN, c.CAN)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFLSF = lsf
+	c.RFState = StateAudio
+	c.rfStreamID = uint16(rand.Intn(65535) + 1)
+	c.rfFrames = 0
+	c.rfErrors = 0
+	c.lastRF = time.Now()
+	c.packets.Reset()
+	log.Printf("M17: RF transmission from %s", lsf)
+	c.notify("rf_start", lsf)
+	c.position(lsf)
+	return true
+}
+
+// writeRFStream decodes a stream frame and forwards it, starting a late entry when the LSF was missed.
+func (c *Control) writeRFStream(frame []byte) bool {
+	if c.RFState == StateRejected {
+		return false
+	}
+
+	f, errs, err := DecodeStreamFrame(frame)
+	if err != nil {
+		return false
+	}
+
+	if c.RFState == StateListening {
+		lsf := c.lich.Add(f)
+		if lsf == nil {
+			return false
+		}
+		c.lich.Reset()
+		log.Printf("M17: RF late entry")
+		if !c.startRF(lsf) {
+			return false
+		}
+	}
+
+	c.lastRF = time.Now()
+	c.rfFrames++
+	c.rfErrors += errs
+
+	if c.Network != nil && c.RFLSF.Stream {
+		if err := c.Network.WriteStream(c.rfStreamID, c.RFLSF, f); err != nil {
+			log.Printf("M17: Unable to forward RF frame: %v", err)
+		}
+	}
+
+	if f.EOT {
+		c.endOfRF(false)
+	}
+	return true
+}
+
+// writeRFPacket decodes a packet frame and passes the packet on once it is complete.
+func (c *Control) writeRFPacket(frame []byte) bool {
+	if c.RFState != StateAudio || c.RFLSF.Stream {
+		return false
+	}
+
+	f, _, err := DecodePacketFrame(frame)
+	if err != nil {
+		return false
+	}
+	c.lastRF = time.Now()
+	c.rfFrames++
+
+	packet, err := c.packets.Add(f)
+	if err != nil {
+		log.Printf("M17: Invalid RF packet from %s: %v", c.RFLSF.Source, err)
+	} else if packet != nil {
+		c.packet(c.RFLSF, packet)
+	}
+	if f.EOF {
+		c.endOfRF(false)
+	}
+	return true
+}
+
+// endOfRF ends the current RF transmission and logs its statistics. When closeNetwork is set, a final
+// frame flagged as the end of stream is sent to the network.
+func (c *Control) endOfRF(closeNetwork bool) {
+	if c.RFState == StateAudio {
+		if closeNetwork && c.Network != nil && c.RFLSF.Stream {
+			end := &StreamFrame{FrameNumber: uint16(c.rfFrames), EOT: true, Payload: make([]byte, StreamPayloadLength)}
+			c.Network.WriteStream(c.rfStreamID, c.RFLSF, end)
+		}
+
+		ber := 0.0
+		if c.rfFrames > 0 {
+			ber = float64(c.rfErrors) * 100.0 / float64(c.rfFrames*streamProtectedBits)
+		}
+		log.Printf("M17: RF end of transmission from %s, %.1f seconds, BER: %.1f%%",
+			c.RFLSF.Source, float64(c.rfFrames)*frameDuration.Seconds(), ber)
+		c.notify("rf_end", c.RFLSF)
+	}
+	c.RFState = StateListening
+	c.RFLSF = nil
+	c.lich.Reset()
+}
+
+// WriteNetworkStream transmits a stream frame received from the network on RF, preceded by a link setup
+// frame at the start of the stream. It returns false when RF is busy or the frame is from another stream.
+func (c *Control) WriteNetworkStream(streamID uint16, lsf *LSF, frame *StreamFrame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio {
+		return false
+	}
+
+	if c.NetState == StateIdle {
+		// Network traffic is sent on our own channel access number
+		own := *lsf
+		own.CAN = c.CAN
+		c.NetLSF = &own
+		c.NetState = StateAudio
+		c.netStreamID = streamID
+		c.netFrames = 0
+		log.Printf("M17: Network transmission from %s", lsf)
+		c.notify("net_start", c.NetLSF)
+		c.position(c.NetLSF)
+		c.output(append([]byte{TagHeader}, EncodeLSFFrame(c.NetLSF)...))
+	} else if streamID != c.netStreamID {
+		return false
+	}
+
+	c.lastNet = time.Now()
+	c.netFrames++
+	c.output(append([]byte{TagData}, EncodeStreamFrame(c.NetLSF, frame)...))
+
+	if frame.EOT {
+		c.output([]byte{TagEOT})
+		c.endOfNetwork()
+	}
+	return true
+}
+
+// endOfNetwork ends the current network transmission.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("M17: Network end of transmission from %s, %.1f seconds",
+			c.NetLSF.Source, float64(c.netFrames)*frameDuration.Seconds())
+		c.notify("net_end", c.NetLSF)
+	}
+	c.NetState = StateIdle
+	c.NetLSF = nil
+}
+
+// CheckTimeouts ends RF or network transmissions that have stopped sending frames.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("M17: RF transmission from %s timed out", c.RFLSF.Source)
+		c.endOfRF(true)
+	}
+
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		log.Printf("M17: Network transmission from %s timed out", c.NetLSF.Source)
+		c.output([]byte{TagEOT})
+		c.endOfNetwork()
+	}
+}
+
+// output passes a modem frame to the Output callback.
+func (c *Control) output(data []byte) {
+	if c.Output != nil {
+		c.Output(data)
+	}
+}
+
+// notify passes a transmission event to the Events callback.
+func (c *Control) notify(event string, lsf *LSF) {
+	if c.Events != nil {
+		c.Events(event, lsf)
+	}
+}
+
+// position passes the GNSS position carried by an LSF to the Data callback.
+func (c *Control) position(lsf *LSF) {
+	if c.Data == nil || !lsf.HasGNSS() {
+		return
+	}
+	p, err := lsf.GNSS()
+	if err != nil {
+		log.Printf("M17: Invalid GNSS data from %s: %v", lsf.Source, err)
+		return
+	}
+	c.Data(&DataEvent{Source: lsf.Source, Destination: lsf.Destination, Position: p})
+}
+
+// packet passes a complete packet to the Data callback, decoding SMS text.
+func (c *Control) packet(lsf *LSF, packet []byte) {
+	if c.Data == nil {
+		return
+	}
+	event := &DataEvent{Source: lsf.Source, Destination: lsf.Destination, Protocol: packet[0], Packet: packet}
+	if packet[0] == PacketSMS {
+		event.Text, _ = DecodeSMS(packet)
+	}
+	c.Data(event)
+}
@@ -0,0 +1,215 @@
+// Package m17 provides M17 protocol logic, including the FEC, interleaving and decorrelation of the physical layer.
+package m17
+
+import "github.com/unklstewy/mmdvm_ghost/pkg/conv"
+
+// Puncture matrices for the link setup frame, stream frames and packet frames.
+var (
+	punctureLSF    = buildPunctureLSF()
+	punctureStream = []byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0}
+	puncturePacket = []byte{1, 1, 1, 1, 1, 1, 1, 0}
+)
+
+// buildPunctureLSF builds the 61-entry P1 matrix: a one followed by fifteen 1, 0, 1, 1 groups, so that
+// the coded bits at 2 mod 4 of each period are left out.
+func buildPunctureLSF() []byte {
+	p := []byte{1}
+	for i := 0; i < 15; i++ {
+		p = append(p, 1, 0, 1, 1)
+	}
+	return p
+}
+
+// decorrelator is XORed with the 368 payload bits of every frame to whiten them.
+var decorrelator = []byte{
+	0xD6, 0xB5, 0xE2, 0x30, 0x82, 0xFF, 0x84, 0x62, 0xBA, 0x4E, 0x96, 0x90, 0xD8, 0x98, 0xDD, 0x5D,
+	0x0C, 0xC8, 0x52, 0x43, 0x91, 0x1D, 0xF8, 0x6E, 0x68, 0x2F, 0x35, 0xDA, 0x14, 0xEA, 0xCD, 0x76,
+	0x19, 0x8D, 0xD5, 0x80, 0xD1, 0x33, 0x87, 0x13, 0x57, 0x18, 0x2D, 0x29, 0x78, 0xC3,
+}
+
+// payloadBits is the number of bits after the sync word.
+const payloadBits = PayloadLength * 8
+
+// interleaveOrder is the quadratic permutation polynomial interleaver, (45x + 92x^2) mod 368.
+var interleaveOrder = buildInterleaveOrder()
+
+// buildInterleaveOrder generates the interleaver.
+func buildInterleaveOrder() []int {
+	order := make([]int, payloadBits)
+	for i := range order {
+		order[i] = (45*i + 92*i*i) % payloadBits
+	}
+	return order
+}
+
+// unpackBits converts bytes to bits, MSB first.
+func unpackBits(data []byte) []byte {
+	out := make([]byte, len(data)*8)
+	for i := range out {
+		out[i] = data[i/8] >> uint(7-i%8) & 1
+	}
+	return out
+}
+
+// packBits converts bits to bytes, MSB first.
+func packBits(in []byte) []byte {
+	out := make([]byte, (len(in)+7)/8)
+	for i, b := range in {
+		out[i/8] |= (b & 1) << uint(7-i%8)
+	}
+	return out
+}
+
+// scramble interleaves and decorrelates 368 payload bits for transmission.
+func scramble(bits []byte) []byte {
+	random := unpackBits(decorrelator)
+	out := make([]byte, payloadBits)
+	for i := range out {
+		out[i] = bits[interleaveOrder[i]] ^ random[i]
+	}
+	return out
+}
+
+// descramble reverses scramble on 368 received payload bits.
+func descramble(bits []byte) []byte {
+	random := unpackBits(decorrelator)
+	out := make([]byte, payloadBits)
+	for i := range bits[:payloadBits] {
+		out[interleaveOrder[i]] = bits[i] ^ random[i]
+	}
+	return out
+}
+
+// puncture removes the coded bits marked zero in the puncture matrix.
+func puncture(coded, matrix []byte) []byte {
+	out := make([]byte, 0, len(coded))
+	for i, b := range coded {
+		if matrix[i%len(matrix)] != 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// depuncture restores the punctured positions of codedLength coded bits, marking them as erasures.
+func depuncture(in, matrix []byte, codedLength int) ([]byte, []bool) {
+	out := make([]byte, codedLength)
+	erased := make([]bool, codedLength)
+	k := 0
+	for i := range out {
+		if matrix[i%len(matrix)] == 0 || k >= len(in) {
+			erased[i] = true
+			continue
+		}
+		out[i] = in[k]
+		k++
+	}
+	return out, erased
+}
+
+// encodeConvolutional convolutionally encodes data bits, adding the four flush bits, and punctures them.
+func encodeConvolutional(bits, matrix []byte) []byte {
+	flushed := append(append(make([]byte, 0, len(bits)+4), bits...), 0, 0, 0, 0)
+	return puncture(conv.Encode(flushed), matrix)
+}
+
+// decodeConvolutional depunctures and decodes punctured bits carrying dataBits bits of data, returning the
+// data bits without the flush bits and the path error count.
+func decodeConvolutional(bits, matrix []byte, dataBits int) ([]byte, int) {
+	coded, erased := depuncture(bits, matrix, (dataBits+4)*2)
+	decoded, errs := conv.Decode(coded, erased)
+	return decoded[:dataBits], errs
+}
+
+// golayMatrix holds the Golay(24,12) parity of each data bit, least significant first.
+var golayMatrix = []uint32{0x8EB, 0x93E, 0xA97, 0xDC6, 0x367, 0x6CD, 0xD99, 0x3DA, 0x7B4, 0xF68, 0x63B, 0xC75}
+
+// golayErrors maps each 12-bit syndrome to its error pattern of up to 3 bits.
+var golayErrors = buildGolayErrors()
+
+// golayParity returns the 12 parity bits of 12 data bits.
+func golayParity(data uint32) uint32 {
+	var parity uint32
+	for i, row := range golayMatrix {
+		if data&(1<<uint(i)) != 0 {
+			parity ^= row
+		}
+	}
+	return parity
+}
+
+// golaySyndrome returns the syndrome of a 24-bit codeword.
+func golaySyndrome(code uint32) uint32 {
+	return golayParity(code>>12&0xFFF) ^ code&0xFFF
+}
+
+// buildGolayErrors builds the syndrome table for all error patterns of weight 1 to 3.
+func buildGolayErrors() map[uint32]uint32 {
+	table := make(map[uint32]uint32)
+	for a := 0; a < 24; a++ {
+		p := uint32(1) << uint(a)
+		table[golaySyndrome(p)] = p
+		for b := a + 1; b < 24; b++ {
+			p := uint32(1)<<uint(a) | uint32(1)<<uint(b)
+			table[golaySyndrome(p)] = p
+			for c := b + 1; c < 24; c++ {
+				p := uint32(1)<<uint(a) | uint32(1)<<uint(b) | uint32(1)<<uint(c)
+				table[golaySyndrome(p)] = p
+			}
+		}
+	}
+	return table
+}
+
+// encodeGolay encodes 12 data bits into a 24-bit codeword, data in the top 12 bits.
+func encodeGolay(data uint32) uint32 {
+	data &= 0xFFF
+	return data<<12 | golayParity(data)
+}
+
+// decodeGolay decodes a 24-bit codeword, correcting up to 3 bit errors. It returns false when the
+// errors cannot be corrected.
+func decodeGolay(code uint32) (uint32, bool) {
+	code &= 0xFFFFFF
+	syndrome := golaySyndrome(code)
+	if syndrome == 0 {
+		return code >> 12, true
+	}
+	pattern, ok := golayErrors[syndrome]
+	if !ok {
+		return code >> 12, false
+	}
+	return (code ^ pattern) >> 12, true
+}
+
+// crc calculates the M17 CRC-16: polynomial 0x5935, initial value 0xFFFF, MSB first.
+func crc(data []byte) uint16 {
+	c := uint16(0xFFFF)
+	for _, b := range data {
+		c ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x5935
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// checkCRC reports whether the last two bytes hold the CRC of the rest of the data, high byte first.
+func checkCRC(data []byte) bool {
+	if len(data) < 3 {
+		return false
+	}
+	c := crc(data[:len(data)-2])
+	return data[len(data)-2] == byte(c>>8) && data[len(data)-1] == byte(c)
+}
+
+// addCRC writes the CRC of all but the last two bytes into the last two bytes, high byte first.
+func addCRC(data []byte) {
+	c := crc(data[:len(data)-2])
+	data[len(data)-2] = byte(c >> 8)
+	data[len(data)-1] = byte(c)
+}
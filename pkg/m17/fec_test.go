@@ -0,0 +1,76 @@
+package m17
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/conv"
+)
+
+// specP1 is the link setup frame puncture matrix P1 as tabulated in the M17 specification.
+var specP1 = []byte{
+	1,
+	1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1,
+	1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1,
+	1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1, 0, 1, 1,
+}
+
+func TestPunctureLSF(t *testing.T) {
+	if !bytes.Equal(punctureLSF, specP1) {
+		t.Fatalf("P1 = %v, want %v", punctureLSF, specP1)
+	}
+}
+
+func TestEncodeLSFFrame(t *testing.T) {
+	lsf := &LSF{Destination: BroadcastName, Source: "G4XYZ", Stream: true, DataType: DataTypeVoice, CAN: 3}
+	frame := EncodeLSFFrame(lsf)
+	if len(frame) != FrameLength || !bytes.Equal(frame[:SyncLength], LSFSync) {
+		t.Fatalf("frame = % X", frame)
+	}
+
+	// The 488 coded bits of the LSF and flush bits, with the 120 bits P1 marks as zero left out
+	data := append(unpackBits(lsf.Bytes()), 0, 0, 0, 0)
+	var want []byte
+	for i, b := range conv.Encode(data) {
+		if specP1[i%len(specP1)] != 0 {
+			want = append(want, b)
+		}
+	}
+	got, err := payloadOf(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("punctured bits differ from P1:\n got %v\nwant %v", got, want)
+	}
+
+	decoded, errs, err := DecodeLSFFrame(frame)
+	if err != nil || errs != 0 {
+		t.Fatalf("DecodeLSFFrame = %d errors, %v", errs, err)
+	}
+	if *decoded != *lsf {
+		t.Errorf("decoded %+v, want %+v", *decoded, *lsf)
+	}
+}
+
+func TestCRC(t *testing.T) {
+	// Test vectors from the M17 specification
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	tests := []struct {
+		data []byte
+		want uint16
+	}{
+		{nil, 0xFFFF},
+		{[]byte("A"), 0x206E},
+		{[]byte("123456789"), 0x772B},
+		{all, 0x1C31},
+	}
+	for _, test := range tests {
+		if got := crc(test.data); got != test.want {
+			t.Errorf("crc(% X) = 0x%04X, want 0x%04X", test.data, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Package m17 provides M17 protocol logic, including the coding of link setup, stream and packet frames.
+package m17
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Stream frame layout: a 48-bit LICH carrying one sixth of the LSF and a counter,
+// a 16-bit frame number and 16 bytes of payload.
+const (
+	lichChunkLength     = 5
+	lichChunks          = LSFLength / lichChunkLength
+	lichBits            = 96
+	StreamPayloadLength = 16
+	streamDataBits      = 16 + StreamPayloadLength*8
+	PacketPayloadLength = 25
+	packetDataBits      = PacketPayloadLength*8 + 6
+	eotFlag             = 0x8000
+)
+
+// StreamFrame is the content of a stream frame.
+type StreamFrame struct {
+	LICH        []byte // One 5-byte chunk of the LSF
+	LICHCounter uint8  // Position of the chunk in the LSF, 0 to 5
+	FrameNumber uint16 // Frame number, without the end of stream flag
+	EOT         bool   // Last frame of the stream
+	Payload     []byte // 16 bytes: two Codec2 3200 frames, or a Codec2 1600 frame and 8 bytes of data
+}
+
+// Voice returns the Codec2 frames carried by the payload for the given data type.
+func (f *StreamFrame) Voice(dataType uint8) [][]byte {
+	switch dataType {
+	case DataTypeVoice:
+		return [][]byte{f.Payload[:8], f.Payload[8:]}
+	case DataTypeVoiceData:
+		return [][]byte{f.Payload[:8]}
+	}
+	return nil
+}
+
+// Data returns the data carried by the payload for the given data type.
+func (f *StreamFrame) Data(dataType uint8) []byte {
+	switch dataType {
+	case DataTypeData:
+		return f.Payload
+	case DataTypeVoiceData:
+		return f.Payload[8:]
+	}
+	return nil
+}
+
+// PacketFrame is the content of a packet frame.
+type PacketFrame struct {
+	Payload []byte // 25 bytes of packet data
+	EOF     bool   // Last frame of the packet
+	Counter uint8  // Frame number, or the number of valid bytes in the last frame
+}
+
+// FrameType identifies a frame from its sync word.
+func FrameType(frame []byte) []byte {
+	if len(frame) < SyncLength {
+		return nil
+	}
+	for _, sync := range [][]byte{LSFSync, StreamSync, PacketSync, EOTSync} {
+		if bytes.Equal(frame[:SyncLength], sync) {
+			return sync
+		}
+	}
+	return nil
+}
+
+// newFrame assembles a frame from its sync word and 368 payload bits.
+func newFrame(sync, bits []byte) []byte {
+	return append(append([]byte{}, sync...), packBits(scramble(bits))...)
+}
+
+// payloadOf returns the descrambled payload bits of a frame.
+func payloadOf(frame []byte) ([]byte, error) {
+	if len(frame) < FrameLength {
+		return nil, errors.New("frame too short")
+	}
+	return descramble(unpackBits(frame[SyncLength:FrameLength])), nil
+}
+
+// EncodeLSFFrame encodes an LSF into a link setup frame.
+func EncodeLSFFrame(l *LSF) []byte {
+	return newFrame(LSFSync, encodeConvolutional(unpackBits(l.Bytes()), punctureLSF))
+}
+
+// DecodeLSFFrame decodes a link setup frame, returning the LSF and the number of bit errors corrected.
+func DecodeLSFFrame(frame []byte) (*LSF, int, error) {
+	bits, err := payloadOf(frame)
+	if err != nil {
+		return nil, 0, err
+	}
+	decoded, errs := decodeConvolutional(bits, punctureLSF, LSFLength*8)
+	l, err := ParseLSF(packBits(decoded))
+	return l, errs, err
+}
+
+// encodeLICH Golay encodes an LSF chunk and its counter into 96 bits.
+func encodeLICH(chunk []byte, counter uint8) []byte {
+	raw := append(append([]byte{}, chunk[:lichChunkLength]...), counter<<5)
+	out := make([]byte, 0, 12)
+	for i := 0; i < 4; i++ {
+		var data uint32
+		if i%2 == 0 {
+			data = uint32(raw[i/2*3])<<4 | uint32(raw[i/2*3+1])>>4
+		} else {
+			data = uint32(raw[i/2*3+1]&0x0F)<<8 | uint32(raw[i/2*3+2])
+		}
+		code := encodeGolay(data)
+		out = append(out, byte(code>>16), byte(code>>8), byte(code))
+	}
+	return unpackBits(out)
+}
+
+// decodeLICH decodes 96 LICH bits into an LSF chunk and its counter.
+func decodeLICH(bits []byte) ([]byte, uint8, error) {
+	coded := packBits(bits[:lichBits])
+	raw := make([]byte, 6)
+	for i := 0; i < 4; i++ {
+		code := uint32(coded[i*3])<<16 | uint32(coded[i*3+1])<<8 | uint32(coded[i*3+2])
+		data, ok := decodeGolay(code)
+		if !ok {
+			return nil, 0, errors.New("uncorrectable LICH")
+		}
+		if i%2 == 0 {
+			raw[i/2*3] = byte(data >> 4)
+			raw[i/2*3+1] = byte(data << 4)
+		} else {
+			raw[i/2*3+1] |= byte(data >> 8 & 0x0F)
+			raw[i/2*3+2] = byte(data)
+		}
+	}
+	counter := raw[5] >> 5
+	if counter >= lichChunks {
+		return nil, 0, errors.New("invalid LICH counter")
+	}
+	return raw[:lichChunkLength], counter, nil
+}
+
+// EncodeStreamFrame encodes a stream frame carrying the LICH chunk of the given LSF selected by the frame number.
+func EncodeStreamFrame(l *LSF, f *StreamFrame) []byte {
+	counter := uint8(f.FrameNumber % lichChunks)
+	chunk := l.Bytes()[int(counter)*lichChunkLength:]
+
+	fn := f.FrameNumber &^ eotFlag
+	if f.EOT {
+		fn |= eotFlag
+	}
+	data := append([]byte{byte(fn >> 8), byte(fn)}, f.Payload[:StreamPayloadLength]...)
+
+	bits := append(encodeLICH(chunk, counter), encodeConvolutional(unpackBits(data), punctureStream)...)
+	return newFrame(StreamSync, bits)
+}
+
+// DecodeStreamFrame decodes a stream frame, returning its content and the number of bit errors corrected.
+// The LICH is nil when it could not be decoded.
+func DecodeStreamFrame(frame []byte) (*StreamFrame, int, error) {
+	bits, err := payloadOf(frame)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	decoded, errs := decodeConvolutional(bits[lichBits:], punctureStream, streamDataBits)
+	data := packBits(decoded)
+	fn := uint16(data[0])<<8 | uint16(data[1])
+
+	f := &StreamFrame{
+		FrameNumber: fn &^ eotFlag,
+		EOT:         fn&eotFlag != 0,
+		Payload:     data[2 : 2+StreamPayloadLength],
+	}
+	if chunk, counter, err := decodeLICH(bits[:lichBits]); err == nil {
+		f.LICH = chunk
+		f.LICHCounter = counter
+	}
+	return f, errs, nil
+}
+
+// EncodePacketFrame encodes a packet frame.
+func EncodePacketFrame(f *PacketFrame) []byte {
+	data := make([]byte, PacketPayloadLength+1)
+	copy(data, f.Payload)
+	data[PacketPayloadLength] = (f.Counter & 0x1F) << 2
+	if f.EOF {
+		data[PacketPayloadLength] |= 0x80
+	}
+	bits := unpackBits(data)[:packetDataBits]
+	return newFrame(PacketSync, encodeConvolutional(bits, puncturePacket))
+}
+
+// DecodePacketFrame decodes a packet frame, returning its content and the number of bit errors corrected.
+func DecodePacketFrame(frame []byte) (*PacketFrame, int, error) {
+	bits, err := payloadOf(frame)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	decoded, errs := decodeConvolutional(bits, puncturePacket, packetDataBits)
+	data := packBits(decoded)
+	return &PacketFrame{
+		Payload: data[:PacketPayloadLength],
+		EOF:     data[PacketPayloadLength]&0x80 != 0,
+		Counter: data[PacketPayloadLength] >> 2 & 0x1F,
+	}, errs, nil
+}
+
+// LICHCollector reassembles an LSF from the LICH chunks of stream frames, for late entry.
+type LICHCollector struct {
+	lsf  [LSFLength]byte
+	have uint8
+}
+
+// Reset discards the collected chunks.
+func (c *LICHCollector) Reset() {
+	c.have = 0
+}
+
+// Add stores the LICH chunk of a stream frame and returns the LSF once all six chunks have been
+// collected with a valid CRC, otherwise nil.
+func (c *LICHCollector) Add(f *StreamFrame) *LSF {
+	if f.LICH == nil {
+		return nil
+	}
+	copy(c.lsf[int(f.LICHCounter)*lichChunkLength:], f.LICH)
+	c.have |= 1 << f.LICHCounter
+	if c.have != 1<<lichChunks-1 {
+		return nil
+	}
+
+	l, err := ParseLSF(c.lsf[:])
+	if err != nil {
+		// Keep collecting, the next chunks may replace a corrupted one
+		return nil
+	}
+	return l
+}
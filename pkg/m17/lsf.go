@@ -0,0 +1,78 @@
+// Package m17 provides M17 protocol logic, including the link setup frame.
+package m17
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LSFLength is the length of the link setup frame: destination, source, type, META and CRC.
+const LSFLength = 30
+
+// metaLength is the length of the META field.
+const metaLength = 14
+
+// LSF is the link setup frame describing a transmission.
+type LSF struct {
+	Destination string // Destination callsign, BroadcastName for everyone
+	Source      string // Source callsign
+	Stream      bool   // Stream mode, otherwise packet mode
+	DataType    uint8  // DataTypeData, DataTypeVoice or DataTypeVoiceData
+	Encryption  uint8  // Encryption type, 0 for none
+	Subtype     uint8  // Encryption subtype
+	CAN         uint8  // Channel access number, 0 to 15
+	Meta        [metaLength]byte
+}
+
+// ParseLSF parses a 30-byte LSF, checking its CRC.
+func ParseLSF(data []byte) (*LSF, error) {
+	if len(data) < LSFLength {
+		return nil, errors.New("LSF too short")
+	}
+	if !checkCRC(data[:LSFLength]) {
+		return nil, errors.New("invalid LSF CRC")
+	}
+
+	kind := uint16(data[12])<<8 | uint16(data[13])
+	l := &LSF{
+		Destination: DecodeCallsignBytes(data[0:6]),
+		Source:      DecodeCallsignBytes(data[6:12]),
+		Stream:      kind&0x0001 != 0,
+		DataType:    uint8(kind >> 1 & 0x03),
+		Encryption:  uint8(kind >> 3 & 0x03),
+		Subtype:     uint8(kind >> 5 & 0x03),
+		CAN:         uint8(kind >> 7 & 0x0F),
+	}
+	copy(l.Meta[:], data[14:28])
+	return l, nil
+}
+
+// Bytes returns the 30-byte LSF with its CRC. Callsigns that cannot be encoded are sent as empty.
+func (l *LSF) Bytes() []byte {
+	data := make([]byte, LSFLength)
+	if dst, err := EncodeCallsignBytes(l.Destination); err == nil {
+		copy(data[0:6], dst)
+	}
+	if src, err := EncodeCallsignBytes(l.Source); err == nil {
+		copy(data[6:12], src)
+	}
+
+	kind := uint16(l.DataType&0x03)<<1 | uint16(l.Encryption&0x03)<<3 | uint16(l.Subtype&0x03)<<5 | uint16(l.CAN&0x0F)<<7
+	if l.Stream {
+		kind |= 0x0001
+	}
+	data[12] = byte(kind >> 8)
+	data[13] = byte(kind)
+	copy(data[14:28], l.Meta[:])
+	addCRC(data)
+	return data
+}
+
+// String returns a short description of the LSF for logging.
+func (l *LSF) String() string {
+	mode := "packet"
+	if l.Stream {
+		mode = "stream"
+	}
+	return fmt.Sprintf("%s to %s, %s, type %d, CAN %d", l.Source, l.Destination, mode, l.DataType, l.CAN)
+}
@@ -2,16 +2,133 @@ package m17
 
 import (
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control handles the M17 RF and network streams.
+var control *Control
+
+// network links the controller to an M17 reflector, nil when disabled.
+var network *Network
+
+// HandleM17Packet passes a modem frame (tag byte plus 48-byte frame) to the M17 controller.
 func HandleM17Packet(packet []byte) {
-	// TODO: Add M17 packet handling logic
+	if control == nil {
+		log.Printf("M17: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// modemCommands maps the tags of controller frames to the modem commands transmitting them.
+var modemCommands = map[byte]byte{
+	TagHeader: modem.CmdM17LinkSetup,
+	TagData:   modem.CmdM17Stream,
+	TagEOT:    modem.CmdM17EOT,
+}
+
+// writeModem transmits a controller frame (tag plus payload) on the modem.
+func writeModem(data []byte) {
+	command, ok := modemCommands[data[0]]
+	if !ok {
+		log.Printf("M17: No modem command for tag 0x%02X", data[0])
+		return
+	}
+	if err := modem.Write(command, data[1:]); err != nil {
+		log.Printf("M17: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so lost RF streams are closed whether or not a reflector is linked.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
 }
 
 // Init initializes the M17 protocol handler with the given configuration.
 func Init(cfg config.M17Config) {
-	// TODO: Add initialization logic for M17
-	fmt.Printf("M17 protocol handler initialized with CAN: %s\n", cfg.CAN)
+	if cfg.CAN < 0 || cfg.CAN > 15 {
+		log.Printf("M17: Invalid CAN %d, using 0", cfg.CAN)
+		cfg.CAN = 0
+	}
+	control = NewControl(uint8(cfg.CAN))
+	control.Events = func(event string, _ *LSF) { cwid.Event(event) }
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+	control.Data = handleData
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if cfg.Enable && cfg.NetworkEnable {
+		initNetwork(cfg)
+	}
+
+	startClock(control)
+	fmt.Printf("M17 protocol handler initialized with CAN: %d\n", cfg.CAN)
+}
+
+// handleData logs packets heard on RF or the network and gates GNSS positions to APRS.
+func handleData(event *DataEvent) {
+	switch {
+	case event.Position != nil:
+		log.Printf("M17: Position from %s: %.5f, %.5f", event.Source, event.Position.Latitude, event.Position.Longitude)
+		if gateway := ax25.GatewayCallsign(); gateway != "" {
+			ax25.SendAPRS(event.Position.APRS(event.Source, gateway))
+		}
+	case event.Protocol == PacketSMS:
+		log.Printf("M17: SMS from %s to %s: %s", event.Source, event.Destination, event.Text)
+	default:
+		log.Printf("M17: Packet of type 0x%02X from %s to %s, %d bytes", event.Protocol, event.Source, event.Destination, len(event.Packet))
+	}
+}
+
+// initNetwork opens the reflector link and connects it to the controller.
+func initNetwork(cfg config.M17Config) {
+	n, err := NewNetwork(cfg.Callsign, cfg.ReflectorModule, cfg.ReflectorAddress, cfg.ReflectorPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("M17: %v", err)
+		return
+	}
+
+	c := control
+	n.Stream = func(streamID uint16, lsf *LSF, frame *StreamFrame) { c.WriteNetworkStream(streamID, lsf, frame) }
+
+	if err := n.Open(); err != nil {
+		log.Printf("M17: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
 }
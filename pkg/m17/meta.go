@@ -0,0 +1,139 @@
+// Package m17 provides M17 protocol logic, including the GNSS position carried in the LSF META field.
+package m17
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"
+)
+
+// META content types, given by the encryption subtype when the stream is not encrypted.
+const (
+	MetaText             = 0 // Text data
+	MetaGNSS             = 1 // GNSS position data
+	MetaExtendedCallsign = 2 // Extended callsign data
+)
+
+// GNSS station types.
+const (
+	StationFixed    = 0
+	StationMobile   = 1
+	StationHandheld = 2
+)
+
+// GNSS flags.
+const (
+	gnssSouth         = 0x01
+	gnssWest          = 0x02
+	gnssAltitudeValid = 0x04
+	gnssSpeedValid    = 0x08
+)
+
+// altitudeOffset is added to the altitude in feet so that it fits an unsigned field.
+const altitudeOffset = 1500
+
+// Position is a GNSS position from the LSF META field.
+type Position struct {
+	Source      uint8   // Data source, such as the client software
+	StationType uint8   // StationFixed, StationMobile or StationHandheld
+	Latitude    float64 // Degrees, negative for south
+	Longitude   float64 // Degrees, negative for west
+	Altitude    int     // Feet, valid when HasAltitude is set
+	Bearing     int     // Degrees, valid when HasSpeed is set
+	Speed       int     // Miles per hour, valid when HasSpeed is set
+	HasAltitude bool
+	HasSpeed    bool
+}
+
+// HasGNSS reports whether the LSF META field carries a GNSS position.
+func (l *LSF) HasGNSS() bool {
+	return l.Encryption == 0 && l.Subtype == MetaGNSS
+}
+
+// GNSS parses the GNSS position in the LSF META field.
+func (l *LSF) GNSS() (*Position, error) {
+	if !l.HasGNSS() {
+		return nil, errors.New("LSF carries no GNSS data")
+	}
+
+	m := l.Meta
+	p := &Position{
+		Source:      m[0],
+		StationType: m[1],
+		Latitude:    float64(m[2]) + float64(uint16(m[3])<<8|uint16(m[4]))/65536.0,
+		Longitude:   float64(m[5]) + float64(uint16(m[6])<<8|uint16(m[7]))/65536.0,
+		HasAltitude: m[8]&gnssAltitudeValid != 0,
+		HasSpeed:    m[8]&gnssSpeedValid != 0,
+		Altitude:    int(uint16(m[9])<<8|uint16(m[10])) - altitudeOffset,
+		Bearing:     int(uint16(m[11])<<8 | uint16(m[12])),
+		Speed:       int(m[13]),
+	}
+	if m[8]&gnssSouth != 0 {
+		p.Latitude = -p.Latitude
+	}
+	if m[8]&gnssWest != 0 {
+		p.Longitude = -p.Longitude
+	}
+	if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+		return nil, errors.New("GNSS position out of range")
+	}
+	return p, nil
+}
+
+// SetGNSS stores a GNSS position in the LSF META field.
+func (l *LSF) SetGNSS(p *Position) {
+	l.Encryption = 0
+	l.Subtype = MetaGNSS
+
+	var m [metaLength]byte
+	m[0] = p.Source
+	m[1] = p.StationType
+
+	lat, lon := math.Abs(p.Latitude), math.Abs(p.Longitude)
+	latFrac := uint16((lat - math.Floor(lat)) * 65536.0)
+	lonFrac := uint16((lon - math.Floor(lon)) * 65536.0)
+	m[2], m[3], m[4] = byte(lat), byte(latFrac>>8), byte(latFrac)
+	m[5], m[6], m[7] = byte(lon), byte(lonFrac>>8), byte(lonFrac)
+
+	if p.Latitude < 0 {
+		m[8] |= gnssSouth
+	}
+	if p.Longitude < 0 {
+		m[8] |= gnssWest
+	}
+	if p.HasAltitude {
+		m[8] |= gnssAltitudeValid
+		alt := uint16(p.Altitude + altitudeOffset)
+		m[9], m[10] = byte(alt>>8), byte(alt)
+	}
+	if p.HasSpeed {
+		m[8] |= gnssSpeedValid
+		m[11], m[12] = byte(p.Bearing>>8), byte(p.Bearing)
+		m[13] = byte(p.Speed)
+	}
+	l.Meta = m
+}
+
+// APRS formats the position as an APRS-IS packet from source, gated by the given station.
+func (p *Position) APRS(source, gateway string) string {
+	symbol := "/["
+	switch p.StationType {
+	case StationFixed:
+		symbol = "/-"
+	case StationMobile:
+		symbol = "/>"
+	}
+
+	// Course/speed and altitude extensions lead the comment
+	var comment string
+	if p.HasSpeed {
+		knots := int(math.Round(float64(p.Speed) * 0.868976))
+		comment += fmt.Sprintf("%03d/%03d", p.Bearing%360, knots)
+	}
+	if p.HasAltitude {
+		comment += fmt.Sprintf("/A=%06d", p.Altitude)
+	}
+	return ax25.PositionPacket(source, "APZM17", gateway, p.Latitude, p.Longitude, symbol, comment+" M17")
+}
@@ -0,0 +1,357 @@
+// Package m17 provides M17 protocol logic, including the reflector network link.
+package m17
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reflector packet signatures.
+var (
+	magicConnect    = []byte("CONN") // Link request: callsign and module
+	magicAck        = []byte("ACKN") // Link accepted
+	magicNack       = []byte("NACK") // Link refused
+	magicPing       = []byte("PING") // Keepalive from the reflector
+	magicPong       = []byte("PONG") // Keepalive reply
+	magicDisconnect = []byte("DISC") // Unlink, from either side
+	magicStream     = []byte("M17 ") // Stream frame
+)
+
+// streamPacketLength is the length of a stream packet: signature, stream ID, LSF without CRC,
+// frame number, payload and CRC.
+const streamPacketLength = 4 + 2 + LSFLength - 2 + 2 + StreamPayloadLength + 2
+
+// Network timing.
+const (
+	netConnectInterval  = 5 * time.Second         // Interval between link requests while unlinked
+	netPingTimeout      = 30 * time.Second        // Link treated as lost without pings for this long
+	netStreamTimeout    = 1500 * time.Millisecond // Incoming stream watchdog
+	netReconnectDelay   = 5 * time.Second         // Delay before reopening a failed socket
+	netReadPollInterval = 100 * time.Millisecond  // Read deadline used to run the timers
+)
+
+// Network is a UDP client linking to a module of an M17 reflector.
+type Network struct {
+	Stream func(streamID uint16, lsf *LSF, frame *StreamFrame) // Receives frames of incoming streams, may be nil
+
+	callsign  []byte
+	module    byte
+	reflector *net.UDPAddr
+	localPort int
+
+	mu          sync.Mutex
+	conn        *net.UDPConn
+	stop        chan struct{}
+	linked      bool
+	lastConnect time.Time
+	lastPing    time.Time
+	inStreamID  uint16
+	lastIn      time.Time
+}
+
+// NewNetwork creates a new Network linking the given callsign to a module of the reflector at the given
+// address and port, listening on the given local port.
+func NewNetwork(callsign, module, reflectorAddress string, reflectorPort, localPort int) (*Network, error) {
+	encoded, err := EncodeCallsignBytes(callsign)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callsign %q: %w", callsign, err)
+	}
+	module = strings.ToUpper(strings.TrimSpace(module))
+	if len(module) != 1 || module[0] < 'A' || module[0] > 'Z' {
+		return nil, fmt.Errorf("invalid reflector module %q", module)
+	}
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", reflectorAddress, reflectorPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reflector address: %w", err)
+	}
+
+	return &Network{
+		callsign:  encoded,
+		module:    module[0],
+		reflector: addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts linking to the reflector.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("M17: Network opened to reflector %s module %c", n.reflector, n.module)
+	return nil
+}
+
+// Close unlinks from the reflector, stops the receive loop and closes the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	if n.linked {
+		if err := n.write(append(append([]byte{}, magicDisconnect...), n.callsign...)); err != nil {
+			log.Printf("M17: Unable to unlink from reflector: %v", err)
+		}
+	}
+	close(n.stop)
+	n.stop = nil
+	n.linked = false
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("M17: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open M17 network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// IsLinked reports whether the reflector has accepted the link and is still pinging.
+func (n *Network) IsLinked() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.linked
+}
+
+// WriteStream sends a stream frame to the reflector.
+func (n *Network) WriteStream(streamID uint16, lsf *LSF, frame *StreamFrame) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.linked {
+		return nil
+	}
+
+	fn := frame.FrameNumber &^ eotFlag
+	if frame.EOT {
+		fn |= eotFlag
+	}
+
+	buffer := make([]byte, 0, streamPacketLength)
+	buffer = append(buffer, magicStream...)
+	buffer = append(buffer, byte(streamID>>8), byte(streamID))
+	buffer = append(buffer, lsf.Bytes()[:LSFLength-2]...)
+	buffer = append(buffer, byte(fn>>8), byte(fn))
+	buffer = append(buffer, frame.Payload[:StreamPayloadLength]...)
+	buffer = append(buffer, 0, 0)
+	addCRC(buffer)
+	return n.write(buffer)
+}
+
+// write sends a packet to the reflector. The caller must hold the lock.
+func (n *Network) write(buffer []byte) error {
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.reflector)
+	return err
+}
+
+// run receives packets, maintains the link and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	buffer := make([]byte, 1500)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+		now := time.Now()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("M17: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.linked = false
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.reflector.IP) && addr.Port == n.reflector.Port {
+			n.receive(buffer[:length], now)
+		} else {
+			log.Printf("M17: Packet received from unknown address %s", addr)
+		}
+
+		n.clock(now)
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(netReconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("M17: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("M17: Network socket reopened")
+	return true
+}
+
+// clock sends link requests while unlinked, and runs the ping and incoming stream watchdogs.
+func (n *Network) clock(now time.Time) {
+	n.mu.Lock()
+	if n.linked && now.Sub(n.lastPing) > netPingTimeout {
+		log.Printf("M17: Link to reflector %s lost", n.reflector)
+		n.linked = false
+	}
+	if !n.linked && now.Sub(n.lastConnect) >= netConnectInterval {
+		n.lastConnect = now
+		packet := append(append(append([]byte{}, magicConnect...), n.callsign...), n.module)
+		if err := n.write(packet); err != nil {
+			log.Printf("M17: Unable to send link request: %v", err)
+		}
+	}
+	if n.inStreamID != 0 && now.Sub(n.lastIn) > netStreamTimeout {
+		log.Printf("M17: Network stream 0x%04X timed out", n.inStreamID)
+		n.inStreamID = 0
+	}
+	n.mu.Unlock()
+
+}
+
+// receive dispatches a packet from the reflector.
+func (n *Network) receive(buffer []byte, now time.Time) {
+	if len(buffer) < 4 {
+		return
+	}
+
+	n.mu.Lock()
+	switch {
+	case bytes.Equal(buffer[:4], magicAck):
+		if !n.linked {
+			log.Printf("M17: Linked to reflector %s module %c", n.reflector, n.module)
+		}
+		n.linked = true
+		n.lastPing = now
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], magicNack):
+		log.Printf("M17: Link to reflector %s module %c refused", n.reflector, n.module)
+		n.linked = false
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], magicPing):
+		n.lastPing = now
+		if err := n.write(append(append([]byte{}, magicPong...), n.callsign...)); err != nil {
+			log.Printf("M17: Unable to answer ping: %v", err)
+		}
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], magicDisconnect):
+		if n.linked {
+			log.Printf("M17: Unlinked by reflector %s", n.reflector)
+		}
+		n.linked = false
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], magicStream):
+		n.mu.Unlock()
+		n.receiveStream(buffer, now)
+
+	default:
+		n.mu.Unlock()
+		log.Printf("M17: Unknown network packet %x", buffer[:4])
+	}
+}
+
+// receiveStream validates a stream packet and passes it on.
+func (n *Network) receiveStream(buffer []byte, now time.Time) {
+	if len(buffer) < streamPacketLength || !checkCRC(buffer[:streamPacketLength]) {
+		log.Printf("M17: Invalid stream packet from reflector")
+		return
+	}
+
+	streamID := uint16(buffer[4])<<8 | uint16(buffer[5])
+	raw := make([]byte, LSFLength)
+	copy(raw, buffer[6:6+LSFLength-2])
+	addCRC(raw)
+	lsf, err := ParseLSF(raw)
+	if err != nil {
+		return
+	}
+
+	offset := 6 + LSFLength - 2
+	fn := uint16(buffer[offset])<<8 | uint16(buffer[offset+1])
+	frame := &StreamFrame{
+		FrameNumber: fn &^ eotFlag,
+		EOT:         fn&eotFlag != 0,
+		Payload:     append([]byte{}, buffer[offset+2:offset+2+StreamPayloadLength]...),
+	}
+
+	n.mu.Lock()
+	if n.inStreamID == 0 {
+		n.inStreamID = streamID
+	} else if streamID != n.inStreamID {
+		// Another stream while busy
+		n.mu.Unlock()
+		return
+	}
+	n.lastIn = now
+	if frame.EOT {
+		n.inStreamID = 0
+	}
+	n.mu.Unlock()
+
+	if n.Stream != nil {
+		n.Stream(streamID, lsf, frame)
+	}
+}
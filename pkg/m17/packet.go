@@ -0,0 +1,112 @@
+// Package m17 provides M17 protocol logic, including packet mode data.
+package m17
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Packet protocol identifiers, the first byte of a packet.
+const (
+	PacketRaw     = 0x00
+	PacketAX25    = 0x01
+	PacketAPRS    = 0x02
+	Packet6LoWPAN = 0x03
+	PacketIPv4    = 0x04
+	PacketSMS     = 0x05
+	PacketWinlink = 0x06
+)
+
+// maxPacketFrames is the largest number of frames in a packet, limited by the 5-bit frame counter.
+const maxPacketFrames = 32
+
+// MaxPacketLength is the largest packet, including the protocol identifier and CRC.
+const MaxPacketLength = maxPacketFrames * PacketPayloadLength
+
+// DataEvent is a packet or GNSS position heard on RF or the network.
+type DataEvent struct {
+	Source      string
+	Destination string
+	Protocol    byte      // Packet protocol identifier, valid when Packet is set
+	Packet      []byte    // Complete packet, protocol identifier first and without the CRC, may be nil
+	Text        string    // Text of an SMS packet
+	Position    *Position // GNSS position from the LSF META field, may be nil
+}
+
+// PacketAssembler reassembles packets from packet frames.
+type PacketAssembler struct {
+	data []byte
+	next uint8
+}
+
+// Reset discards a partially received packet.
+func (a *PacketAssembler) Reset() {
+	a.data = a.data[:0]
+	a.next = 0
+}
+
+// Add stores a packet frame. When the last frame has been received it returns the packet, protocol identifier
+// first and without the CRC; otherwise it returns nil. Missing frames and CRC failures discard the packet.
+func (a *PacketAssembler) Add(f *PacketFrame) ([]byte, error) {
+	if !f.EOF {
+		if f.Counter != a.next {
+			a.Reset()
+			return nil, fmt.Errorf("packet frame %d received, expected %d", f.Counter, a.next)
+		}
+		a.data = append(a.data, f.Payload[:PacketPayloadLength]...)
+		a.next++
+		return nil, nil
+	}
+
+	defer a.Reset()
+
+	count := int(f.Counter)
+	if count < 1 || count > PacketPayloadLength {
+		return nil, fmt.Errorf("invalid last packet frame length %d", count)
+	}
+	packet := append(a.data, f.Payload[:count]...)
+	if len(packet) < 3 || !checkCRC(packet) {
+		return nil, errors.New("invalid packet CRC")
+	}
+	return append([]byte{}, packet[:len(packet)-2]...), nil
+}
+
+// SplitPacket adds the CRC to a packet (protocol identifier first) and splits it into packet frames.
+func SplitPacket(packet []byte) ([]*PacketFrame, error) {
+	data := append(append([]byte{}, packet...), 0, 0)
+	if len(data) > MaxPacketLength {
+		return nil, errors.New("packet too long")
+	}
+	addCRC(data)
+
+	var frames []*PacketFrame
+	for i := 0; i < len(data); i += PacketPayloadLength {
+		payload := make([]byte, PacketPayloadLength)
+		n := copy(payload, data[i:])
+		f := &PacketFrame{Payload: payload, Counter: uint8(len(frames))}
+		if i+PacketPayloadLength >= len(data) {
+			f.EOF = true
+			f.Counter = uint8(n)
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
+
+// EncodeSMS builds an SMS packet carrying the given text.
+func EncodeSMS(text string) []byte {
+	return append(append([]byte{PacketSMS}, text...), 0)
+}
+
+// DecodeSMS returns the text of an SMS packet.
+func DecodeSMS(packet []byte) (string, error) {
+	if len(packet) < 1 || packet[0] != PacketSMS {
+		return "", errors.New("not an SMS packet")
+	}
+	text := string(packet[1:])
+	if end := strings.IndexByte(text, 0); end >= 0 {
+		text = text[:end]
+	}
+	return text, nil
+}
@@ -0,0 +1,196 @@
+package modem
+
+import (
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+)
+
+// MMDVM serial protocol framing and commands.
+const (
+	FrameStart   = 0xE0 // First byte of every frame
+	CmdSetConfig = 0x02 // Modem configuration
+	ModeIdle     = 0x00 // Idle mode
+)
+
+// SET_CONFIG flag bits, byte 3.
+const (
+	flagRXInvert     = 0x01
+	flagTXInvert     = 0x02
+	flagPTTInvert    = 0x04
+	flagYSFLowDev    = 0x08
+	flagDebug        = 0x10
+	flagCOSAsLockout = 0x20
+	flagSimplex      = 0x80
+)
+
+// SET_CONFIG mode enable bits, byte 4.
+const (
+	enableDStar  = 0x01
+	enableDMR    = 0x02
+	enableYSF    = 0x04
+	enableP25    = 0x08
+	enableNXDN   = 0x10
+	enablePOCSAG = 0x20
+	enableM17    = 0x40
+	enableFM     = 0x80
+)
+
+// setConfigLength is the length of a SET_CONFIG frame.
+const setConfigLength = 26
+
+// defaultTXHang is the transmit hang in seconds for modes without their own setting.
+const defaultTXHang = 5
+
+// Settings holds the modem configuration sent with SET_CONFIG.
+type Settings struct {
+	RXInvert        bool
+	TXInvert        bool
+	PTTInvert       bool
+	YSFLowDeviation bool // Transmit YSF with low deviation
+	Debug           bool
+	Duplex          bool
+
+	DStar, DMR, YSF, P25, NXDN, POCSAG, M17, FM bool // Enabled modes
+
+	TXDelay      int // Milliseconds between keying the transmitter and sending data
+	RXLevel      int // Percent
+	CWIdTXLevel  int // Percent
+	DStarTXLevel int // Percent
+	DMRTXLevel   int // Percent
+	YSFTXLevel   int // Percent
+	P25TXLevel   int // Percent
+	NXDNTXLevel  int // Percent
+	POCSAGLevel  int // Percent
+	FMTXLevel    int // Percent
+	M17TXLevel   int // Percent
+	DMRColorCode int
+	DMRDelay     int
+	TXDCOffset   int
+	RXDCOffset   int
+	YSFTXHang    int // Seconds
+	P25TXHang    int // Seconds
+	NXDNTXHang   int // Seconds
+	M17TXHang    int // Seconds
+}
+
+// NewSettings derives the modem settings from the loaded configuration.
+func NewSettings(cfg *config.Config) Settings {
+	tx := cfg.Modem.TXLevel
+	return Settings{
+		YSFLowDeviation: cfg.YSF.LowDeviation,
+		Duplex:          cfg.General.Duplex,
+
+		DStar:  cfg.DStar.Enable,
+		DMR:    cfg.DMR.Enable,
+		YSF:    cfg.YSF.Enable,
+		P25:    cfg.P25.Enable,
+		NXDN:   cfg.NXDN.Enable,
+		POCSAG: cfg.Pocsag.Enable,
+		M17:    cfg.M17.Enable,
+		FM:     cfg.FM.Enable,
+
+		TXDelay:      cfg.Modem.TXDelay,
+		RXLevel:      cfg.Modem.RXLevel,
+		CWIdTXLevel:  tx,
+		DStarTXLevel: tx,
+		DMRTXLevel:   tx,
+		YSFTXLevel:   tx,
+		P25TXLevel:   tx,
+		NXDNTXLevel:  tx,
+		POCSAGLevel:  tx,
+		FMTXLevel:    tx,
+		M17TXLevel:   tx,
+		DMRColorCode: cfg.DMR.ColorCode,
+		DMRDelay:     cfg.Modem.DMRDelay,
+		YSFTXHang:    cfg.YSF.TXHang,
+		P25TXHang:    defaultTXHang,
+		NXDNTXHang:   defaultTXHang,
+		M17TXHang:    defaultTXHang,
+	}
+}
+
+// level converts a percentage to the 0 to 255 range used by the modem.
+func level(percent int) byte {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	return byte((percent*255 + 50) / 100)
+}
+
+// clampByte limits a value to 0 to 255.
+func clampByte(v int) byte {
+	if v < 0 {
+		return 0
+	} else if v > 255 {
+		return 255
+	}
+	return byte(v)
+}
+
+// SetConfigFrame builds the SET_CONFIG frame for the settings.
+func (s Settings) SetConfigFrame() []byte {
+	buffer := make([]byte, setConfigLength)
+	buffer[0] = FrameStart
+	buffer[1] = setConfigLength
+	buffer[2] = CmdSetConfig
+
+	flags := []struct {
+		set bool
+		bit byte
+	}{
+		{s.RXInvert, flagRXInvert},
+		{s.TXInvert, flagTXInvert},
+		{s.PTTInvert, flagPTTInvert},
+		{s.YSFLowDeviation, flagYSFLowDev},
+		{s.Debug, flagDebug},
+		{!s.Duplex, flagSimplex},
+	}
+	for _, f := range flags {
+		if f.set {
+			buffer[3] |= f.bit
+		}
+	}
+
+	modes := []struct {
+		set bool
+		bit byte
+	}{
+		{s.DStar, enableDStar},
+		{s.DMR, enableDMR},
+		{s.YSF, enableYSF},
+		{s.P25, enableP25},
+		{s.NXDN, enableNXDN},
+		{s.POCSAG, enablePOCSAG},
+		{s.M17, enableM17},
+		{s.FM, enableFM},
+	}
+	for _, m := range modes {
+		if m.set {
+			buffer[4] |= m.bit
+		}
+	}
+
+	buffer[5] = clampByte(s.TXDelay / 10) // In 10ms units
+	buffer[6] = ModeIdle
+	buffer[7] = level(s.RXLevel)
+	buffer[8] = level(s.CWIdTXLevel)
+	buffer[9] = clampByte(s.DMRColorCode)
+	buffer[10] = clampByte(s.DMRDelay)
+	buffer[11] = 128 // Formerly the oscillator offset
+	buffer[12] = level(s.DStarTXLevel)
+	buffer[13] = level(s.DMRTXLevel)
+	buffer[14] = level(s.YSFTXLevel)
+	buffer[15] = level(s.P25TXLevel)
+	buffer[16] = clampByte(s.TXDCOffset + 128)
+	buffer[17] = clampByte(s.RXDCOffset + 128)
+	buffer[18] = level(s.NXDNTXLevel)
+	buffer[19] = clampByte(s.YSFTXHang)
+	buffer[20] = level(s.POCSAGLevel)
+	buffer[21] = level(s.FMTXLevel)
+	buffer[22] = clampByte(s.P25TXHang)
+	buffer[23] = clampByte(s.NXDNTXHang)
+	buffer[24] = level(s.M17TXLevel)
+	buffer[25] = clampByte(s.M17TXHang)
+	return buffer
+}
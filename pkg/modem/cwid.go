@@ -0,0 +1,25 @@
+package modem
+
+import (
+	"errors"
+	"strings"
+)
+
+// CmdSendCWId asks the modem to send its text in Morse at the CW ID level while idle.
+const CmdSendCWId = 0x0A
+
+// maxCWIdText bounds the CW ID text so the frame fits the one byte length.
+const maxCWIdText = 200
+
+// CWIdFrame builds the SEND_CWID frame for the given text.
+func CWIdFrame(text string) ([]byte, error) {
+	if text == "" {
+		return nil, errors.New("empty CW ID text")
+	}
+	if len(text) > maxCWIdText {
+		return nil, errors.New("CW ID text too long")
+	}
+	buffer := make([]byte, 0, 3+len(text))
+	buffer = append(buffer, FrameStart, byte(3+len(text)), CmdSendCWId)
+	return append(buffer, strings.ToUpper(text)...), nil
+}
@@ -0,0 +1,177 @@
+package modem
+
+import (
+	"strings"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+)
+
+// FM parameter commands, sent after SET_CONFIG when FM is enabled.
+const (
+	CmdSetFMParams1 = 0x60 // CW ID
+	CmdSetFMParams2 = 0x61 // Courtesy tone
+	CmdSetFMParams3 = 0x62 // Timeout, CTCSS and squelch timers
+)
+
+// FM parameter flag bits.
+const (
+	flagFMIdAtStart = 0x01
+	flagFMIdAtEnd   = 0x02
+	flagFMIdAtLatch = 0x04
+
+	flagFMUseCOS    = 0x01
+	flagFMCOSInvert = 0x02
+)
+
+// maxFMText bounds the CW ID and courtesy tone text so the frames fit the one byte length.
+const maxFMText = 200
+
+// FMSettings holds the FM mode configuration sent with the SET_FM_PARAMS commands.
+type FMSettings struct {
+	Callsign          string // CW ID text
+	CallsignSpeed     int    // Words per minute
+	CallsignFrequency int    // Hz
+	CallsignTime      int    // Minutes
+	CallsignHoldoff   int    // Minutes
+	CallsignHighLevel int    // Percent
+	CallsignLowLevel  int    // Percent
+	CallsignAtStart   bool
+	CallsignAtEnd     bool
+	CallsignAtLatch   bool
+
+	Ack          string // Courtesy tone text
+	AckSpeed     int    // Words per minute
+	AckFrequency int    // Hz
+	AckMinTime   int    // Seconds
+	AckDelay     int    // Milliseconds
+	AckLevel     int    // Percent
+
+	Timeout            int     // Seconds
+	TimeoutLevel       int     // Percent
+	CTCSSFrequency     float64 // Hz
+	CTCSSHighThreshold int
+	CTCSSLowThreshold  int
+	CTCSSLevel         int // Percent
+	KerchunkTime       int // Seconds
+	HangTime           int // Seconds
+	UseCOS             bool
+	COSInvert          bool
+	RFAudioBoost       int
+	MaxDevLevel        int // Percent
+}
+
+// NewFMSettings derives the FM settings from the loaded configuration, identifying with the general
+// callsign when no CW ID text is configured.
+func NewFMSettings(cfg *config.Config) FMSettings {
+	fm := cfg.FM
+	callsign := fm.Callsign
+	if callsign == "" {
+		callsign = cfg.General.Callsign
+	}
+	return FMSettings{
+		Callsign:          callsign,
+		CallsignSpeed:     fm.CallsignSpeed,
+		CallsignFrequency: fm.CallsignFrequency,
+		CallsignTime:      fm.CallsignTime,
+		CallsignHoldoff:   fm.CallsignHoldoff,
+		CallsignHighLevel: fm.CallsignHighLevel,
+		CallsignLowLevel:  fm.CallsignLowLevel,
+		CallsignAtStart:   fm.CallsignAtStart,
+		CallsignAtEnd:     fm.CallsignAtEnd,
+		CallsignAtLatch:   fm.CallsignAtLatch,
+
+		Ack:          fm.RFAck,
+		AckSpeed:     fm.AckSpeed,
+		AckFrequency: fm.AckFrequency,
+		AckMinTime:   fm.AckMinTime,
+		AckDelay:     fm.AckDelay,
+		AckLevel:     fm.AckLevel,
+
+		Timeout:            fm.Timeout,
+		TimeoutLevel:       fm.TimeoutLevel,
+		CTCSSFrequency:     fm.CTCSSFrequency,
+		CTCSSHighThreshold: fm.CTCSSHighThreshold,
+		CTCSSLowThreshold:  fm.CTCSSLowThreshold,
+		CTCSSLevel:         fm.CTCSSLevel,
+		KerchunkTime:       fm.KerchunkTime,
+		HangTime:           fm.HangTime,
+		UseCOS:             fm.UseCOS,
+		COSInvert:          fm.COSInvert,
+		RFAudioBoost:       fm.RFAudioBoost,
+		MaxDevLevel:        fm.MaxDevLevel,
+	}
+}
+
+// fmText returns CW text as sent to the modem: upper case and truncated to fit a frame.
+func fmText(text string) []byte {
+	text = strings.ToUpper(text)
+	if len(text) > maxFMText {
+		text = text[:maxFMText]
+	}
+	return []byte(text)
+}
+
+// fmFrame builds a frame for an FM parameter command from its fields.
+func fmFrame(command byte, fields []byte) []byte {
+	buffer := make([]byte, 0, 3+len(fields))
+	buffer = append(buffer, FrameStart, byte(3+len(fields)), command)
+	return append(buffer, fields...)
+}
+
+// FMParamsFrames builds the SET_FM_PARAMS frames for the settings, in the order they are sent.
+func (s FMSettings) FMParamsFrames() [][]byte {
+	var idFlags byte
+	if s.CallsignAtStart {
+		idFlags |= flagFMIdAtStart
+	}
+	if s.CallsignAtEnd {
+		idFlags |= flagFMIdAtEnd
+	}
+	if s.CallsignAtLatch {
+		idFlags |= flagFMIdAtLatch
+	}
+	params1 := append([]byte{
+		clampByte(s.CallsignSpeed),
+		clampByte(s.CallsignFrequency / 10), // In 10Hz units
+		clampByte(s.CallsignTime),
+		clampByte(s.CallsignHoldoff),
+		level(s.CallsignHighLevel),
+		level(s.CallsignLowLevel),
+		idFlags,
+	}, fmText(s.Callsign)...)
+
+	params2 := append([]byte{
+		clampByte(s.AckSpeed),
+		clampByte(s.AckFrequency / 10), // In 10Hz units
+		clampByte(s.AckMinTime),
+		clampByte(s.AckDelay / 10), // In 10ms units
+		level(s.AckLevel),
+	}, fmText(s.Ack)...)
+
+	var squelchFlags byte
+	if s.UseCOS {
+		squelchFlags |= flagFMUseCOS
+	}
+	if s.COSInvert {
+		squelchFlags |= flagFMCOSInvert
+	}
+	params3 := []byte{
+		clampByte(s.Timeout / 5), // In 5s units
+		level(s.TimeoutLevel),
+		clampByte(int(s.CTCSSFrequency)), // Whole Hz, the modem matches the standard tone
+		clampByte(s.CTCSSHighThreshold),
+		clampByte(s.CTCSSLowThreshold),
+		level(s.CTCSSLevel),
+		clampByte(s.KerchunkTime),
+		clampByte(s.HangTime),
+		squelchFlags,
+		clampByte(s.RFAudioBoost),
+		level(s.MaxDevLevel),
+	}
+
+	return [][]byte{
+		fmFrame(CmdSetFMParams1, params1),
+		fmFrame(CmdSetFMParams2, params2),
+		fmFrame(CmdSetFMParams3, params3),
+	}
+}
@@ -1,5 +1,318 @@
 package modem
 
-func InitModem() {
-	// TODO: Initialize modem
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+)
+
+// MMDVM commands carrying mode data and replies, as defined by the MMDVM firmware.
+const (
+	CmdDStarHeader  = 0x10
+	CmdDStarData    = 0x11
+	CmdDStarLost    = 0x12
+	CmdDStarEOT     = 0x13
+	CmdDMRData1     = 0x18
+	CmdDMRLost1     = 0x19
+	CmdDMRData2     = 0x1A
+	CmdDMRLost2     = 0x1B
+	CmdDMRStart     = 0x1D
+	CmdYSFData      = 0x20
+	CmdYSFLost      = 0x21
+	CmdP25Header    = 0x30
+	CmdP25LDU       = 0x31
+	CmdP25Lost      = 0x32
+	CmdNXDNData     = 0x40
+	CmdNXDNLost     = 0x41
+	CmdM17LinkSetup = 0x45
+	CmdM17Stream    = 0x46
+	CmdM17Packet    = 0x47
+	CmdM17Lost      = 0x48
+	CmdM17EOT       = 0x49
+	CmdPOCSAGData   = 0x50
+	CmdAX25Data     = 0x55
+	CmdFMData       = 0x65
+	CmdFMStatus     = 0x66
+	CmdFMEOT        = 0x67
+	CmdAck          = 0x70
+	CmdNak          = 0x7F
+)
+
+// reopenDelay is the delay before reopening a port that failed.
+const reopenDelay = 5 * time.Second
+
+// maxPayload is the largest payload that fits a frame with its one byte length.
+const maxPayload = 255 - 3
+
+// Modem is a serial link to an MMDVM modem. Frames may be written from any goroutine; frames read from the
+// modem are passed to Receive, except the status replies, which are kept for Status. The configuration
+// frames are sent each time the port is opened, so a modem that is reset or reconnected is configured again.
+type Modem struct {
+	Receive func(command byte, payload []byte) // Receives frames read from the modem, may be nil
+
+	port   string
+	config [][]byte
+
+	mu       sync.Mutex
+	file     *os.File
+	stop     chan struct{}
+	status   Status
+	statusAt time.Time
+}
+
+// device is the modem opened by InitModem, nil when none is open.
+var (
+	deviceMu sync.Mutex
+	device   *Modem
+)
+
+// NewModem creates a new Modem on the given serial port, sending the given frames whenever it is opened.
+func NewModem(port string, config [][]byte) *Modem {
+	return &Modem{
+		port:   port,
+		config: config,
+	}
+}
+
+// Open opens the serial port, sends the configuration and starts the receive loop.
+func (m *Modem) Open() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return errors.New("modem already open")
+	}
+	if err := m.openPort(); err != nil {
+		return err
+	}
+
+	m.stop = make(chan struct{})
+	go m.run(m.stop)
+	go m.poll(m.stop)
+	log.Printf("Modem: Opened %s", m.port)
+	return nil
+}
+
+// Close stops the receive loop and closes the serial port.
+func (m *Modem) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop == nil {
+		return
+	}
+	close(m.stop)
+	m.stop = nil
+	if m.file != nil {
+		m.file.Close()
+		m.file = nil
+	}
+	log.Printf("Modem: Closed %s", m.port)
+}
+
+// openPort opens and configures the serial port, then sends the configuration frames. The caller must hold
+// the lock.
+func (m *Modem) openPort() error {
+	file, err := os.OpenFile(m.port, openFlags, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open modem port: %w", err)
+	}
+	if err := configurePort(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to configure modem port: %w", err)
+	}
+	for _, frame := range m.config {
+		if _, err := file.Write(frame); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to configure modem: %w", err)
+		}
+	}
+	m.file = file
+	return nil
+}
+
+// Write sends a frame with the given command and payload to the modem.
+func (m *Modem) Write(command byte, payload []byte) error {
+	if len(payload) > maxPayload {
+		return fmt.Errorf("modem payload too long: %d bytes", len(payload))
+	}
+	frame := make([]byte, 0, 3+len(payload))
+	frame = append(frame, FrameStart, byte(3+len(payload)), command)
+	if err := m.WriteFrame(append(frame, payload...)); err != nil {
+		return err
+	}
+	if command == CmdPOCSAGData {
+		m.usePOCSAGSpace()
+	}
+	return nil
+}
+
+// WriteFrame sends a complete frame, starting with FrameStart, to the modem.
+func (m *Modem) WriteFrame(frame []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.file == nil {
+		return errors.New("modem port not open")
+	}
+	_, err := m.file.Write(frame)
+	return err
+}
+
+// run reads frames and reopens the port after errors until stopped.
+func (m *Modem) run(stop chan struct{}) {
+	for {
+		m.mu.Lock()
+		file := m.file
+		m.mu.Unlock()
+
+		if file == nil {
+			if !m.reopen(stop) {
+				return
+			}
+			continue
+		}
+
+		err := m.read(bufio.NewReader(file))
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		log.Printf("Modem: Read failed, reopening: %v", err)
+		m.mu.Lock()
+		if m.file == file {
+			m.file.Close()
+			m.file = nil
+		}
+		m.mu.Unlock()
+	}
+}
+
+// reopen waits and reopens the port, returning false if the modem was closed meanwhile.
+func (m *Modem) reopen(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(reopenDelay):
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != stop {
+		return false
+	}
+	if err := m.openPort(); err != nil {
+		log.Printf("Modem: Reopen failed: %v", err)
+		return true
+	}
+	log.Printf("Modem: Reopened %s", m.port)
+	return true
+}
+
+// read passes the frames read from the port to Receive until a read fails. Bytes outside a frame are
+// skipped, so the stream resynchronises on the next FrameStart.
+func (m *Modem) read(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != FrameStart {
+			continue
+		}
+
+		length, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if length < 3 {
+			log.Printf("Modem: Invalid frame length %d", length)
+			continue
+		}
+		frame := make([]byte, length-2)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return err
+		}
+
+		if frame[0] == CmdNak && len(frame) >= 3 {
+			log.Printf("Modem: Command 0x%02X rejected, reason %d", frame[1], frame[2])
+		}
+		if frame[0] == CmdGetStatus {
+			if status, ok := parseStatus(frame[1:]); ok {
+				m.setStatus(status)
+			}
+			continue
+		}
+		if m.Receive != nil {
+			m.Receive(frame[0], frame[1:])
+		}
+	}
+}
+
+// InitModem opens the modem named in the configuration and configures it with SET_CONFIG, followed by the
+// SET_FM_PARAMS frames when FM is enabled. Frames read from the modem are passed to receive. Any modem opened
+// by a previous call is closed first.
+func InitModem(cfg *config.Config, receive func(command byte, payload []byte)) error {
+	deviceMu.Lock()
+	defer deviceMu.Unlock()
+
+	if device != nil {
+		device.Close()
+		device = nil
+	}
+	if cfg.Modem.Port == "" {
+		return errors.New("no modem port configured")
+	}
+
+	frames := [][]byte{NewSettings(cfg).SetConfigFrame()}
+	if cfg.FM.Enable {
+		frames = append(frames, NewFMSettings(cfg).FMParamsFrames()...)
+	}
+	m := NewModem(cfg.Modem.Port, frames)
+	m.Receive = receive
+	if err := m.Open(); err != nil {
+		return err
+	}
+	device = m
+	return nil
+}
+
+// IsOpen reports whether InitModem has opened a modem.
+func IsOpen() bool {
+	deviceMu.Lock()
+	defer deviceMu.Unlock()
+	return device != nil
+}
+
+// Write sends a frame with the given command and payload to the modem opened by InitModem.
+func Write(command byte, payload []byte) error {
+	deviceMu.Lock()
+	m := device
+	deviceMu.Unlock()
+
+	if m == nil {
+		return errors.New("modem not open")
+	}
+	return m.Write(command, payload)
+}
+
+// WriteFrame sends a complete frame to the modem opened by InitModem.
+func WriteFrame(frame []byte) error {
+	deviceMu.Lock()
+	m := device
+	deviceMu.Unlock()
+
+	if m == nil {
+		return errors.New("modem not open")
+	}
+	return m.WriteFrame(frame)
 }
@@ -0,0 +1,46 @@
+//go:build linux
+
+// Package modem provides the MMDVM modem link, including the Linux serial port setup.
+package modem
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openFlags opens the modem port without making it the controlling terminal.
+const openFlags = os.O_RDWR | unix.O_NOCTTY
+
+// configurePort puts the serial port in raw mode at 115200 baud, 8N1, as the MMDVM firmware expects.
+// The descriptor stays non-blocking so that Close interrupts a pending read.
+func configurePort(file *os.File) error {
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var termiosErr error
+	err = conn.Control(func(fd uintptr) {
+		t, err := unix.IoctlGetTermios(int(fd), unix.TCGETS)
+		if err != nil {
+			termiosErr = err
+			return
+		}
+		t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL |
+			unix.IXON | unix.IXOFF | unix.IXANY
+		t.Oflag &^= unix.OPOST
+		t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+		t.Cflag |= unix.CS8 | unix.CLOCAL | unix.CREAD | unix.B115200
+		t.Ispeed = unix.B115200
+		t.Ospeed = unix.B115200
+		t.Cc[unix.VMIN] = 1
+		t.Cc[unix.VTIME] = 0
+		termiosErr = unix.IoctlSetTermios(int(fd), unix.TCSETS, t)
+	})
+	if err != nil {
+		return err
+	}
+	return termiosErr
+}
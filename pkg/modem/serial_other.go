@@ -0,0 +1,15 @@
+//go:build !linux
+
+// Package modem provides the MMDVM modem link, including the serial port setup on other systems.
+package modem
+
+import "os"
+
+// openFlags opens the modem port for reading and writing.
+const openFlags = os.O_RDWR
+
+// configurePort leaves the serial port as it is; it must be set to raw mode at 115200 baud beforehand,
+// for example with stty.
+func configurePort(file *os.File) error {
+	return nil
+}
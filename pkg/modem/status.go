@@ -0,0 +1,103 @@
+package modem
+
+import (
+	"time"
+)
+
+// CmdGetStatus asks the modem for its state. The modem replies with the same command.
+const CmdGetStatus = 0x01
+
+// statusInterval is how often the modem is asked for its state.
+const statusInterval = 250 * time.Millisecond
+
+// statusTimeout is how long a status reply is trusted for.
+const statusTimeout = 2 * time.Second
+
+// statusFlagTX is set in the GET_STATUS flags while the modem is transmitting.
+const statusFlagTX = 0x01
+
+// Offsets in the GET_STATUS reply of the flags and the free space in the POCSAG transmit buffer.
+const (
+	statusOffsetFlags  = 2
+	statusOffsetPOCSAG = 9
+)
+
+// Status is the modem state from its latest reply to GET_STATUS.
+type Status struct {
+	TX          bool // The modem is transmitting
+	POCSAGSpace int  // Free space in the POCSAG transmit buffer, in batches
+}
+
+// parseStatus parses the payload of a GET_STATUS reply, returning false if it is too short.
+func parseStatus(payload []byte) (Status, bool) {
+	if len(payload) <= statusOffsetPOCSAG {
+		return Status{}, false
+	}
+	return Status{
+		TX:          payload[statusOffsetFlags]&statusFlagTX != 0,
+		POCSAGSpace: int(payload[statusOffsetPOCSAG]),
+	}, true
+}
+
+// poll asks the modem for its state every statusInterval until stopped.
+func (m *Modem) poll(stop chan struct{}) {
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// Write failures are handled by the receive loop reopening the port
+			m.WriteFrame([]byte{FrameStart, 3, CmdGetStatus})
+		}
+	}
+}
+
+// setStatus records a status reply.
+func (m *Modem) setStatus(status Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+	m.statusAt = time.Now()
+}
+
+// usePOCSAGSpace counts a POCSAG frame written since the latest status reply against the buffer space.
+func (m *Modem) usePOCSAGSpace() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.POCSAGSpace = max(m.status.POCSAGSpace-1, 0)
+}
+
+// Status returns the latest modem state, the zero Status when the modem has not replied recently.
+func (m *Modem) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.statusAt) > statusTimeout {
+		return Status{}
+	}
+	return m.status
+}
+
+// Transmitting reports whether the modem opened by InitModem is transmitting for any mode, false when none
+// is open.
+func Transmitting() bool {
+	deviceMu.Lock()
+	m := device
+	deviceMu.Unlock()
+
+	return m != nil && m.Status().TX
+}
+
+// POCSAGSpace returns the free space in the POCSAG transmit buffer of the modem opened by InitModem, in
+// batches, 0 when none is open.
+func POCSAGSpace() int {
+	deviceMu.Lock()
+	m := device
+	deviceMu.Unlock()
+
+	if m == nil {
+		return 0
+	}
+	return m.Status().POCSAGSpace
+}
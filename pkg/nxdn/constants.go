@@ -0,0 +1,117 @@
+// Package nxdn provides NXDN protocol logic.
+package nxdn
+
+// MMDVM modem frame tags used by NXDN.
+const (
+	TagData = 0x01 // Frame carries a 48-byte NXDN frame
+	TagLost = 0x02 // Modem lost the signal
+)
+
+// Frame layout in bits: the frame sync word, LICH, SACCH and two FACCH1 halves, which carry four
+// voice frames when not stolen for signalling.
+const (
+	FrameLength      = 48 // Bytes
+	fswBits          = 20
+	lichOffset       = 20
+	lichBits         = 16
+	sacchOffset      = 36
+	sacchBits        = 60
+	facch1Offset     = 96
+	facch1Bits       = 144
+	facch1Offset2    = facch1Offset + facch1Bits
+	voiceOffset      = 12 // Bytes, the first voice frame follows the SACCH
+	voiceFrameLength = 9  // Bytes of one coded AMBE frame
+)
+
+// FSWBytes starts every NXDN frame. Only the top four bits of the last byte belong to the sync word.
+var FSWBytes = []byte{0xCD, 0xF5, 0x90}
+
+// fswMask selects the sync word bits of FSWBytes.
+var fswMask = []byte{0xFF, 0xFF, 0xF0}
+
+// RF channel types (RFCT) from the LICH.
+const (
+	RFCTRCCH  = 0 // Control channel
+	RFCTRTCH  = 1 // Traffic channel
+	RFCTRDCH  = 2 // Repeater data and voice channel
+	RFCTRTCHC = 3 // Composite control and traffic channel
+)
+
+// Usage (functional channel type) values from the LICH.
+const (
+	USCSACCHNS     = 0 // Non-superframe SACCH, used by headers and terminators
+	USCUDCH        = 1 // User data channel
+	USCSACCHSS     = 2 // Superframe SACCH, used by voice frames
+	USCSACCHSSIdle = 3 // Superframe SACCH while idle
+)
+
+// Steal options from the LICH, giving which halves of the frame carry FACCH1 instead of voice.
+const (
+	StealFACCH   = 0 // Both halves are FACCH1
+	StealFACCH12 = 1 // The second half is FACCH1
+	StealFACCH11 = 2 // The first half is FACCH1
+	StealNone    = 3 // Four voice frames
+)
+
+// Directions from the LICH.
+const (
+	DirectionInbound  = 0 // Radio to repeater
+	DirectionOutbound = 1 // Repeater to radio
+)
+
+// SACCH structure values giving the position of a fragment in a superframe.
+const (
+	SR44     = 0 // Last of four fragments
+	SR34     = 1
+	SR24     = 2
+	SR14     = 3 // First of four fragments
+	SRSingle = 0 // Complete message in a non-superframe SACCH
+)
+
+// Layer 3 message types.
+const (
+	MessageVCALL      = 0x01 // Voice call
+	MessageVCALLIV    = 0x03 // Voice call initialization vector
+	MessageTXRELEX    = 0x07 // Extended transmission release
+	MessageTXREL      = 0x08 // Transmission release
+	MessageDCALLHDR   = 0x09 // Data call header
+	MessageDCALLDATA  = 0x0B // Data call user data
+	MessageDCALLACK   = 0x0C // Data call acknowledgement
+	MessageHEADDLY    = 0x0F // Header delay
+	MessageIDLE       = 0x10 // Idle
+	MessageSDCALLREQ  = 0x38 // Short data call request header
+	MessageSDCALLRESP = 0x3B // Short data call response
+)
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a transmission
+	StateAudio     = "AUDIO"     // Transmission in progress
+	StateRejected  = "REJECTED"  // RF transmission rejected, frames are ignored until it ends
+	StateIdle      = "IDLE"      // Network idle
+)
+
+// scramblerData is XORed with every frame on air. It inverts the symbols selected by the PN9 sequence
+// x^9 + x^4 + 1, starting with 0xE4 after the frame sync word.
+var scramblerData = buildScrambler()
+
+// buildScrambler builds the scrambler by flipping the sign bit of each dibit selected by the PN9 sequence.
+func buildScrambler() []byte {
+	table := make([]byte, FrameLength)
+	state := uint16(0xE4)
+	for symbol := fswBits / 2; symbol < FrameLength*4; symbol++ {
+		if state&1 != 0 {
+			table[symbol/4] |= 0x80 >> uint(2*(symbol%4))
+		}
+		feedback := (state ^ state>>4) & 1
+		state = state>>1 | feedback<<8
+	}
+	return table
+}
+
+// scramble applies the scrambler to a frame in place. Scrambling and descrambling are the same operation.
+func scramble(frame []byte) {
+	for i := 0; i < FrameLength && i < len(frame); i++ {
+		frame[i] ^= scramblerData[i]
+	}
+}
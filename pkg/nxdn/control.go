@@ -0,0 +1,393 @@
+// Package nxdn provides NXDN protocol logic, including the RF and network state machine.
+package nxdn
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// frameTimeout is how long a transmission may go without frames before it is treated as lost.
+const frameTimeout = 1500 * time.Millisecond
+
+// frameDuration is the air time of one NXDN frame.
+const frameDuration = 80 * time.Millisecond
+
+// NetworkWriter receives RF frames to forward to the network.
+type NetworkWriter interface {
+	WriteFrame(frame []byte, call Call, end bool) error
+}
+
+// Control manages the NXDN RF and network transmissions.
+type Control struct {
+	RAN      uint8                         // Radio access number RF traffic must use, 0 accepts any
+	RFState  string                        // Current RF state
+	NetState string                        // Current network state
+	Network  NetworkWriter                 // Network the RF frames are forwarded to, may be nil
+	Output   func(data []byte)             // Receives modem frames (tag plus frame) for transmission, may be nil
+	Events   func(event string, call Call) // Receives transmission start and end notifications, may be nil
+
+	RFCall  Call // Parties of the current RF transmission
+	NetCall Call // Parties of the current network transmission
+
+	mu         sync.Mutex
+	rfLICH     *LICH
+	rfSACCH    SACCHCollector
+	netSACCH   SACCHCollector
+	rfFrames   int
+	rfBadLICH  int
+	rfBadSACCH int
+	rfErrors   int
+	netFrames  int
+	lastRF     time.Time
+	lastNet    time.Time
+}
+
+// NewControl creates a new Control for the given radio access number.
+func NewControl(ran uint8) *Control {
+	return &Control{
+		RAN:      ran & 0x3F,
+		RFState:  StateListening,
+		NetState: StateIdle,
+	}
+}
+
+// WriteModem handles a frame received from the modem: a tag byte followed by the 48-byte scrambled frame.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case TagLost:
+		if c.RFState == StateAudio {
+			log.Printf("NXDN: RF transmission lost from %s", c.RFCall)
+			c.writeNetwork(TerminatorFrame(c.RFCall, c.RAN, DirectionInbound), true)
+		}
+		c.endOfRF()
+		return false
+	case TagData:
+		return c.writeRFFrame(data[1:])
+	default:
+		log.Printf("NXDN: Unknown modem tag 0x%02X", data[0])
+		return false
+	}
+}
+
+// writeRFFrame descrambles an RF frame, decodes its LICH and runs the RF state machine.
+func (c *Control) writeRFFrame(data []byte) bool {
+	if len(data) < FrameLength {
+		log.Printf("NXDN: RF frame too short: %d bytes", len(data))
+		return false
+	}
+	frame := append([]byte{}, data[:FrameLength]...)
+	scramble(frame)
+
+	lich, err := DecodeLICH(frame)
+	if err != nil {
+		if c.RFState != StateAudio || c.rfLICH == nil {
+			return false
+		}
+		// Carry on with the previous LICH
+		c.rfBadLICH++
+		last := *c.rfLICH
+		lich = &last
+	}
+	if lich.RFCT != RFCTRDCH {
+		return false
+	}
+	if lich.USC == USCUDCH {
+		if c.RFState == StateListening {
+			log.Printf("NXDN: RF data calls are not supported")
+			c.RFState = StateRejected
+		}
+		return false
+	}
+
+	sacch, errs, err := DecodeSACCH(frame)
+	if err == nil {
+		if c.RAN != 0 && sacch.RAN != c.RAN && sacch.RAN != 0 {
+			if c.RFState == StateListening {
+				log.Printf("NXDN: RF transmission on RAN %d ignored, this is RAN %d", sacch.RAN, c.RAN)
+			}
+			return false
+		}
+	} else if c.RFState == StateAudio {
+		c.rfBadSACCH++
+	}
+
+	if lich.USC == USCSACCHNS {
+		return c.writeRFSignalling(frame, lich, errs)
+	}
+	return c.writeRFVoice(frame, lich, sacch, errs)
+}
+
+// writeRFSignalling handles a non-superframe frame, whose two FACCH1 halves carry a call header or
+// transmission release.
+func (c *Control) writeRFSignalling(frame []byte, lich *LICH, errs int) bool {
+	message, facchErrs, err := decodeAnyFACCH1(frame)
+	if err != nil {
+		if c.RFState != StateAudio {
+			return false
+		}
+		// Pass the frame on unchanged
+		c.accept(frame, lich, errs, false)
+		return true
+	}
+
+	switch message.MessageType() {
+	case MessageVCALL:
+		if c.RFState == StateListening && !c.startRF(message.Call(), false) {
+			return false
+		}
+	case MessageTXREL:
+		if c.RFState == StateRejected {
+			c.endOfRF()
+			return false
+		}
+	}
+	if c.RFState != StateAudio {
+		return false
+	}
+
+	end := message.MessageType() == MessageTXREL
+	c.accept(frame, lich, errs+facchErrs, end)
+	if end {
+		c.endOfRF()
+	}
+	return true
+}
+
+// writeRFVoice handles a voice frame, joining the transmission from the SACCH superframe when the header was missed.
+func (c *Control) writeRFVoice(frame []byte, lich *LICH, sacch *SACCH, errs int) bool {
+	if c.RFState == StateRejected {
+		return false
+	}
+	if c.RFState == StateListening {
+		if sacch == nil {
+			return false
+		}
+		message := c.rfSACCH.Add(sacch)
+		if message == nil || message.MessageType() != MessageVCALL {
+			return false
+		}
+		if !c.startRF(message.Call(), true) {
+			return false
+		}
+	}
+
+	// A stolen half may carry the transmission release
+	end := false
+	for _, second := range []bool{false, true} {
+		stolen := lich.Option == StealFACCH || (second && lich.Option == StealFACCH12) || (!second && lich.Option == StealFACCH11)
+		if !stolen {
+			continue
+		}
+		if message, facchErrs, err := DecodeFACCH1(frame, second); err == nil {
+			errs += facchErrs
+			end = end || message.MessageType() == MessageTXREL
+		}
+	}
+
+	c.accept(frame, lich, errs, end)
+	if end {
+		c.endOfRF()
+	}
+	return true
+}
+
+// startRF starts an RF transmission. It returns false when the network is busy.
+func (c *Control) startRF(call Call, late bool) bool {
+	if c.NetState != StateIdle {
+		log.Printf("NXDN: RF transmission from %s ignored, network transmission in progress", call)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFCall = call
+	c.RFState = StateAudio
+	c.rfFrames = 0
+	c.rfBadLICH = 0
+	c.rfBadSACCH = 0
+	c.rfErrors = 0
+
+	if late {
+		log.Printf("NXDN: RF late entry from %s", call)
+	} else {
+		log.Printf("NXDN: RF header from %s", call)
+	}
+	c.notify("rf_start", call)
+	return true
+}
+
+// accept counts an RF frame of the current transmission and forwards it to the network with a regenerated LICH.
+func (c *Control) accept(frame []byte, lich *LICH, errs int, end bool) {
+	copy(frame, FSWBytes)
+	lich.Encode(frame)
+	c.rfLICH = lich
+	c.lastRF = time.Now()
+	c.rfFrames++
+	c.rfErrors += errs
+	c.writeNetwork(frame, end)
+}
+
+// writeNetwork forwards a descrambled RF frame to the network.
+func (c *Control) writeNetwork(frame []byte, end bool) {
+	if c.Network == nil {
+		return
+	}
+	if err := c.Network.WriteFrame(frame, c.RFCall, end); err != nil {
+		log.Printf("NXDN: Unable to forward RF frame: %v", err)
+	}
+}
+
+// endOfRF ends the current RF transmission and logs its statistics.
+func (c *Control) endOfRF() {
+	if c.RFState == StateAudio {
+		log.Printf("NXDN: RF end of transmission from %s, %.1f seconds, %d bad LICH, %d bad SACCH, %d bits corrected",
+			c.RFCall, float64(c.rfFrames)*frameDuration.Seconds(), c.rfBadLICH, c.rfBadSACCH, c.rfErrors)
+		c.notify("rf_end", c.RFCall)
+	}
+	c.RFState = StateListening
+	c.RFCall = Call{}
+	c.rfLICH = nil
+	c.rfSACCH.Reset()
+}
+
+// WriteNetworkFrame transmits a descrambled 48-byte frame received from the network on RF, marked with our
+// radio access number. It returns false when RF is busy or the frame cannot be decoded.
+func (c *Control) WriteNetworkFrame(frame []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio || len(frame) < FrameLength {
+		return false
+	}
+	out := append([]byte{}, frame[:FrameLength]...)
+
+	lich, err := DecodeLICH(out)
+	if err != nil || lich.RFCT != RFCTRDCH {
+		return false
+	}
+
+	sacch, _, err := DecodeSACCH(out)
+	if err == nil {
+		sacch.RAN = c.RAN
+		sacch.Encode(out)
+	}
+
+	end := false
+	if lich.USC == USCSACCHNS {
+		if message, _, err := decodeAnyFACCH1(out); err == nil {
+			switch message.MessageType() {
+			case MessageVCALL:
+				c.startNetwork(message.Call())
+			case MessageTXREL:
+				end = true
+			}
+		}
+	} else if c.NetState == StateIdle && sacch != nil {
+		if message := c.netSACCH.Add(sacch); message != nil && message.MessageType() == MessageVCALL {
+			c.startNetwork(message.Call())
+		}
+	}
+	if c.NetState != StateAudio {
+		return false
+	}
+
+	copy(out, FSWBytes)
+	lich.Direction = DirectionOutbound
+	lich.Encode(out)
+	scramble(out)
+
+	c.lastNet = time.Now()
+	c.netFrames++
+	c.output(append([]byte{TagData}, out...))
+
+	if end {
+		c.endOfNetwork()
+	}
+	return true
+}
+
+// startNetwork starts a network transmission if none is in progress.
+func (c *Control) startNetwork(call Call) {
+	if c.NetState != StateIdle {
+		return
+	}
+	c.NetCall = call
+	c.NetState = StateAudio
+	c.netFrames = 0
+	log.Printf("NXDN: Network transmission from %s", call)
+	c.notify("net_start", call)
+}
+
+// endOfNetwork ends the current network transmission.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("NXDN: Network end of transmission from %s, %.1f seconds",
+			c.NetCall, float64(c.netFrames)*frameDuration.Seconds())
+		c.notify("net_end", c.NetCall)
+	}
+	c.NetState = StateIdle
+	c.NetCall = Call{}
+	c.netSACCH.Reset()
+}
+
+// CheckTimeouts ends RF or network transmissions that have stopped sending frames.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("NXDN: RF transmission from %s timed out", c.RFCall)
+		c.writeNetwork(TerminatorFrame(c.RFCall, c.RAN, DirectionInbound), true)
+		c.endOfRF()
+	}
+
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		log.Printf("NXDN: Network transmission from %s timed out", c.NetCall)
+		c.output(append([]byte{TagData}, scrambled(TerminatorFrame(c.NetCall, c.RAN, DirectionOutbound))...))
+		c.endOfNetwork()
+	}
+}
+
+// TerminatorFrame builds a descrambled frame releasing the given call, with the transmission release
+// carried in both FACCH1 halves.
+func TerminatorFrame(call Call, ran uint8, direction uint8) []byte {
+	frame := make([]byte, FrameLength)
+	copy(frame, FSWBytes)
+	lich := &LICH{RFCT: RFCTRDCH, USC: USCSACCHNS, Option: StealFACCH, Direction: direction}
+	lich.Encode(frame)
+	sacch := &SACCH{Structure: SRSingle, RAN: ran}
+	sacch.Encode(frame)
+	message := NewLayer3(MessageTXREL, call.Source, call.Destination, call.Group)
+	EncodeFACCH1(frame, message, false)
+	EncodeFACCH1(frame, message, true)
+	return frame
+}
+
+// scrambled returns a scrambled copy of a frame.
+func scrambled(frame []byte) []byte {
+	out := append([]byte{}, frame...)
+	scramble(out)
+	return out
+}
+
+// output passes a modem frame to the Output callback.
+func (c *Control) output(data []byte) {
+	if c.Output != nil {
+		c.Output(data)
+	}
+}
+
+// notify passes a transmission event to the Events callback.
+func (c *Control) notify(event string, call Call) {
+	if c.Events != nil {
+		c.Events(event, call)
+	}
+}
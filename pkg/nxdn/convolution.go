@@ -0,0 +1,164 @@
+// Package nxdn provides NXDN protocol logic, including the FEC shared by the SACCH and FACCH1.
+package nxdn
+
+import "github.com/unklstewy/mmdvm_ghost/pkg/conv"
+
+// readBit returns bit n of data, MSB first.
+func readBit(data []byte, n int) byte {
+	return data[n/8] >> uint(7-n%8) & 1
+}
+
+// writeBit sets bit n of data, MSB first, to b.
+func writeBit(data []byte, n int, b byte) {
+	mask := byte(0x80) >> uint(n%8)
+	if b != 0 {
+		data[n/8] |= mask
+	} else {
+		data[n/8] &^= mask
+	}
+}
+
+// channel describes how a control channel is coded: the number of data bits before the CRC and tail,
+// the CRC width, the interleaver columns and the puncturing period and position.
+type channel struct {
+	dataBits      int
+	crcBits       int
+	columns       int
+	punctureEvery int
+	punctureAt    int
+}
+
+// SACCH: 26 data bits, CRC-6, 12x5 interleaver, every sixth coded bit punctured.
+var sacchChannel = channel{dataBits: 26, crcBits: 6, columns: 5, punctureEvery: 6, punctureAt: 5}
+
+// FACCH1: 80 data bits, CRC-12, 16x9 interleaver, every fourth coded bit punctured.
+var facch1Channel = channel{dataBits: 80, crcBits: 12, columns: 9, punctureEvery: 4, punctureAt: 1}
+
+// codedBits returns the number of coded bits before puncturing, including the four tail bits.
+func (ch channel) codedBits() int {
+	return (ch.dataBits + ch.crcBits + 4) * 2
+}
+
+// airBits returns the number of bits on air after puncturing.
+func (ch channel) airBits() int {
+	coded := ch.codedBits()
+	return coded - coded/ch.punctureEvery
+}
+
+// interleave returns the position on air of punctured bit i.
+func (ch channel) interleave(i int) int {
+	rows := ch.airBits() / ch.columns
+	return (i%ch.columns)*rows + i/ch.columns
+}
+
+// punctured reports whether coded bit i is left out on air.
+func (ch channel) punctured(i int) bool {
+	return i%ch.punctureEvery == ch.punctureAt
+}
+
+// crc returns the CRC of the data bits.
+func (ch channel) crc(bits []byte) uint16 {
+	if ch.crcBits == 6 {
+		return uint16(crc6(bits))
+	}
+	return crc12(bits)
+}
+
+// decodeBits deinterleaves, depunctures and Viterbi decodes a channel starting at bit offset of the frame.
+// It returns the decoded bits, one per byte, with the path error count.
+func (ch channel) decodeBits(frame []byte, offset int) ([]byte, int) {
+	air := make([]byte, ch.airBits())
+	for i := range air {
+		air[i] = readBit(frame, offset+ch.interleave(i))
+	}
+
+	coded := make([]byte, ch.codedBits())
+	erased := make([]bool, len(coded))
+	k := 0
+	for i := range coded {
+		if ch.punctured(i) {
+			erased[i] = true
+			continue
+		}
+		coded[i] = air[k]
+		k++
+	}
+	return conv.Decode(coded, erased)
+}
+
+// decode decodes a channel starting at bit offset of the frame. It returns the data bits packed MSB first,
+// the path error count and whether the CRC matched.
+func (ch channel) decode(frame []byte, offset int) ([]byte, int, bool) {
+	bits, errs := ch.decodeBits(frame, offset)
+	crc := uint16(0)
+	for _, b := range bits[ch.dataBits : ch.dataBits+ch.crcBits] {
+		crc = crc<<1 | uint16(b)
+	}
+	valid := crc == ch.crc(bits[:ch.dataBits])
+
+	out := make([]byte, (ch.dataBits+7)/8)
+	for i := 0; i < ch.dataBits; i++ {
+		writeBit(out, i, bits[i])
+	}
+	return out, errs, valid
+}
+
+// raw decodes a channel starting at bit offset of the frame without checking the CRC, returning the data
+// and CRC bits packed MSB first.
+func (ch channel) raw(frame []byte, offset int) []byte {
+	bits, _ := ch.decodeBits(frame, offset)
+	out := make([]byte, (ch.dataBits+ch.crcBits+7)/8)
+	for i := 0; i < ch.dataBits+ch.crcBits; i++ {
+		writeBit(out, i, bits[i])
+	}
+	return out
+}
+
+// encode adds the CRC and tail to the data bits, then codes, punctures and interleaves them into
+// the frame at bit offset.
+func (ch channel) encode(data []byte, frame []byte, offset int) {
+	bits := make([]byte, 0, ch.dataBits+ch.crcBits+4)
+	for i := 0; i < ch.dataBits; i++ {
+		bits = append(bits, readBit(data, i))
+	}
+	crc := ch.crc(bits)
+	for i := ch.crcBits - 1; i >= 0; i-- {
+		bits = append(bits, byte(crc>>uint(i)&1))
+	}
+	bits = append(bits, 0, 0, 0, 0)
+
+	k := 0
+	for i, b := range conv.Encode(bits) {
+		if ch.punctured(i) {
+			continue
+		}
+		writeBit(frame, offset+ch.interleave(k), b)
+		k++
+	}
+}
+
+// crc6 calculates the SACCH CRC-6: polynomial x^6 + x^5 + x^2 + x + 1, initial value 0x3F.
+func crc6(bits []byte) byte {
+	crc := byte(0x3F)
+	for _, b := range bits {
+		feedback := b ^ crc>>5&1
+		crc <<= 1
+		if feedback != 0 {
+			crc ^= 0x27
+		}
+	}
+	return crc & 0x3F
+}
+
+// crc12 calculates the FACCH1 CRC-12: polynomial x^12 + x^11 + x^3 + x^2 + x + 1, initial value 0xFFF.
+func crc12(bits []byte) uint16 {
+	crc := uint16(0x0FFF)
+	for _, b := range bits {
+		feedback := uint16(b) ^ crc>>11&1
+		crc <<= 1
+		if feedback != 0 {
+			crc ^= 0x080F
+		}
+	}
+	return crc & 0x0FFF
+}
@@ -0,0 +1,47 @@
+// Package nxdn provides NXDN protocol logic, including the fast associated control channel (FACCH1).
+package nxdn
+
+import "errors"
+
+// facch1DataLength is the number of message bytes carried by one FACCH1.
+const facch1DataLength = 10
+
+// DecodeFACCH1 decodes the FACCH1 in one half of a descrambled frame, the second half when second is set.
+// It returns the layer 3 message with the path error count.
+func DecodeFACCH1(frame []byte, second bool) (*Layer3, int, error) {
+	if len(frame) < FrameLength {
+		return nil, 0, errors.New("frame too short")
+	}
+
+	offset := facch1Offset
+	if second {
+		offset = facch1Offset2
+	}
+	data, errs, valid := facch1Channel.decode(frame, offset)
+	if !valid {
+		return nil, errs, errors.New("invalid FACCH1 CRC")
+	}
+
+	var message Layer3
+	copy(message[:], data)
+	return &message, errs, nil
+}
+
+// EncodeFACCH1 writes a layer 3 message as FACCH1 into one half of a descrambled frame, the second half
+// when second is set.
+func EncodeFACCH1(frame []byte, message *Layer3, second bool) {
+	offset := facch1Offset
+	if second {
+		offset = facch1Offset2
+	}
+	facch1Channel.encode(message[:facch1DataLength], frame, offset)
+}
+
+// decodeAnyFACCH1 decodes the FACCH1 from either half of a frame whose halves both carry it.
+func decodeAnyFACCH1(frame []byte) (*Layer3, int, error) {
+	message, errs, err := DecodeFACCH1(frame, false)
+	if err == nil {
+		return message, errs, nil
+	}
+	return DecodeFACCH1(frame, true)
+}
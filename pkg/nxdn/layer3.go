@@ -0,0 +1,62 @@
+// Package nxdn provides NXDN protocol logic, including layer 3 call control messages.
+package nxdn
+
+import "fmt"
+
+// Layer3Length is the length of a layer 3 message as carried by FACCH1.
+const Layer3Length = 10
+
+// Layer3 is a layer 3 call control message.
+type Layer3 [Layer3Length]byte
+
+// NewLayer3 creates a call control message of the given type between source and destination.
+func NewLayer3(messageType uint8, source, dest uint16, group bool) *Layer3 {
+	var m Layer3
+	m[0] = messageType & 0x3F
+	if !group {
+		m[2] = 0x80 // Individual call
+	}
+	m[3], m[4] = byte(source>>8), byte(source)
+	m[5], m[6] = byte(dest>>8), byte(dest)
+	return &m
+}
+
+// MessageType returns the message type, such as MessageVCALL.
+func (m *Layer3) MessageType() uint8 {
+	return m[0] & 0x3F
+}
+
+// Source returns the source unit ID.
+func (m *Layer3) Source() uint16 {
+	return uint16(m[3])<<8 | uint16(m[4])
+}
+
+// Destination returns the destination unit or group ID.
+func (m *Layer3) Destination() uint16 {
+	return uint16(m[5])<<8 | uint16(m[6])
+}
+
+// IsGroup reports whether the call is to a group rather than an individual unit.
+func (m *Layer3) IsGroup() bool {
+	return m[2]&0x80 == 0
+}
+
+// Call returns the parties of a call control message.
+func (m *Layer3) Call() Call {
+	return Call{Source: m.Source(), Destination: m.Destination(), Group: m.IsGroup()}
+}
+
+// Call identifies the parties of an NXDN call.
+type Call struct {
+	Source      uint16 // Source unit ID
+	Destination uint16 // Destination unit or group ID
+	Group       bool   // Destination is a group
+}
+
+// String returns a human readable form of the call.
+func (c Call) String() string {
+	if c.Group {
+		return fmt.Sprintf("%d to TG %d", c.Source, c.Destination)
+	}
+	return fmt.Sprintf("%d to %d", c.Source, c.Destination)
+}
@@ -0,0 +1,73 @@
+// Package nxdn provides NXDN protocol logic, including the link information channel (LICH).
+package nxdn
+
+import (
+	"errors"
+	"fmt"
+)
+
+// LICH is the link information channel, giving the type and use of the rest of the frame.
+type LICH struct {
+	RFCT      uint8 // RF channel type
+	USC       uint8 // Usage of the frame, the functional channel type
+	Option    uint8 // Steal option for voice frames, otherwise the data channel option
+	Direction uint8 // DirectionInbound or DirectionOutbound
+}
+
+// lichParity returns the even parity of the RFCT and usage bits.
+func lichParity(value byte) byte {
+	return (value>>7 ^ value>>6 ^ value>>5 ^ value>>4) & 1
+}
+
+// DecodeLICH decodes the LICH of a descrambled frame. Each LICH bit is carried in the first bit of a dibit.
+func DecodeLICH(frame []byte) (*LICH, error) {
+	if len(frame) < FrameLength {
+		return nil, errors.New("frame too short")
+	}
+
+	return ParseLICH(lichByte(frame))
+}
+
+// lichByte returns the raw LICH byte of a descrambled frame, parity bit included.
+func lichByte(frame []byte) byte {
+	var value byte
+	for i := 0; i < lichBits/2; i++ {
+		value = value<<1 | readBit(frame, lichOffset+2*i)
+	}
+	return value
+}
+
+// ParseLICH parses a raw LICH byte as carried by the network protocols, checking its parity.
+func ParseLICH(value byte) (*LICH, error) {
+	if value&1 != lichParity(value) {
+		return nil, fmt.Errorf("invalid LICH parity: 0x%02X", value)
+	}
+
+	return &LICH{
+		RFCT:      value >> 6 & 3,
+		USC:       value >> 4 & 3,
+		Option:    value >> 2 & 3,
+		Direction: value >> 1 & 1,
+	}, nil
+}
+
+// Byte returns the raw LICH byte, parity bit included.
+func (l *LICH) Byte() byte {
+	value := l.RFCT&3<<6 | l.USC&3<<4 | l.Option&3<<2 | l.Direction&1<<1
+	return value | lichParity(value)
+}
+
+// Encode writes the LICH into a descrambled frame.
+func (l *LICH) Encode(frame []byte) {
+	value := l.Byte()
+
+	for i := 0; i < lichBits/2; i++ {
+		writeBit(frame, lichOffset+2*i, value>>uint(7-i)&1)
+		writeBit(frame, lichOffset+2*i+1, 1)
+	}
+}
+
+// String returns a human readable form of the LICH.
+func (l *LICH) String() string {
+	return fmt.Sprintf("RFCT %d, usage %d, option %d, direction %d", l.RFCT, l.USC, l.Option, l.Direction)
+}
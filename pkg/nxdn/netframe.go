@@ -0,0 +1,102 @@
+// Package nxdn provides NXDN protocol logic, including the frame form used by the network protocols.
+package nxdn
+
+import (
+	"errors"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/ambe"
+)
+
+// Network frame layout: the LICH byte, the SACCH with its CRC, then two 14-byte halves each holding either
+// a FACCH1 with its CRC or two voice frames without FEC. The 49 bits of the two voice frames are packed
+// back to back, the second starting at bit 49 of the half.
+const (
+	NetFrameLength = 33
+	netSACCH       = 1
+	netHalves      = 5
+	netHalfLength  = 14
+	netVoiceBits   = 49
+)
+
+// halfStolen reports whether a half of a frame carries FACCH1 rather than voice.
+func halfStolen(lich *LICH, second bool) bool {
+	if lich.USC != USCSACCHSS && lich.USC != USCSACCHSSIdle {
+		return true
+	}
+	switch lich.Option {
+	case StealFACCH:
+		return true
+	case StealFACCH12:
+		return second
+	case StealFACCH11:
+		return !second
+	}
+	return false
+}
+
+// toNetFrame converts a descrambled frame to the network form.
+func toNetFrame(frame []byte) ([]byte, error) {
+	lich, err := DecodeLICH(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, NetFrameLength)
+	out[0] = lich.Byte()
+	copy(out[netSACCH:], sacchChannel.raw(frame, sacchOffset))
+
+	for h, second := range []bool{false, true} {
+		half := out[netHalves+h*netHalfLength:]
+		if halfStolen(lich, second) {
+			copy(half, facch1Channel.raw(frame, facch1Offset+h*facch1Bits))
+			continue
+		}
+		for k := 0; k < 2; k++ {
+			start := voiceOffset + (2*h+k)*voiceFrameLength
+			voice, _, err := ambe.Decode2450(frame[start : start+voiceFrameLength])
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < netVoiceBits; i++ {
+				writeBit(half, k*netVoiceBits+i, readBit(voice, i))
+			}
+		}
+	}
+	return out, nil
+}
+
+// fromNetFrame converts the network form back to a descrambled frame.
+func fromNetFrame(data []byte) ([]byte, error) {
+	if len(data) < NetFrameLength {
+		return nil, errors.New("network frame too short")
+	}
+	lich, err := ParseLICH(data[0])
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, FrameLength)
+	copy(frame, FSWBytes)
+	lich.Encode(frame)
+	sacchChannel.encode(data[netSACCH:netHalves], frame, sacchOffset)
+
+	for h, second := range []bool{false, true} {
+		half := data[netHalves+h*netHalfLength : netHalves+(h+1)*netHalfLength]
+		if halfStolen(lich, second) {
+			facch1Channel.encode(half, frame, facch1Offset+h*facch1Bits)
+			continue
+		}
+		for k := 0; k < 2; k++ {
+			bits := make([]byte, ambe.DataLength)
+			for i := 0; i < netVoiceBits; i++ {
+				writeBit(bits, i, readBit(half, k*netVoiceBits+i))
+			}
+			voice, err := ambe.Encode2450(bits)
+			if err != nil {
+				return nil, err
+			}
+			copy(frame[voiceOffset+(2*h+k)*voiceFrameLength:], voice)
+		}
+	}
+	return frame, nil
+}
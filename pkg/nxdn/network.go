@@ -0,0 +1,392 @@
+// Package nxdn provides NXDN protocol logic, including the NXDNGateway and Icom network links.
+package nxdn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Network protocols.
+const (
+	ProtocolIcom    = "icom"    // Icom repeater protocol, as spoken between MMDVMHost and NXDNGateway
+	ProtocolGateway = "gateway" // NXDNGateway and NXDNReflector protocol, carrying the unit and group IDs
+)
+
+// Icom protocol packets: a fixed header, a type marker in bytes 37 to 39 and the network frame from byte 40.
+var icomSignature = []byte{'I', 'C', 'O', 'M', 0x01, 0x01, 0x08, 0xE0}
+
+const (
+	icomPacketLength = 102
+	icomTypeOffset   = 37
+	icomDataOffset   = 40
+)
+
+// Icom packet type markers.
+var (
+	icomTypeVoiceHeader = []byte{0x23, 0x1C, 0x21} // Header or trailer of a voice call
+	icomTypeVoiceBody   = []byte{0x23, 0x10, 0x61} // Voice frame
+	icomTypeData        = []byte{0x23, 0x02, 0x18} // Data frame
+)
+
+// Gateway protocol packet signatures.
+var (
+	nxdnPoll   = []byte("NXDNP") // Poll, carrying the callsign and talkgroup
+	nxdnData   = []byte("NXDND") // Frame, carrying the unit IDs, flags and the network frame
+	nxdnUnlink = []byte("NXDNU") // Unlink, sent when the repeater shuts down
+)
+
+// Gateway protocol packet layout.
+const (
+	pollPacketLength = 17
+	dataPacketLength = 10 + NetFrameLength
+	callsignLength   = 10
+)
+
+// Gateway protocol data flags.
+const (
+	flagGroup   = 0x01
+	flagData    = 0x02
+	flagHeader  = 0x04
+	flagTrailer = 0x08
+)
+
+// Network timing.
+const (
+	netPollInterval     = 5 * time.Second         // Interval between gateway protocol polls
+	netLinkTimeout      = 60 * time.Second        // Link treated as down without any packets for this long
+	netStreamTimeout    = 1500 * time.Millisecond // Incoming stream watchdog
+	netReconnectDelay   = 5 * time.Second         // Delay before reopening a failed socket
+	netReadPollInterval = 100 * time.Millisecond  // Read deadline used to run the timers
+)
+
+// Network is a UDP client speaking either the Icom or the NXDNGateway protocol.
+type Network struct {
+	Frame func(frame []byte) // Receives descrambled frames of incoming transmissions, may be nil
+
+	protocol  string
+	callsign  []byte
+	tg        uint16
+	gateway   *net.UDPAddr
+	localPort int
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	stop     chan struct{}
+	linked   bool
+	lastRx   time.Time
+	lastPoll time.Time
+	inStream bool
+	lastIn   time.Time
+}
+
+// NewNetwork creates a new Network speaking the given protocol to the gateway at the given address and port,
+// listening on the given local port. The callsign and talkgroup identify the repeater in gateway protocol polls.
+func NewNetwork(protocol, callsign string, tg int, gatewayAddress string, gatewayPort, localPort int) (*Network, error) {
+	protocol = strings.ToLower(protocol)
+	if protocol != ProtocolIcom && protocol != ProtocolGateway {
+		return nil, fmt.Errorf("unknown network protocol %q", protocol)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", gatewayAddress, gatewayPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gateway address: %w", err)
+	}
+
+	return &Network{
+		protocol:  protocol,
+		callsign:  []byte(fmt.Sprintf("%-*.*s", callsignLength, callsignLength, strings.ToUpper(callsign))),
+		tg:        uint16(tg),
+		gateway:   addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts the receive loop.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("NXDN: Network opened to gateway %s using the %s protocol", n.gateway, n.protocol)
+	return nil
+}
+
+// Close sends an unlink to the gateway when using the gateway protocol, stops the receive loop and closes
+// the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	if n.protocol == ProtocolGateway {
+		if err := n.write(n.identity(nxdnUnlink)); err != nil {
+			log.Printf("NXDN: Unable to unlink from gateway: %v", err)
+		}
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("NXDN: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open NXDN network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// WriteFrame sends a descrambled frame to the gateway. The end flag marks the last frame of a transmission.
+func (n *Network) WriteFrame(frame []byte, call Call, end bool) error {
+	data, err := toNetFrame(frame)
+	if err != nil {
+		return err
+	}
+	lich, _ := ParseLICH(data[0])
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.protocol == ProtocolIcom {
+		return n.write(icomPacket(data, lich))
+	}
+	return n.write(gatewayPacket(data, lich, call, end))
+}
+
+// icomPacket builds an Icom protocol packet carrying a network frame.
+func icomPacket(data []byte, lich *LICH) []byte {
+	buffer := make([]byte, icomPacketLength)
+	copy(buffer, icomSignature)
+	switch lich.USC {
+	case USCSACCHNS:
+		copy(buffer[icomTypeOffset:], icomTypeVoiceHeader)
+	case USCUDCH:
+		copy(buffer[icomTypeOffset:], icomTypeData)
+	default:
+		copy(buffer[icomTypeOffset:], icomTypeVoiceBody)
+	}
+	copy(buffer[icomDataOffset:], data)
+	return buffer
+}
+
+// gatewayPacket builds a gateway protocol data packet carrying a network frame.
+func gatewayPacket(data []byte, lich *LICH, call Call, end bool) []byte {
+	var flags byte
+	if call.Group {
+		flags |= flagGroup
+	}
+	if lich.USC == USCUDCH {
+		flags |= flagData
+	}
+	if lich.USC == USCSACCHNS {
+		// Header and trailer frames carry the message type in the first FACCH1 byte
+		switch data[netHalves] & 0x3F {
+		case MessageVCALL:
+			flags |= flagHeader
+		case MessageTXREL:
+			flags |= flagTrailer
+		}
+	}
+	if end {
+		flags |= flagTrailer
+	}
+
+	buffer := make([]byte, 0, dataPacketLength)
+	buffer = append(buffer, nxdnData...)
+	buffer = append(buffer, byte(call.Source>>8), byte(call.Source), byte(call.Destination>>8), byte(call.Destination), flags)
+	buffer = append(buffer, data...)
+	return buffer
+}
+
+// identity builds a poll or unlink packet carrying the callsign and talkgroup.
+func (n *Network) identity(signature []byte) []byte {
+	buffer := make([]byte, 0, pollPacketLength)
+	buffer = append(buffer, signature...)
+	buffer = append(buffer, n.callsign...)
+	return append(buffer, byte(n.tg>>8), byte(n.tg))
+}
+
+// IsLinked reports whether the gateway has sent anything recently.
+func (n *Network) IsLinked() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.linked
+}
+
+// write sends a packet to the gateway. The caller must hold the lock.
+func (n *Network) write(buffer []byte) error {
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.gateway)
+	return err
+}
+
+// run receives packets, sends polls and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	buffer := make([]byte, 1500)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+		now := time.Now()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("NXDN: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.linked = false
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.gateway.IP) && addr.Port == n.gateway.Port {
+			n.receive(buffer[:length], now)
+		} else {
+			log.Printf("NXDN: Packet received from unknown address %s", addr)
+		}
+
+		n.clock(now)
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(netReconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("NXDN: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("NXDN: Network reconnected to gateway %s", n.gateway)
+	return true
+}
+
+// clock runs the poll timer, the link watchdog and the incoming stream watchdog.
+func (n *Network) clock(now time.Time) {
+	n.mu.Lock()
+	if n.protocol == ProtocolGateway && now.Sub(n.lastPoll) >= netPollInterval {
+		n.lastPoll = now
+		if err := n.write(n.identity(nxdnPoll)); err != nil {
+			log.Printf("NXDN: Unable to poll gateway: %v", err)
+		}
+	}
+	if n.linked && now.Sub(n.lastRx) > netLinkTimeout {
+		log.Printf("NXDN: Link to gateway %s lost", n.gateway)
+		n.linked = false
+	}
+	if n.inStream && now.Sub(n.lastIn) > netStreamTimeout {
+		log.Printf("NXDN: Network stream timed out")
+		n.inStream = false
+	}
+	n.mu.Unlock()
+
+}
+
+// receive dispatches a packet from the gateway.
+func (n *Network) receive(buffer []byte, now time.Time) {
+	n.mu.Lock()
+	n.lastRx = now
+	if !n.linked {
+		log.Printf("NXDN: Link to gateway %s established", n.gateway)
+		n.linked = true
+	}
+
+	var data []byte
+	end := false
+	switch {
+	case n.protocol == ProtocolIcom && bytes.HasPrefix(buffer, icomSignature[:4]):
+		if len(buffer) != icomPacketLength {
+			n.mu.Unlock()
+			return
+		}
+		data = buffer[icomDataOffset : icomDataOffset+NetFrameLength]
+
+	case n.protocol == ProtocolGateway && bytes.HasPrefix(buffer, nxdnData):
+		if len(buffer) < dataPacketLength {
+			n.mu.Unlock()
+			return
+		}
+		end = buffer[9]&flagTrailer != 0
+		data = buffer[10:dataPacketLength]
+
+	case n.protocol == ProtocolGateway && (bytes.HasPrefix(buffer, nxdnPoll) || bytes.HasPrefix(buffer, nxdnUnlink)):
+		n.mu.Unlock()
+		return
+
+	default:
+		n.mu.Unlock()
+		log.Printf("NXDN: Unknown network packet %x", buffer[:min(len(buffer), 5)])
+		return
+	}
+
+	n.inStream = !end
+	n.lastIn = now
+	n.mu.Unlock()
+
+	frame, err := fromNetFrame(data)
+	if err != nil {
+		log.Printf("NXDN: Invalid network frame: %v", err)
+		return
+	}
+	if n.Frame != nil {
+		n.Frame(frame)
+	}
+}
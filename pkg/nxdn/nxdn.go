@@ -2,15 +2,113 @@ package nxdn
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control handles the NXDN RF and network transmissions.
+var control *Control
+
+// network links the controller to an NXDNGateway, nil when disabled.
+var network *Network
+
+// HandleNXDNPacket passes a modem frame (tag byte plus 48-byte frame) to the NXDN controller.
 func HandleNXDNPacket(packet []byte) {
-	// TODO: Add NXDN packet handling logic
+	if control == nil {
+		log.Printf("NXDN: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// modemCommands maps the tags of controller frames to the modem commands transmitting them.
+var modemCommands = map[byte]byte{
+	TagData: modem.CmdNXDNData,
+}
+
+// writeModem transmits a controller frame (tag plus payload) on the modem.
+func writeModem(data []byte) {
+	command, ok := modemCommands[data[0]]
+	if !ok {
+		log.Printf("NXDN: No modem command for tag 0x%02X", data[0])
+		return
+	}
+	if err := modem.Write(command, data[1:]); err != nil {
+		log.Printf("NXDN: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so RF calls end even when no NXDNGateway is configured.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
 }
 
 // Init initializes the NXDN protocol handler with the given configuration.
 func Init(config config.NXDNConfig) {
-	fmt.Printf("NXDN protocol handler initialized with Port: %s\n", config.Port)
+	if config.RAN < 0 || config.RAN > 63 {
+		log.Printf("NXDN: Invalid RAN %d, using 1", config.RAN)
+		config.RAN = 1
+	}
+	control = NewControl(uint8(config.RAN))
+	control.Events = func(event string, _ Call) { cwid.Event(event) }
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if config.Enable && config.NetworkEnable {
+		initNetwork(config)
+	}
+	startClock(control)
+	fmt.Printf("NXDN protocol handler initialized with Port: %s, RAN: %d\n", config.Port, config.RAN)
+}
+
+// initNetwork opens the gateway link and connects it to the controller.
+func initNetwork(cfg config.NXDNConfig) {
+	n, err := NewNetwork(cfg.Protocol, cfg.Callsign, cfg.GatewayTG, cfg.GatewayAddress, cfg.GatewayPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("NXDN: %v", err)
+		return
+	}
+
+	c := control
+	n.Frame = func(frame []byte) { c.WriteNetworkFrame(frame) }
+
+	if err := n.Open(); err != nil {
+		log.Printf("NXDN: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
 }
@@ -0,0 +1,82 @@
+// Package nxdn provides NXDN protocol logic, including the slow associated control channel (SACCH).
+package nxdn
+
+import "errors"
+
+// sacchDataBits is the number of message bits carried by one SACCH.
+const sacchDataBits = 18
+
+// SACCH is a decoded slow associated control channel. Voice frames carry a layer 3 message split over
+// a superframe of four SACCH fragments.
+type SACCH struct {
+	Structure uint8   // Position of the fragment, SR14 to SR44, or SRSingle
+	RAN       uint8   // Radio access number
+	Data      [3]byte // 18 message bits, MSB first
+}
+
+// DecodeSACCH decodes the SACCH of a descrambled frame, returning it with the path error count.
+func DecodeSACCH(frame []byte) (*SACCH, int, error) {
+	if len(frame) < FrameLength {
+		return nil, 0, errors.New("frame too short")
+	}
+
+	data, errs, valid := sacchChannel.decode(frame, sacchOffset)
+	if !valid {
+		return nil, errs, errors.New("invalid SACCH CRC")
+	}
+
+	s := &SACCH{Structure: data[0] >> 6, RAN: data[0] & 0x3F}
+	for i := 0; i < sacchDataBits; i++ {
+		writeBit(s.Data[:], i, readBit(data, 8+i))
+	}
+	return s, errs, nil
+}
+
+// Encode writes the SACCH into a descrambled frame.
+func (s *SACCH) Encode(frame []byte) {
+	data := make([]byte, 4)
+	data[0] = s.Structure&3<<6 | s.RAN&0x3F
+	for i := 0; i < sacchDataBits; i++ {
+		writeBit(data, 8+i, readBit(s.Data[:], i))
+	}
+	sacchChannel.encode(data, frame, sacchOffset)
+}
+
+// SACCHCollector reassembles the layer 3 message carried by a superframe of SACCH fragments, used to
+// join a transmission whose header was missed.
+type SACCHCollector struct {
+	data    [Layer3Length]byte
+	next    uint8
+	started bool
+}
+
+// Reset discards any collected fragments.
+func (c *SACCHCollector) Reset() {
+	c.data = [Layer3Length]byte{}
+	c.next = SR14
+	c.started = false
+}
+
+// Add stores a SACCH fragment, returning the message once all four fragments have been received in order.
+func (c *SACCHCollector) Add(s *SACCH) *Layer3 {
+	if s.Structure == SR14 {
+		c.Reset()
+		c.started = true
+	} else if !c.started || s.Structure != c.next {
+		c.Reset()
+		return nil
+	}
+
+	index := int(SR14 - s.Structure)
+	for i := 0; i < sacchDataBits; i++ {
+		writeBit(c.data[:], index*sacchDataBits+i, readBit(s.Data[:], i))
+	}
+
+	if s.Structure != SR44 {
+		c.next = s.Structure - 1
+		return nil
+	}
+	message := Layer3(c.data)
+	c.Reset()
+	return &message
+}
@@ -0,0 +1,153 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the IMBE voice frame FEC.
+package p25
+
+import (
+	"errors"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/utils"
+)
+
+// IMBELength is the length in bytes of the 88 voice parameter bits of an IMBE frame, without FEC.
+const IMBELength = 11
+
+// imbeStarts are the first bits of the nine coded IMBE frames of an LDU.
+var imbeStarts = []int{114, 262, 452, 640, 830, 1020, 1208, 1398, 1578}
+
+// imbeStop returns the end of the coded IMBE frame starting at start, which spans two status symbols.
+func imbeStop(start int) int {
+	return start + imbeBits + 4
+}
+
+// Vector sizes of a coded IMBE frame: four Golay(23,12) vectors, three Hamming(15,11) vectors and
+// seven unprotected bits.
+var (
+	imbeCodedSizes = []int{23, 23, 23, 23, 15, 15, 15, 7}
+	imbeDataSizes  = []int{12, 12, 12, 12, 11, 11, 11, 7}
+)
+
+// imbeInterleave gives the position within the 144 coded bits of each bit of the coded vectors, in order.
+var imbeInterleave = buildIMBEInterleave()
+
+// buildIMBEInterleave builds the interleaver, which spreads each vector over the frame in pairs of
+// positions twelve bits apart.
+func buildIMBEInterleave() []int {
+	pairs := [][2]int{{0, 7}, {1, 6}, {2, 9}, {3, 8}, {4, 11}, {5, 10}}
+	table := make([]int, 0, imbeBits)
+	for _, p := range pairs {
+		for k := 0; k < imbeBits/12; k++ {
+			table = append(table, 12*k+p[0], 12*k+p[1])
+		}
+	}
+	return table
+}
+
+// imbeMasks returns the PRNG masks modulating vectors 1 to 6, seeded from the data of vector 0.
+func imbeMasks(seed uint32) []uint32 {
+	masks := make([]uint32, len(imbeCodedSizes))
+	pr := 16 * seed
+	for v := 1; v < 7; v++ {
+		for i := 0; i < imbeCodedSizes[v]; i++ {
+			pr = (173*pr + 13849) % 65536
+			masks[v] = masks[v]<<1 | pr>>15
+		}
+	}
+	return masks
+}
+
+// decodeIMBE strips the FEC from 144 coded bits, one per byte, returning the voice parameters packed
+// into IMBELength bytes and the number of bits corrected.
+func decodeIMBE(coded []byte) ([]byte, int) {
+	vectors := make([]uint32, len(imbeCodedSizes))
+	n := 0
+	for v, size := range imbeCodedSizes {
+		for i := 0; i < size; i++ {
+			vectors[v] = vectors[v]<<1 | uint32(coded[imbeInterleave[n]])
+			n++
+		}
+	}
+
+	data := make([]uint32, len(vectors))
+	errs := 0
+	var e int
+	data[0], e = utils.DecodeGolay23127(vectors[0])
+	errs += e
+	masks := imbeMasks(data[0])
+	for v := 1; v < len(vectors); v++ {
+		code := vectors[v] ^ masks[v]
+		switch {
+		case v < 4:
+			data[v], e = utils.DecodeGolay23127(code)
+		case v < 7:
+			data[v], e = hamming15113.decode(code)
+		default:
+			data[v], e = code, 0
+		}
+		errs += e
+	}
+
+	out := make([]byte, 0, 88)
+	for v, size := range imbeDataSizes {
+		out = append(out, valueBits(uint64(data[v]), size)...)
+	}
+	return fromBits(out), errs
+}
+
+// encodeIMBE adds the FEC to voice parameters packed as returned by decodeIMBE, returning 144 coded bits,
+// one per byte.
+func encodeIMBE(imbe []byte) []byte {
+	in := toBits(imbe, 88)
+	data := make([]uint32, len(imbeDataSizes))
+	n := 0
+	for v, size := range imbeDataSizes {
+		data[v] = uint32(bitsValue(in[n : n+size]))
+		n += size
+	}
+
+	masks := imbeMasks(data[0])
+	coded := make([]byte, imbeBits)
+	n = 0
+	for v, size := range imbeCodedSizes {
+		var code uint32
+		switch {
+		case v < 4:
+			code = utils.EncodeGolay23127(data[v])
+		case v < 7:
+			code = hamming15113.encode(data[v])
+		default:
+			code = data[v]
+		}
+		code ^= masks[v]
+		for i := size - 1; i >= 0; i-- {
+			coded[imbeInterleave[n]] = byte(code >> uint(i) & 1)
+			n++
+		}
+	}
+	return coded
+}
+
+// DecodeVoice returns the nine IMBE frames of an LDU with the number of bits corrected.
+func DecodeVoice(frame []byte) ([9][]byte, int, error) {
+	var imbe [9][]byte
+	if len(frame) < LDUFrameLength {
+		return imbe, 0, errors.New("frame too short")
+	}
+
+	total := 0
+	for i, start := range imbeStarts {
+		var errs int
+		imbe[i], errs = decodeIMBE(extractBits(frame, start, imbeStop(start)))
+		total += errs
+	}
+	return imbe, total, nil
+}
+
+// encodeVoice writes the nine IMBE frames into an LDU. Missing frames are sent as silence.
+func encodeVoice(frame []byte, imbe [9][]byte) {
+	for i, start := range imbeStarts {
+		voice := imbe[i]
+		if len(voice) != IMBELength {
+			voice = SilenceIMBE
+		}
+		insertBits(frame, encodeIMBE(voice), start)
+	}
+}
@@ -0,0 +1,89 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including bit access around the status symbols.
+package p25
+
+// readBit returns bit n of data, MSB first.
+func readBit(data []byte, n int) byte {
+	return data[n/8] >> uint(7-n%8) & 1
+}
+
+// writeBit sets bit n of data, MSB first, to b.
+func writeBit(data []byte, n int, b byte) {
+	mask := byte(0x80) >> uint(n%8)
+	if b != 0 {
+		data[n/8] |= mask
+	} else {
+		data[n/8] &^= mask
+	}
+}
+
+// isStatus reports whether bit n of a frame belongs to a status symbol, sent every 70 bits.
+func isStatus(n int) bool {
+	return n%ssSpacing >= ssStart
+}
+
+// extractBits returns the bits of a frame from start up to stop, one per byte, skipping the status symbols.
+func extractBits(frame []byte, start, stop int) []byte {
+	out := make([]byte, 0, stop-start)
+	for n := start; n < stop; n++ {
+		if !isStatus(n) {
+			out = append(out, readBit(frame, n))
+		}
+	}
+	return out
+}
+
+// insertBits writes bits, one per byte, into a frame from start onwards, skipping the status symbols.
+func insertBits(frame []byte, bits []byte, start int) {
+	n := start
+	for _, b := range bits {
+		for isStatus(n) {
+			n++
+		}
+		writeBit(frame, n, b)
+		n++
+	}
+}
+
+// addStatusSymbols sets the status symbols of a frame to inbound channel busy, as sent by a repeater.
+func addStatusSymbols(frame []byte) {
+	for n := ssStart; n+1 < len(frame)*8; n += ssSpacing {
+		writeBit(frame, n, 0)
+		writeBit(frame, n+1, 1)
+	}
+}
+
+// toBits unpacks the first count bits of data, MSB first, one per byte.
+func toBits(data []byte, count int) []byte {
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = readBit(data, i)
+	}
+	return out
+}
+
+// fromBits packs bits, one per byte, MSB first.
+func fromBits(bits []byte) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		writeBit(out, i, b)
+	}
+	return out
+}
+
+// bitsValue returns bits, one per byte, as an integer, first bit in the MSB.
+func bitsValue(bits []byte) uint64 {
+	var v uint64
+	for _, b := range bits {
+		v = v<<1 | uint64(b)
+	}
+	return v
+}
+
+// valueBits returns the low count bits of v, one per byte, MSB first.
+func valueBits(v uint64, count int) []byte {
+	out := make([]byte, count)
+	for i := range out {
+		out[i] = byte(v >> uint(count-1-i) & 1)
+	}
+	return out
+}
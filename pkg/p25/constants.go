@@ -0,0 +1,61 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic.
+package p25
+
+// MMDVM modem frame tags used by P25.
+const (
+	TagData = 0x01 // Frame carries a P25 frame
+	TagLost = 0x02 // Modem lost the signal
+)
+
+// Frame lengths in bytes, including the frame sync, NID and status symbols.
+const (
+	HDUFrameLength   = 99
+	LDUFrameLength   = 216
+	TDUFrameLength   = 18
+	TDULCFrameLength = 54
+	TSDUFrameLength  = 45
+)
+
+// Frame layout in bits.
+const (
+	nidStart  = 48  // First bit of the NID
+	nidStop   = 114 // First bit after the NID, which includes one status symbol
+	hduStop   = 780 // First bit after the HDU header data
+	imbeBits  = 144 // Coded IMBE frame
+	ssStart   = 70  // First status symbol bit
+	ssSpacing = 72  // Distance between status symbols
+)
+
+// SyncBytes starts every P25 frame.
+var SyncBytes = []byte{0x55, 0x75, 0xF5, 0xFF, 0x77, 0xFF}
+
+// Data unit IDs (DUID) from the NID.
+const (
+	DUIDHDU   = 0x0 // Header data unit
+	DUIDTDU   = 0x3 // Terminator data unit without link control
+	DUIDLDU1  = 0x5 // Logical link data unit 1, voice with link control
+	DUIDTSDU  = 0x7 // Trunking signalling data unit
+	DUIDLDU2  = 0xA // Logical link data unit 2, voice with encryption sync
+	DUIDPDU   = 0xC // Packet data unit
+	DUIDTDULC = 0xF // Terminator data unit with link control
+)
+
+// NACAny accepts RF traffic with any network access code.
+const NACAny = 0xF7E
+
+// Link control formats (LCF).
+const (
+	LCFGroup   = 0x00 // Group voice channel user
+	LCFPrivate = 0x03 // Unit to unit voice channel user
+)
+
+// ALGIDClear is the algorithm ID of unencrypted traffic.
+const ALGIDClear = 0x80
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a transmission
+	StateAudio     = "AUDIO"     // Transmission in progress
+	StateRejected  = "REJECTED"  // RF transmission rejected, frames are ignored until it ends
+	StateIdle      = "IDLE"      // Network idle
+)
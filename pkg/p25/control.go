@@ -0,0 +1,319 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the RF and network state machine.
+package p25
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// frameTimeout is how long a transmission may go without frames before it is treated as lost.
+const frameTimeout = 1500 * time.Millisecond
+
+// frameDuration is the air time of one LDU.
+const frameDuration = 180 * time.Millisecond
+
+// NetworkWriter receives RF voice to forward to the network.
+type NetworkWriter interface {
+	WriteLDU(ldu *LDU) error
+	WriteEnd() error
+}
+
+// Control manages the P25 RF and network transmissions.
+type Control struct {
+	NAC      uint16                     // Network access code RF traffic must use, NACAny accepts any; also used on transmit
+	RFState  string                     // Current RF state
+	NetState string                     // Current network state
+	Network  NetworkWriter              // Network the RF voice is forwarded to, may be nil
+	Output   func(data []byte)          // Receives modem frames (tag plus frame) for transmission, may be nil
+	Events   func(event string, lc *LC) // Receives transmission start and end notifications, may be nil
+
+	RFLC  *LC // Link control of the current RF transmission
+	NetLC *LC // Link control of the current network transmission
+
+	mu        sync.Mutex
+	rfHeader  *Header
+	rfFrames  int
+	rfErrors  int
+	rfBadLC   int
+	netFrames int
+	lastRF    time.Time
+	lastNet   time.Time
+}
+
+// NewControl creates a new Control for the given network access code.
+func NewControl(nac uint16) *Control {
+	return &Control{
+		NAC:      nac & 0xFFF,
+		RFState:  StateListening,
+		NetState: StateIdle,
+	}
+}
+
+// WriteModem handles a frame received from the modem: a tag byte followed by a P25 frame.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case TagLost:
+		if c.RFState == StateAudio {
+			log.Printf("P25: RF transmission lost from %s", c.RFLC)
+			c.writeNetworkEnd()
+		}
+		c.endOfRF()
+		return false
+	case TagData:
+		return c.writeRFFrame(data[1:])
+	default:
+		log.Printf("P25: Unknown modem tag 0x%02X", data[0])
+		return false
+	}
+}
+
+// writeRFFrame decodes the NID of an RF frame, filters it by NAC and dispatches it by data unit.
+func (c *Control) writeRFFrame(frame []byte) bool {
+	nid, _, err := DecodeNID(frame)
+	if err != nil {
+		return false
+	}
+	if c.NAC != NACAny && nid.NAC != c.NAC {
+		if c.RFState == StateListening && (nid.DUID == DUIDHDU || nid.DUID == DUIDLDU1) {
+			log.Printf("P25: RF transmission on NAC 0x%03X ignored, this is NAC 0x%03X", nid.NAC, c.NAC)
+		}
+		return false
+	}
+
+	switch nid.DUID {
+	case DUIDHDU:
+		return c.writeRFHeader(frame)
+	case DUIDLDU1, DUIDLDU2:
+		return c.writeRFLDU(frame, nid.DUID)
+	case DUIDTDU, DUIDTDULC:
+		if c.RFState == StateRejected {
+			c.endOfRF()
+			return false
+		}
+		if c.RFState != StateAudio {
+			return false
+		}
+		c.writeNetworkEnd()
+		c.endOfRF()
+		return true
+	case DUIDTSDU:
+		if c.RFState == StateListening {
+			log.Printf("P25: RF TSDU on NAC 0x%03X", nid.NAC)
+		}
+		return false
+	default:
+		if c.RFState == StateListening {
+			log.Printf("P25: RF data unit 0x%X is not supported", nid.DUID)
+		}
+		return false
+	}
+}
+
+// writeRFHeader remembers the header preceding a transmission.
+func (c *Control) writeRFHeader(frame []byte) bool {
+	if c.RFState != StateListening {
+		return false
+	}
+	header, _, err := DecodeHDU(frame)
+	if err != nil {
+		log.Printf("P25: Invalid RF header: %v", err)
+		return false
+	}
+	if header.ALGID != ALGIDClear {
+		log.Printf("P25: RF header for TG %d, encrypted with algorithm 0x%02X", header.TGID, header.ALGID)
+	} else {
+		log.Printf("P25: RF header for TG %d", header.TGID)
+	}
+	c.rfHeader = header
+	return true
+}
+
+// writeRFLDU decodes a voice frame, starting a transmission on the first LDU1 with valid link control.
+func (c *Control) writeRFLDU(frame []byte, duid uint8) bool {
+	if c.RFState == StateRejected {
+		return false
+	}
+
+	ldu, errs, err := DecodeLDU(frame, duid)
+	if err != nil {
+		return false
+	}
+
+	if c.RFState == StateListening {
+		if ldu.LC == nil {
+			return false
+		}
+		if !c.startRF(ldu.LC) {
+			return false
+		}
+	}
+
+	if duid == DUIDLDU1 {
+		if ldu.LC == nil {
+			// Carry on with the link control of the transmission
+			c.rfBadLC++
+			ldu.LC = c.RFLC
+		} else if *ldu.LC != *c.RFLC {
+			log.Printf("P25: RF link control changed to %s", ldu.LC)
+			c.RFLC = ldu.LC
+		}
+	}
+
+	c.lastRF = time.Now()
+	c.rfFrames++
+	c.rfErrors += errs
+
+	if c.Network != nil {
+		if err := c.Network.WriteLDU(ldu); err != nil {
+			log.Printf("P25: Unable to forward RF voice: %v", err)
+		}
+	}
+	return true
+}
+
+// startRF starts an RF transmission. It returns false when the network is busy.
+func (c *Control) startRF(lc *LC) bool {
+	if c.NetState != StateIdle {
+		log.Printf("P25: RF transmission from %s ignored, network transmission in progress", lc)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFLC = lc
+	c.RFState = StateAudio
+	c.rfFrames = 0
+	c.rfErrors = 0
+	c.rfBadLC = 0
+
+	if c.rfHeader == nil {
+		log.Printf("P25: RF late entry from %s", lc)
+	} else {
+		log.Printf("P25: RF transmission from %s", lc)
+	}
+	c.notify("rf_start", lc)
+	return true
+}
+
+// writeNetworkEnd tells the network the RF transmission has ended.
+func (c *Control) writeNetworkEnd() {
+	if c.Network == nil {
+		return
+	}
+	if err := c.Network.WriteEnd(); err != nil {
+		log.Printf("P25: Unable to forward RF end of transmission: %v", err)
+	}
+}
+
+// endOfRF ends the current RF transmission and logs its statistics.
+func (c *Control) endOfRF() {
+	if c.RFState == StateAudio {
+		log.Printf("P25: RF end of transmission from %s, %.1f seconds, %d bad LC, %d bits corrected",
+			c.RFLC, float64(c.rfFrames)*frameDuration.Seconds(), c.rfBadLC, c.rfErrors)
+		c.notify("rf_end", c.RFLC)
+	}
+	c.RFState = StateListening
+	c.RFLC = nil
+	c.rfHeader = nil
+}
+
+// WriteNetworkLDU transmits an LDU received from the network on RF, preceded by a header at the start of a
+// transmission. It returns false when RF is busy or a transmission cannot be started yet.
+func (c *Control) WriteNetworkLDU(ldu *LDU) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio {
+		return false
+	}
+
+	if c.NetState == StateIdle {
+		// Transmissions start on link control
+		if ldu.DUID != DUIDLDU1 || ldu.LC == nil {
+			return false
+		}
+		c.NetLC = ldu.LC
+		c.NetState = StateAudio
+		c.netFrames = 0
+		log.Printf("P25: Network transmission from %s", ldu.LC)
+		c.notify("net_start", ldu.LC)
+
+		header := &Header{MFID: ldu.LC.MFID}
+		header.ALGID = ALGIDClear
+		if ldu.LC.IsGroup() {
+			header.TGID = uint16(ldu.LC.Destination)
+		}
+		c.output(EncodeHDU(c.NAC, header))
+	}
+
+	if ldu.DUID == DUIDLDU1 && ldu.LC == nil {
+		ldu.LC = c.NetLC
+	}
+
+	c.lastNet = time.Now()
+	c.netFrames++
+	c.output(ldu.Encode(c.NAC))
+	return true
+}
+
+// WriteNetworkEnd ends the network transmission on RF with a terminator.
+func (c *Control) WriteNetworkEnd() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.NetState == StateAudio {
+		c.output(EncodeTDU(c.NAC))
+	}
+	c.endOfNetwork()
+}
+
+// endOfNetwork ends the current network transmission.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("P25: Network end of transmission from %s, %.1f seconds",
+			c.NetLC, float64(c.netFrames)*frameDuration.Seconds())
+		c.notify("net_end", c.NetLC)
+	}
+	c.NetState = StateIdle
+	c.NetLC = nil
+}
+
+// CheckTimeouts ends RF or network transmissions that have stopped sending frames.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("P25: RF transmission from %s timed out", c.RFLC)
+		c.writeNetworkEnd()
+		c.endOfRF()
+	}
+
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		log.Printf("P25: Network transmission from %s timed out", c.NetLC)
+		c.output(EncodeTDU(c.NAC))
+		c.endOfNetwork()
+	}
+}
+
+// output passes a frame to the Output callback with its modem tag.
+func (c *Control) output(frame []byte) {
+	if c.Output != nil {
+		c.Output(append([]byte{TagData}, frame...))
+	}
+}
+
+// notify passes a transmission event to the Events callback.
+func (c *Control) notify(event string, lc *LC) {
+	if c.Events != nil {
+		c.Events(event, lc)
+	}
+}
@@ -0,0 +1,265 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the block codes protecting link control,
+// headers and voice.
+package p25
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/utils"
+)
+
+// hamming describes a single error correcting Hamming code by the data bits each parity bit covers.
+type hamming struct {
+	dataBits int
+	parity   [][]int
+	errors   map[uint32]int // Syndrome to the position of the bit in error
+}
+
+// Hamming(10,6,3) protects the hex words of link control and encryption sync.
+var hamming1063 = newHamming(6, [][]int{{0, 1, 2, 5}, {0, 1, 3, 5}, {0, 2, 3, 4}, {1, 2, 3, 4}})
+
+// Hamming(15,11,3) protects the last three vectors of an IMBE frame.
+var hamming15113 = newHamming(11, [][]int{{0, 1, 2, 3, 5, 7, 8}, {1, 2, 3, 4, 6, 8, 9}, {2, 3, 4, 5, 7, 9, 10}, {0, 1, 2, 4, 6, 7, 10}})
+
+// newHamming builds a Hamming code and its syndrome table.
+func newHamming(dataBits int, parity [][]int) *hamming {
+	h := &hamming{dataBits: dataBits, parity: parity, errors: make(map[uint32]int)}
+	length := dataBits + len(parity)
+	for pos := 0; pos < length; pos++ {
+		h.errors[h.syndrome(uint32(1)<<uint(length-1-pos))] = pos
+	}
+	return h
+}
+
+// paritiesOf returns the parity bits of data bits, first parity bit in the MSB.
+func (h *hamming) paritiesOf(data uint32) uint32 {
+	var p uint32
+	for _, covered := range h.parity {
+		var b uint32
+		for _, i := range covered {
+			b ^= data >> uint(h.dataBits-1-i) & 1
+		}
+		p = p<<1 | b
+	}
+	return p
+}
+
+// syndrome returns the syndrome of a codeword, data bits first.
+func (h *hamming) syndrome(code uint32) uint32 {
+	data := code >> uint(len(h.parity))
+	return h.paritiesOf(data) ^ code&(1<<uint(len(h.parity))-1)
+}
+
+// encode returns the codeword of data bits, data first.
+func (h *hamming) encode(data uint32) uint32 {
+	return data<<uint(len(h.parity)) | h.paritiesOf(data)
+}
+
+// decode corrects a single bit error, returning the data bits and the number of bits corrected.
+func (h *hamming) decode(code uint32) (uint32, int) {
+	errs := 0
+	if s := h.syndrome(code); s != 0 {
+		if pos, ok := h.errors[s]; ok {
+			code ^= 1 << uint(h.dataBits+len(h.parity)-1-pos)
+		}
+		errs = 1
+	}
+	return code >> uint(len(h.parity)), errs
+}
+
+// encodeGolay186 encodes 6 data bits with the Golay(18,6,8) code, a shortened Golay(24,12,8).
+func encodeGolay186(data uint32) uint32 {
+	return utils.EncodeGolay24128(data&0x3F) & 0x3FFFF
+}
+
+// decodeGolay186 decodes a Golay(18,6,8) codeword, returning the 6 data bits and the number of bits corrected.
+func decodeGolay186(code uint32) (uint32, int) {
+	data, _ := utils.DecodeGolay24128(code & 0x3FFFF)
+	return data & 0x3F, bits.OnesCount32(code&0x3FFFF ^ encodeGolay186(data))
+}
+
+// GF(64) arithmetic for the Reed-Solomon codes, with primitive polynomial x^6 + x + 1.
+var gfExp, gfLog = buildGF64()
+
+// buildGF64 builds the exponent and logarithm tables of GF(64).
+func buildGF64() ([126]byte, [64]byte) {
+	var exp [126]byte
+	var log [64]byte
+	x := 1
+	for i := 0; i < 63; i++ {
+		exp[i] = byte(x)
+		exp[i+63] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x40 != 0 {
+			x ^= 0x43
+		}
+	}
+	return exp, log
+}
+
+// gfMul multiplies two elements of GF(64).
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// rsGenerator returns the generator polynomial with roots alpha^1 to alpha^parity, highest power first.
+func rsGenerator(parity int) []byte {
+	g := []byte{1}
+	for i := 1; i <= parity; i++ {
+		next := make([]byte, len(g)+1)
+		for j, c := range g {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfExp[i])
+		}
+		g = next
+	}
+	return g
+}
+
+// rsEncode returns the parity symbols of a shortened Reed-Solomon code over GF(64) for the data symbols.
+func rsEncode(data []byte, parity int) []byte {
+	g := rsGenerator(parity)
+	remainder := make([]byte, parity)
+	for _, d := range data {
+		feedback := d ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[parity-1] = 0
+		for j := 0; j < parity; j++ {
+			remainder[j] ^= gfMul(feedback, g[j+1])
+		}
+	}
+	return remainder
+}
+
+// gfDiv divides two elements of GF(64), b non-zero.
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(int(gfLog[a])+63-int(gfLog[b]))%63]
+}
+
+// gfEval evaluates a polynomial over GF(64), lowest power first, at x.
+func gfEval(poly []byte, x byte) byte {
+	var y byte
+	for i := len(poly) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ poly[i]
+	}
+	return y
+}
+
+// rsDecode corrects up to parity/2 symbol errors in place in a shortened Reed-Solomon codeword over GF(64),
+// data symbols followed by parity symbols. It finds the error locator with Berlekamp-Massey, the error
+// positions with a Chien search and the error values with Forney's algorithm, returning the number of bits
+// corrected.
+func rsDecode(codeword []byte, parity int) (int, error) {
+	n := len(codeword)
+
+	// Syndromes S1 to S(parity), the codeword evaluated at the roots of the generator
+	syndromes := make([]byte, parity)
+	valid := true
+	for j := range syndromes {
+		root := gfExp[j+1]
+		var s byte
+		for _, c := range codeword {
+			s = gfMul(s, root) ^ c
+		}
+		syndromes[j] = s
+		valid = valid && s == 0
+	}
+	if valid {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey: the error locator polynomial, lowest power first
+	locator := []byte{1}
+	previous := []byte{1}
+	degree, shift, lastDiscrepancy := 0, 1, byte(1)
+	for k := 0; k < parity; k++ {
+		discrepancy := syndromes[k]
+		for i := 1; i <= degree && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], syndromes[k-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		scale := gfDiv(discrepancy, lastDiscrepancy)
+		next := make([]byte, max(len(locator), len(previous)+shift))
+		copy(next, locator)
+		for i, c := range previous {
+			next[i+shift] ^= gfMul(scale, c)
+		}
+		if 2*degree <= k {
+			previous, degree, lastDiscrepancy, shift = locator, k+1-degree, discrepancy, 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if degree > parity/2 {
+		return 0, fmt.Errorf("too many Reed-Solomon errors: %d", degree)
+	}
+
+	// Chien search: the symbol at index i has power n-1-i, and is in error when the locator has a root at
+	// the inverse of its position
+	var positions []int
+	for i := 0; i < n; i++ {
+		if gfEval(locator, gfExp[(63-(n-1-i)%63)%63]) == 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) != degree {
+		return 0, fmt.Errorf("uncorrectable Reed-Solomon errors: %d located of %d", len(positions), degree)
+	}
+
+	// Forney: the error evaluator is the syndrome polynomial times the locator, modulo x^parity
+	evaluator := make([]byte, parity)
+	for i := range evaluator {
+		for j := 0; j <= i && j < len(locator); j++ {
+			evaluator[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for j := 1; j < len(locator); j += 2 {
+		derivative[j-1] = locator[j]
+	}
+
+	corrected := 0
+	for _, i := range positions {
+		inverse := gfExp[(63-(n-1-i)%63)%63]
+		denominator := gfEval(derivative, inverse)
+		if denominator == 0 {
+			return 0, errors.New("uncorrectable Reed-Solomon errors")
+		}
+		value := gfDiv(gfEval(evaluator, inverse), denominator)
+		codeword[i] ^= value
+		corrected += bits.OnesCount8(value)
+	}
+	return corrected, nil
+}
+
+// hexWords splits bits, one per byte, into 6-bit symbols.
+func hexWords(bits []byte) []byte {
+	out := make([]byte, len(bits)/6)
+	for i := range out {
+		out[i] = byte(bitsValue(bits[i*6 : i*6+6]))
+	}
+	return out
+}
+
+// hexBits joins 6-bit symbols into bits, one per byte.
+func hexBits(words []byte) []byte {
+	out := make([]byte, 0, len(words)*6)
+	for _, w := range words {
+		out = append(out, valueBits(uint64(w), 6)...)
+	}
+	return out
+}
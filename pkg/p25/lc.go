@@ -0,0 +1,190 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including link control, encryption sync and the
+// header data unit.
+package p25
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Hex word segments carrying link control in LDU1 and encryption sync in LDU2, between the voice frames.
+var ldHexStarts = []int{410, 600, 788, 978, 1168, 1356}
+
+// ldHexStops are the ends of the hex word segments.
+var ldHexStops = []int{452, 640, 830, 1020, 1208, 1398}
+
+// Reed-Solomon parity symbol counts.
+const (
+	lcParity  = 12 // RS(24,12,13) protecting link control
+	esParity  = 8  // RS(24,16,9) protecting encryption sync
+	hduParity = 16 // RS(36,20,17) protecting the header
+)
+
+// LC is the link control carried by LDU1 and terminators, identifying the parties of a call.
+type LC struct {
+	LCF            uint8  // Link control format, LCFGroup or LCFPrivate
+	MFID           uint8  // Manufacturer ID
+	ServiceOptions uint8  // Emergency, encryption and priority flags
+	Destination    uint32 // Talkgroup for group calls, unit ID for private calls
+	Source         uint32 // Source unit ID
+}
+
+// IsGroup reports whether the call is to a talkgroup.
+func (lc *LC) IsGroup() bool {
+	return lc.LCF&0x3F != LCFPrivate
+}
+
+// bytes returns the 72 bits of link control.
+func (lc *LC) bytes() []byte {
+	b := make([]byte, 9)
+	b[0], b[1], b[2] = lc.LCF, lc.MFID, lc.ServiceOptions
+	if lc.IsGroup() {
+		b[4], b[5] = byte(lc.Destination>>8), byte(lc.Destination)
+	} else {
+		b[3], b[4], b[5] = byte(lc.Destination>>16), byte(lc.Destination>>8), byte(lc.Destination)
+	}
+	b[6], b[7], b[8] = byte(lc.Source>>16), byte(lc.Source>>8), byte(lc.Source)
+	return b
+}
+
+// parseLC parses 72 bits of link control.
+func parseLC(b []byte) *LC {
+	lc := &LC{
+		LCF:            b[0],
+		MFID:           b[1],
+		ServiceOptions: b[2],
+		Source:         uint32(b[6])<<16 | uint32(b[7])<<8 | uint32(b[8]),
+	}
+	if lc.IsGroup() {
+		lc.Destination = uint32(b[4])<<8 | uint32(b[5])
+	} else {
+		lc.Destination = uint32(b[3])<<16 | uint32(b[4])<<8 | uint32(b[5])
+	}
+	return lc
+}
+
+// String returns a human readable form of the call.
+func (lc *LC) String() string {
+	if lc.IsGroup() {
+		return fmt.Sprintf("%d to TG %d", lc.Source, lc.Destination)
+	}
+	return fmt.Sprintf("%d to %d", lc.Source, lc.Destination)
+}
+
+// Encryption is the encryption sync carried by LDU2 and the header.
+type Encryption struct {
+	MI    [9]byte // Message indicator
+	ALGID uint8   // Algorithm ID, ALGIDClear when unencrypted
+	KID   uint16  // Key ID
+}
+
+// bytes returns the 96 bits of encryption sync.
+func (es *Encryption) bytes() []byte {
+	b := make([]byte, 12)
+	copy(b, es.MI[:])
+	b[9], b[10], b[11] = es.ALGID, byte(es.KID>>8), byte(es.KID)
+	return b
+}
+
+// parseEncryption parses 96 bits of encryption sync.
+func parseEncryption(b []byte) *Encryption {
+	es := &Encryption{ALGID: b[9], KID: uint16(b[10])<<8 | uint16(b[11])}
+	copy(es.MI[:], b[:9])
+	return es
+}
+
+// decodeHexSegments reads the Hamming(10,6,3) coded hex words between the voice frames of an LDU, corrects
+// them with their Reed-Solomon parity and returns the data bytes with the number of bits corrected.
+func decodeHexSegments(frame []byte, parity int) ([]byte, int, error) {
+	var coded []byte
+	for i, start := range ldHexStarts {
+		coded = append(coded, extractBits(frame, start, ldHexStops[i])...)
+	}
+
+	words := make([]byte, len(coded)/10)
+	errs := 0
+	for i := range words {
+		data, e := hamming1063.decode(uint32(bitsValue(coded[i*10 : i*10+10])))
+		words[i] = byte(data)
+		errs += e
+	}
+	corrected, err := rsDecode(words, parity)
+	errs += corrected
+	if err != nil {
+		return nil, errs, err
+	}
+	return fromBits(hexBits(words[:len(words)-parity])), errs, nil
+}
+
+// encodeHexSegments writes data bytes as Reed-Solomon and Hamming(10,6,3) coded hex words between the
+// voice frames of an LDU.
+func encodeHexSegments(frame []byte, data []byte, parity int) {
+	words := hexWords(toBits(data, len(data)*8))
+	words = append(words, rsEncode(words, parity)...)
+
+	coded := make([]byte, 0, len(words)*10)
+	for _, w := range words {
+		coded = append(coded, valueBits(uint64(hamming1063.encode(uint32(w))), 10)...)
+	}
+	for i, start := range ldHexStarts {
+		insertBits(frame, coded[i*40:(i+1)*40], start)
+	}
+}
+
+// Header is the content of a header data unit, sent before the first LDU of a transmission.
+type Header struct {
+	Encryption
+	MFID uint8  // Manufacturer ID
+	TGID uint16 // Talkgroup
+}
+
+// DecodeHDU decodes the header of a header data unit, returning it with the number of bits corrected.
+func DecodeHDU(frame []byte) (*Header, int, error) {
+	if len(frame) < HDUFrameLength {
+		return nil, 0, errors.New("frame too short")
+	}
+
+	coded := extractBits(frame, nidStop, hduStop)
+	words := make([]byte, len(coded)/18)
+	errs := 0
+	for i := range words {
+		data, e := decodeGolay186(uint32(bitsValue(coded[i*18 : i*18+18])))
+		words[i] = byte(data)
+		errs += e
+	}
+	corrected, err := rsDecode(words, hduParity)
+	errs += corrected
+	if err != nil {
+		return nil, errs, fmt.Errorf("invalid header: %w", err)
+	}
+
+	b := fromBits(hexBits(words[:len(words)-hduParity]))
+	h := &Header{MFID: b[9], TGID: uint16(b[13])<<8 | uint16(b[14])}
+	copy(h.MI[:], b[:9])
+	h.ALGID = b[10]
+	h.KID = uint16(b[11])<<8 | uint16(b[12])
+	return h, errs, nil
+}
+
+// EncodeHDU builds a header data unit frame for the given NAC.
+func EncodeHDU(nac uint16, h *Header) []byte {
+	frame := make([]byte, HDUFrameLength)
+	nid := &NID{NAC: nac, DUID: DUIDHDU}
+	nid.Encode(frame)
+
+	b := make([]byte, 15)
+	copy(b, h.MI[:])
+	b[9], b[10] = h.MFID, h.ALGID
+	b[11], b[12] = byte(h.KID>>8), byte(h.KID)
+	b[13], b[14] = byte(h.TGID>>8), byte(h.TGID)
+
+	words := hexWords(toBits(b, 120))
+	words = append(words, rsEncode(words, hduParity)...)
+	coded := make([]byte, 0, len(words)*18)
+	for _, w := range words {
+		coded = append(coded, valueBits(uint64(encodeGolay186(uint32(w))), 18)...)
+	}
+	insertBits(frame, coded, nidStop)
+	addStatusSymbols(frame)
+	return frame
+}
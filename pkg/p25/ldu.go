@@ -0,0 +1,82 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the logical link data units carrying voice.
+package p25
+
+import (
+	"errors"
+)
+
+// LDU is a logical link data unit: nine IMBE voice frames with either link control (LDU1) or encryption
+// sync (LDU2).
+type LDU struct {
+	DUID       uint8       // DUIDLDU1 or DUIDLDU2
+	Voice      [9][]byte   // IMBE voice parameters, IMBELength bytes each
+	LC         *LC         // Link control of an LDU1, nil if it could not be decoded
+	Encryption *Encryption // Encryption sync of an LDU2, nil if it could not be decoded
+}
+
+// DecodeLDU decodes an LDU1 or LDU2 frame, returning it with the number of bits corrected. Link control or
+// encryption sync failing its Reed-Solomon check is left nil rather than failing the frame.
+func DecodeLDU(frame []byte, duid uint8) (*LDU, int, error) {
+	if duid != DUIDLDU1 && duid != DUIDLDU2 {
+		return nil, 0, errors.New("not an LDU")
+	}
+
+	voice, errs, err := DecodeVoice(frame)
+	if err != nil {
+		return nil, 0, err
+	}
+	ldu := &LDU{DUID: duid, Voice: voice}
+
+	if duid == DUIDLDU1 {
+		data, e, err := decodeHexSegments(frame, lcParity)
+		errs += e
+		if err == nil {
+			ldu.LC = parseLC(data)
+		}
+	} else {
+		data, e, err := decodeHexSegments(frame, esParity)
+		errs += e
+		if err == nil {
+			ldu.Encryption = parseEncryption(data)
+		}
+	}
+	return ldu, errs, nil
+}
+
+// Encode builds the LDU frame for the given NAC. Missing link control or encryption sync is sent as clear.
+func (l *LDU) Encode(nac uint16) []byte {
+	frame := make([]byte, LDUFrameLength)
+	nid := &NID{NAC: nac, DUID: l.DUID}
+	nid.Encode(frame)
+	encodeVoice(frame, l.Voice)
+
+	if l.DUID == DUIDLDU1 {
+		lc := l.LC
+		if lc == nil {
+			lc = &LC{}
+		}
+		encodeHexSegments(frame, lc.bytes(), lcParity)
+	} else {
+		es := l.Encryption
+		if es == nil {
+			es = &Encryption{ALGID: ALGIDClear}
+		}
+		encodeHexSegments(frame, es.bytes(), esParity)
+	}
+
+	// The low speed data is left empty, which is a valid codeword
+	addStatusSymbols(frame)
+	return frame
+}
+
+// EncodeTDU builds a terminator data unit frame for the given NAC.
+func EncodeTDU(nac uint16) []byte {
+	frame := make([]byte, TDUFrameLength)
+	nid := &NID{NAC: nac, DUID: DUIDTDU}
+	nid.Encode(frame)
+	addStatusSymbols(frame)
+	return frame
+}
+
+// SilenceIMBE is an IMBE frame of silence, used to fill voice frames lost on the network.
+var SilenceIMBE = []byte{0x04, 0x0C, 0xFD, 0x7B, 0xFB, 0x7D, 0xF2, 0x7B, 0x3D, 0x9E, 0x45}
@@ -0,0 +1,452 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the P25Gateway and reflector network link.
+package p25
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Network packet types.
+const (
+	netPoll   = 0xF0 // Poll, carrying the callsign
+	netUnlink = 0xF1 // Unlink, sent when the repeater shuts down
+	netTDU    = 0x80 // Terminator
+	netLDU1   = 0x62 // First of the nine LDU1 voice records
+	netLDU2   = 0x6B // First of the nine LDU2 voice records
+)
+
+// callsignLength is the length of the callsign carried by polls and unlinks.
+const callsignLength = 10
+
+// tduPacketLength is the length of a terminator packet.
+const tduPacketLength = 17
+
+// voiceRecord is the layout of one of the nine voice records of an LDU on the network.
+type voiceRecord struct {
+	length int // Record length in bytes
+	imbe   int // Offset of the IMBE voice frame
+}
+
+// voiceRecords are the layouts of the records carrying the nine voice frames of an LDU, in order.
+var voiceRecords = [9]voiceRecord{
+	{22, 10}, {14, 1}, {17, 5}, {17, 5}, {17, 5}, {17, 5}, {17, 5}, {17, 5}, {16, 4},
+}
+
+// Network timing.
+const (
+	netPollInterval     = 5 * time.Second         // Interval between polls
+	netLinkTimeout      = 60 * time.Second        // Link treated as down without any packets for this long
+	netStreamTimeout    = 1500 * time.Millisecond // Incoming stream watchdog
+	netReconnectDelay   = 5 * time.Second         // Delay before reopening a failed socket
+	netReadPollInterval = 100 * time.Millisecond  // Read deadline used to run the timers
+)
+
+// Network is a UDP client speaking the P25Gateway and P25Reflector protocol, which carries each LDU as nine
+// voice records with the link control or encryption sync spread over them.
+type Network struct {
+	LDU func(ldu *LDU) // Receives LDUs of incoming transmissions, may be nil
+	End func()         // Called at the end of an incoming transmission, may be nil
+
+	callsign  []byte
+	gateway   *net.UDPAddr
+	localPort int
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	stop     chan struct{}
+	linked   bool
+	lastRx   time.Time
+	lastPoll time.Time
+	lastLC   *LC
+	inLDU    *LDU
+	inData   []byte
+	inMask   uint16
+	inActive bool
+	lastIn   time.Time
+}
+
+// NewNetwork creates a new Network identifying itself with the given callsign to the gateway at the given
+// address and port, listening on the given local port.
+func NewNetwork(callsign, gatewayAddress string, gatewayPort, localPort int) (*Network, error) {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", gatewayAddress, gatewayPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gateway address: %w", err)
+	}
+
+	id := []byte(fmt.Sprintf("%-*.*s", callsignLength, callsignLength, strings.ToUpper(callsign)))
+	return &Network{
+		callsign:  id,
+		gateway:   addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts the receive loop.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("P25: Network opened to gateway %s", n.gateway)
+	return nil
+}
+
+// Close sends an unlink to the gateway, stops the receive loop and closes the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	if err := n.write(n.identity(netUnlink)); err != nil {
+		log.Printf("P25: Unable to unlink from gateway: %v", err)
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("P25: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open P25 network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// WriteLDU sends an LDU to the gateway as nine voice records. An LDU1 without link control is sent with the
+// link control of the previous one.
+func (n *Network) WriteLDU(ldu *LDU) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var records [][]byte
+	switch ldu.DUID {
+	case DUIDLDU1:
+		lc := ldu.LC
+		if lc == nil {
+			lc = n.lastLC
+		}
+		if lc == nil {
+			return errors.New("no link control for LDU1")
+		}
+		n.lastLC = lc
+		records = ldu1Records(ldu, lc)
+	case DUIDLDU2:
+		records = ldu2Records(ldu)
+	default:
+		return errors.New("not an LDU")
+	}
+
+	for _, record := range records {
+		if err := n.write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteEnd sends a terminator to the gateway.
+func (n *Network) WriteEnd() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.lastLC = nil
+	packet := make([]byte, tduPacketLength)
+	packet[0] = netTDU
+	return n.write(packet)
+}
+
+// buildRecords builds the nine records of an LDU, starting with the given record type, with the voice frames
+// in place.
+func buildRecords(ldu *LDU, first byte) [][]byte {
+	records := make([][]byte, 9)
+	for i, layout := range voiceRecords {
+		record := make([]byte, layout.length)
+		record[0] = first + byte(i)
+		imbe := ldu.Voice[i]
+		if len(imbe) != IMBELength {
+			imbe = SilenceIMBE
+		}
+		copy(record[layout.imbe:], imbe)
+		records[i] = record
+	}
+	return records
+}
+
+// ldu1Records builds the records of an LDU1, carrying the link control and its Reed-Solomon parity.
+func ldu1Records(ldu *LDU, lc *LC) [][]byte {
+	records := buildRecords(ldu, netLDU1)
+	data := lc.bytes()
+	copy(records[2][1:4], data[0:3])
+	copy(records[3][1:4], data[3:6])
+	copy(records[4][1:4], data[6:9])
+	parity := rsParityBytes(data, lcParity)
+	copy(records[5][1:4], parity[0:3])
+	copy(records[6][1:4], parity[3:6])
+	copy(records[7][1:4], parity[6:9])
+	return records
+}
+
+// ldu2Records builds the records of an LDU2, carrying the encryption sync and its Reed-Solomon parity.
+func ldu2Records(ldu *LDU) [][]byte {
+	records := buildRecords(ldu, netLDU2)
+	es := ldu.Encryption
+	if es == nil {
+		es = &Encryption{ALGID: ALGIDClear}
+	}
+	data := es.bytes()
+	copy(records[2][1:4], data[0:3])
+	copy(records[3][1:4], data[3:6])
+	copy(records[4][1:4], data[6:9])
+	copy(records[5][1:4], data[9:12])
+	parity := rsParityBytes(data, esParity)
+	copy(records[6][1:4], parity[0:3])
+	copy(records[7][1:4], parity[3:6])
+	return records
+}
+
+// rsParityBytes returns the Reed-Solomon parity of data bytes, packed into bytes.
+func rsParityBytes(data []byte, parity int) []byte {
+	words := hexWords(toBits(data, len(data)*8))
+	return fromBits(hexBits(rsEncode(words, parity)))
+}
+
+// identity builds a poll or unlink packet carrying the callsign.
+func (n *Network) identity(packetType byte) []byte {
+	return append([]byte{packetType}, n.callsign...)
+}
+
+// IsLinked reports whether the gateway has answered recently.
+func (n *Network) IsLinked() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.linked
+}
+
+// write sends a packet to the gateway. The caller must hold the lock.
+func (n *Network) write(buffer []byte) error {
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.gateway)
+	return err
+}
+
+// run receives packets, sends polls and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	buffer := make([]byte, 1500)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+		now := time.Now()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("P25: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.linked = false
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.gateway.IP) && addr.Port == n.gateway.Port {
+			n.receive(buffer[:length], now)
+		} else {
+			log.Printf("P25: Packet received from unknown address %s", addr)
+		}
+
+		n.clock(now)
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(netReconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("P25: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("P25: Network reconnected to gateway %s", n.gateway)
+	return true
+}
+
+// clock runs the poll timer, the link watchdog and the incoming stream watchdog.
+func (n *Network) clock(now time.Time) {
+	n.mu.Lock()
+	if now.Sub(n.lastPoll) >= netPollInterval {
+		n.lastPoll = now
+		if err := n.write(n.identity(netPoll)); err != nil {
+			log.Printf("P25: Unable to poll gateway: %v", err)
+		}
+	}
+	if n.linked && now.Sub(n.lastRx) > netLinkTimeout {
+		log.Printf("P25: Link to gateway %s lost", n.gateway)
+		n.linked = false
+	}
+	timedOut := n.inActive && now.Sub(n.lastIn) > netStreamTimeout
+	if timedOut {
+		log.Printf("P25: Network stream timed out")
+		n.inActive = false
+		n.inLDU = nil
+	}
+	n.mu.Unlock()
+
+	if timedOut && n.End != nil {
+		n.End()
+	}
+}
+
+// receive dispatches a packet from the gateway.
+func (n *Network) receive(buffer []byte, now time.Time) {
+	n.mu.Lock()
+	n.lastRx = now
+	if !n.linked {
+		log.Printf("P25: Link to gateway %s established", n.gateway)
+		n.linked = true
+	}
+
+	if len(buffer) < 1 {
+		n.mu.Unlock()
+		return
+	}
+
+	switch packetType := buffer[0]; {
+	case packetType == netPoll:
+		n.mu.Unlock()
+
+	case packetType == netUnlink:
+		log.Printf("P25: Unlinked by gateway %s", n.gateway)
+		n.linked = false
+		n.mu.Unlock()
+
+	case packetType == netTDU:
+		active := n.inActive
+		n.inActive = false
+		n.inLDU = nil
+		n.mu.Unlock()
+
+		if active && n.End != nil {
+			n.End()
+		}
+
+	case packetType >= netLDU1 && packetType < netLDU1+9:
+		ldu := n.receiveRecord(buffer, DUIDLDU1, int(packetType-netLDU1), now)
+		n.mu.Unlock()
+
+		if ldu != nil && n.LDU != nil {
+			n.LDU(ldu)
+		}
+
+	case packetType >= netLDU2 && packetType < netLDU2+9:
+		ldu := n.receiveRecord(buffer, DUIDLDU2, int(packetType-netLDU2), now)
+		n.mu.Unlock()
+
+		if ldu != nil && n.LDU != nil {
+			n.LDU(ldu)
+		}
+
+	default:
+		n.mu.Unlock()
+		log.Printf("P25: Unknown network packet type 0x%02X", packetType)
+	}
+}
+
+// receiveRecord adds a voice record to the LDU being reassembled, returning the LDU when its last record
+// arrives. Voice records lost in between are replaced by silence, link control or encryption sync with
+// records lost is left nil. The caller must hold the lock.
+func (n *Network) receiveRecord(record []byte, duid uint8, index int, now time.Time) *LDU {
+	layout := voiceRecords[index]
+	if len(record) < layout.length {
+		return nil
+	}
+
+	if index == 0 || n.inLDU == nil || n.inLDU.DUID != duid {
+		n.inLDU = &LDU{DUID: duid}
+		n.inData = make([]byte, 12)
+		n.inMask = 0
+	}
+	n.inActive = true
+	n.lastIn = now
+
+	n.inMask |= 1 << uint(index)
+	n.inLDU.Voice[index] = append([]byte{}, record[layout.imbe:layout.imbe+IMBELength]...)
+	if index >= 2 && index <= 5 {
+		copy(n.inData[(index-2)*3:], record[1:4])
+	}
+	if index < 8 {
+		return nil
+	}
+
+	ldu := n.inLDU
+	n.inLDU = nil
+	for i := range ldu.Voice {
+		if ldu.Voice[i] == nil {
+			ldu.Voice[i] = SilenceIMBE
+		}
+	}
+	// Link control is in records 2 to 4, encryption sync in records 2 to 5
+	if duid == DUIDLDU1 && n.inMask&0x1C == 0x1C {
+		ldu.LC = parseLC(n.inData[:9])
+	} else if duid == DUIDLDU2 && n.inMask&0x3C == 0x3C {
+		ldu.Encryption = parseEncryption(n.inData)
+	}
+	return ldu
+}
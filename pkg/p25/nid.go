@@ -0,0 +1,71 @@
+// Package p25 provides APCO P25 Phase 1 protocol logic, including the network identifier (NID).
+package p25
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/bch"
+)
+
+// validDUIDs are the data unit IDs defined for Phase 1.
+var validDUIDs = []uint8{DUIDHDU, DUIDTDU, DUIDLDU1, DUIDTSDU, DUIDLDU2, DUIDPDU, DUIDTDULC}
+
+// NID is the network identifier following the frame sync: the network access code and the data unit ID.
+type NID struct {
+	NAC  uint16 // Network access code, 12 bits
+	DUID uint8  // Data unit ID, 4 bits
+}
+
+// duidParity returns the bit following the BCH codeword in the NID, which the standard sets by data unit:
+// 1 for the voice LDUs and 0 for the others.
+func duidParity(duid uint8) uint64 {
+	switch duid {
+	case DUIDLDU1, DUIDLDU2:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// codeword returns the 64-bit NID: the BCH codeword followed by the parity bit of the DUID.
+func (n *NID) codeword() uint64 {
+	data := n.NAC&0xFFF<<4 | uint16(n.DUID&0x0F)
+	return bch.Encode(data)<<1 | duidParity(n.DUID&0x0F)
+}
+
+// DecodeNID decodes the NID of a frame, returning it with the number of bits corrected, counting a wrong
+// parity bit as one.
+func DecodeNID(frame []byte) (*NID, int, error) {
+	if len(frame)*8 < nidStop {
+		return nil, 0, errors.New("frame too short")
+	}
+	received := bitsValue(extractBits(frame, nidStart, nidStop))
+
+	// The last bit is the DUID parity, not part of the BCH codeword
+	data, errs, err := bch.Decode(received >> 1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid NID: %w", err)
+	}
+
+	nid := &NID{NAC: data >> 4, DUID: uint8(data & 0x0F)}
+	if !slices.Contains(validDUIDs, nid.DUID) {
+		return nil, errs, fmt.Errorf("invalid DUID 0x%X", nid.DUID)
+	}
+	if received&1 != duidParity(nid.DUID) {
+		errs++
+	}
+	return nid, errs, nil
+}
+
+// Encode writes the frame sync and NID into a frame.
+func (n *NID) Encode(frame []byte) {
+	copy(frame, SyncBytes)
+	insertBits(frame, valueBits(n.codeword(), 64), nidStart)
+}
+
+// String returns a human readable form of the NID.
+func (n *NID) String() string {
+	return fmt.Sprintf("NAC 0x%03X, DUID 0x%X", n.NAC, n.DUID)
+}
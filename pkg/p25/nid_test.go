@@ -0,0 +1,30 @@
+package p25
+
+import (
+	"testing"
+)
+
+func TestNIDParity(t *testing.T) {
+	parity := map[uint8]uint64{
+		DUIDHDU: 0, DUIDTDU: 0, DUIDLDU1: 1, DUIDTSDU: 0, DUIDLDU2: 1, DUIDTDULC: 0,
+	}
+	for duid, want := range parity {
+		n := &NID{NAC: 0x293, DUID: duid}
+		if got := n.codeword() & 1; got != want {
+			t.Errorf("DUID 0x%X parity bit = %d, want %d", duid, got, want)
+		}
+
+		frame := make([]byte, LDUFrameLength)
+		n.Encode(frame)
+		decoded, errs, err := DecodeNID(frame)
+		if err != nil || errs != 0 || *decoded != *n {
+			t.Errorf("DUID 0x%X: DecodeNID = %v, %d, %v", duid, decoded, errs, err)
+		}
+
+		// A wrong parity bit is counted as a corrected error
+		insertBits(frame, valueBits(n.codeword()^1, 64), nidStart)
+		if decoded, errs, err := DecodeNID(frame); err != nil || errs != 1 || *decoded != *n {
+			t.Errorf("DUID 0x%X with wrong parity: DecodeNID = %v, %d, %v", duid, decoded, errs, err)
+		}
+	}
+}
@@ -1,5 +1,116 @@
 package p25
 
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
+)
+
+// control handles the P25 RF and network transmissions.
+var control *Control
+
+// network links the controller to a P25Gateway, nil when disabled.
+var network *Network
+
+// HandleP25Packet passes a modem frame (tag byte plus P25 frame) to the P25 controller.
 func HandleP25Packet(packet []byte) {
-	// TODO: Add P25 packet handling logic
+	if control == nil {
+		log.Printf("P25: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// modemCommands maps the tags of controller frames to the modem commands transmitting them. The modem
+// treats headers and LDUs alike, so every frame is sent as an LDU.
+var modemCommands = map[byte]byte{
+	TagData: modem.CmdP25LDU,
+}
+
+// writeModem transmits a controller frame (tag plus payload) on the modem.
+func writeModem(data []byte) {
+	command, ok := modemCommands[data[0]]
+	if !ok {
+		log.Printf("P25: No modem command for tag 0x%02X", data[0])
+		return
+	}
+	if err := modem.Write(command, data[1:]); err != nil {
+		log.Printf("P25: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so RF calls time out without a P25Gateway link.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
+}
+
+// Init initializes the P25 protocol handler with the given configuration.
+func Init(config config.P25Config) {
+	if config.NAC < 0 || config.NAC > 0xFFF {
+		log.Printf("P25: Invalid NAC 0x%X, using 0x293", config.NAC)
+		config.NAC = 0x293
+	}
+	control = NewControl(uint16(config.NAC))
+	control.Events = func(event string, _ *LC) { cwid.Event(event) }
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if config.Enable && config.NetworkEnable {
+		initNetwork(config)
+	}
+	startClock(control)
+	fmt.Printf("P25 protocol handler initialized with Port: %s, NAC: 0x%03X\n", config.Port, config.NAC)
+}
+
+// initNetwork opens the gateway link and connects it to the controller.
+func initNetwork(cfg config.P25Config) {
+	n, err := NewNetwork(cfg.Callsign, cfg.GatewayAddress, cfg.GatewayPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("P25: %v", err)
+		return
+	}
+
+	c := control
+	n.LDU = func(ldu *LDU) { c.WriteNetworkLDU(ldu) }
+	n.End = c.WriteNetworkEnd
+
+	if err := n.Open(); err != nil {
+		log.Printf("P25: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
 }
@@ -0,0 +1,213 @@
+// Package pocsag provides POCSAG paging protocol logic, including the time slot scheduled transmit queue.
+package pocsag
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BitRate is the transmit rate in bits per second.
+const BitRate = 1200
+
+// SlotDuration is the length of a DAPNET time slot. The slots repeat every SlotCount slots, counted from the
+// Unix epoch.
+const SlotDuration = 6400 * time.Millisecond
+
+// codewordDuration is the air time of one codeword.
+const codewordDuration = time.Second * 32 / BitRate
+
+// slotCodewords is the number of codewords that fit in one time slot.
+const slotCodewords = int(SlotDuration / codewordDuration)
+
+// Control queues pages and passes them to the modem as transmissions within the assigned time slots,
+// highest priority first, while the modem is not transmitting for another mode.
+type Control struct {
+	Output func(data []byte) // Receives the preamble and each batch for transmission, may be nil
+	Busy   func() bool       // Reports whether the modem is transmitting for another mode, may be nil
+	Space  func() int        // Reports the free modem buffer space in batches, may be nil when unlimited
+	Store  *Store            // Persists undelivered pages, may be nil
+
+	mu        sync.Mutex
+	queue     []*Message
+	slots     [SlotCount]bool
+	busyUntil time.Time
+	pending   [][]byte
+}
+
+// NewControl creates a new Control with an empty queue, allowed to transmit in every time slot until slots
+// are assigned.
+func NewControl() *Control {
+	c := &Control{}
+	for i := range c.slots {
+		c.slots[i] = true
+	}
+	return c
+}
+
+// CurrentSlot returns the DAPNET time slot at the given time.
+func CurrentSlot(now time.Time) int {
+	return int(now.UnixNano()/int64(SlotDuration)) % SlotCount
+}
+
+// SetSlots sets the time slots transmissions are allowed in.
+func (c *Control) SetSlots(slots [SlotCount]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slots = slots
+}
+
+// Queue adds a page to the transmit queue behind the pages of the same or higher priority, storing it.
+func (c *Control) Queue(m *Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m.Created.IsZero() {
+		m.Created = time.Now()
+	}
+	if c.Store != nil && m.ID == 0 {
+		if err := c.Store.Add(m); err != nil {
+			log.Printf("POCSAG: Unable to store page: %v", err)
+		}
+	}
+
+	log.Printf("POCSAG: Queued %s", m)
+	c.insert(m)
+}
+
+// insert adds a page to the queue in priority order. The caller must hold the lock.
+func (c *Control) insert(m *Message) {
+	i := sort.Search(len(c.queue), func(i int) bool {
+		return c.queue[i].Priority < m.Priority
+	})
+	c.queue = append(c.queue, nil)
+	copy(c.queue[i+1:], c.queue[i:])
+	c.queue[i] = m
+}
+
+// Restore queues the pages left in the store by a previous run.
+func (c *Control) Restore(now time.Time) {
+	if c.Store == nil {
+		return
+	}
+	messages, err := c.Store.Load(now)
+	if err != nil {
+		log.Printf("POCSAG: Unable to load stored pages: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range messages {
+		c.insert(m)
+	}
+	if len(messages) > 0 {
+		log.Printf("POCSAG: Restored %d undelivered pages", len(messages))
+	}
+}
+
+// Pending returns the number of queued pages.
+func (c *Control) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// window returns the number of codewords that can be sent from now until the end of the current run of
+// allowed time slots, 0 when the current slot is not allowed. The caller must hold the lock.
+func (c *Control) window(now time.Time) int {
+	slot := CurrentSlot(now)
+	if !c.slots[slot] {
+		return 0
+	}
+
+	start := now.UnixNano() / int64(SlotDuration) * int64(SlotDuration)
+	end := time.Unix(0, start).Add(SlotDuration)
+	for i := 1; i < SlotCount && c.slots[(slot+i)%SlotCount]; i++ {
+		end = end.Add(SlotDuration)
+	}
+	return int(end.Sub(now) / codewordDuration)
+}
+
+// Clock passes the current transmission to the modem as its buffer frees up, and starts a transmission of
+// the queued pages that fit in the remaining allowed time once the previous transmission has finished and
+// the modem is free. Without an Output, pages stay queued and stored.
+func (c *Control) Clock(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Output == nil {
+		return
+	}
+	if len(c.pending) > 0 {
+		c.feed()
+		return
+	}
+	if len(c.queue) == 0 || now.Before(c.busyUntil) {
+		return
+	}
+	if c.Busy != nil && c.Busy() {
+		return
+	}
+	available := c.window(now)
+	if available == 0 {
+		return
+	}
+
+	// Drop pages that could never be sent
+	for len(c.queue) > 0 && len(Encode(c.queue[:1])) > slotCodewords {
+		log.Printf("POCSAG: Dropping %s, too long for a time slot", c.queue[0])
+		c.delete(c.queue[:1])
+		c.queue = c.queue[1:]
+	}
+
+	// Take the pages that fit, in priority order
+	var messages []*Message
+	var out []uint32
+	for len(c.queue) > 0 {
+		next := Encode(append(messages, c.queue[0]))
+		if len(next) > available {
+			break
+		}
+		messages, out = append(messages, c.queue[0]), next
+		c.queue = c.queue[1:]
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	c.busyUntil = now.Add(time.Duration(len(out)) * codewordDuration)
+	log.Printf("POCSAG: Transmitting %d pages in slot %X, %d batches", len(messages), CurrentSlot(now),
+		(len(out)-PreambleCodewords)/BatchCodewords)
+
+	c.pending = append(c.pending, Bytes(out[:PreambleCodewords]))
+	for i := PreambleCodewords; i < len(out); i += BatchCodewords {
+		c.pending = append(c.pending, Bytes(out[i:i+BatchCodewords]))
+	}
+	c.delete(messages)
+	c.feed()
+}
+
+// feed passes as much of the current transmission to the Output as the modem buffer has space for. The
+// caller must hold the lock.
+func (c *Control) feed() {
+	n := len(c.pending)
+	if c.Space != nil {
+		n = min(n, c.Space())
+	}
+	for _, data := range c.pending[:n] {
+		c.Output(data)
+	}
+	c.pending = c.pending[n:]
+}
+
+// delete removes sent or dropped pages from the store. The caller must hold the lock.
+func (c *Control) delete(messages []*Message) {
+	if c.Store == nil {
+		return
+	}
+	if err := c.Store.Delete(messages); err != nil {
+		log.Printf("POCSAG: Unable to remove delivered pages from store: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package pocsag
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlFeedsModemBuffer(t *testing.T) {
+	c := NewControl()
+	var written [][]byte
+	space := 2
+	c.Output = func(data []byte) {
+		written = append(written, data)
+		space--
+	}
+	c.Space = func() int { return space }
+
+	// A long page spans several batches
+	page := &Message{Address: 0x1234, Type: TypeAlphanumeric, Text: strings.Repeat("Paging ", 20)}
+	batches := (len(Encode([]*Message{page})) - PreambleCodewords) / BatchCodewords
+	c.Queue(page)
+	if batches < 3 {
+		t.Fatalf("test page encodes to %d batches, want at least 3", batches)
+	}
+
+	now := time.Now()
+	c.Clock(now)
+	if len(written) != 2 {
+		t.Fatalf("wrote %d frames with space for 2", len(written))
+	}
+	if len(written[0]) != PreambleCodewords*CodewordLength || len(written[1]) != BatchCodewords*CodewordLength {
+		t.Errorf("wrote frames of %d and %d bytes, want the preamble and a batch", len(written[0]), len(written[1]))
+	}
+	if c.Pending() != 0 {
+		t.Errorf("%d pages still queued once transmission started", c.Pending())
+	}
+
+	// Nothing more is written until the modem reports free space
+	c.Clock(now.Add(clockInterval))
+	if len(written) != 2 {
+		t.Fatalf("wrote %d frames without buffer space", len(written))
+	}
+	for space = 1; len(written) < 1+batches; space = 1 {
+		before := len(written)
+		now = now.Add(clockInterval)
+		c.Clock(now)
+		if len(written) != before+1 {
+			t.Fatalf("wrote %d frames with space for 1", len(written)-before)
+		}
+	}
+	c.Clock(now.Add(clockInterval))
+	if len(written) != 1+batches {
+		t.Errorf("wrote %d frames for a transmission of %d", len(written), 1+batches)
+	}
+}
+
+func TestControlWaitsForModem(t *testing.T) {
+	c := NewControl()
+	written := 0
+	transmitting := true
+	c.Output = func([]byte) { written++ }
+	c.Busy = func() bool { return transmitting }
+
+	c.Queue(&Message{Address: 0x1234, Type: TypeNumeric, Text: "123"})
+	now := time.Now()
+	c.Clock(now)
+	if written != 0 {
+		t.Fatal("transmitted while the modem was busy")
+	}
+
+	transmitting = false
+	c.Clock(now.Add(clockInterval))
+	if written != 2 {
+		t.Errorf("wrote %d frames, want the preamble and one batch", written)
+	}
+}
@@ -0,0 +1,370 @@
+// Package pocsag provides POCSAG paging protocol logic, including the DAPNET transmitter client.
+package pocsag
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DAPNET message types carried by "#" lines.
+const (
+	dapnetNumeric      = 5
+	dapnetAlphanumeric = 6
+)
+
+// broadcastAddresses are the DAPNET addresses of time and rubric broadcasts, sent at low priority.
+var broadcastAddresses = map[uint32]bool{
+	224:  true, // Skyper time
+	2504: true, // Alphapoc time
+	4512: true, // Skyper rubric names
+	4520: true, // Skyper rubric content
+}
+
+// SlotCount is the number of time slots in the DAPNET transmit cycle.
+const SlotCount = 16
+
+// Network timing.
+const (
+	netConnectTimeout   = 10 * time.Second       // Timeout connecting to the core
+	netLinkTimeout      = 180 * time.Second      // Connection treated as dead without any lines for this long
+	netReconnectDelay   = 10 * time.Second       // Delay before reconnecting after a failure
+	netReadPollInterval = 100 * time.Millisecond // Read deadline used to run the timers
+)
+
+// DAPNET is a TCP client registering as a transmitter with a DAPNET core, receiving pages and time slot
+// assignments.
+type DAPNET struct {
+	Message func(m *Message)            // Receives pages to transmit, may be nil
+	Slots   func(slots [SlotCount]bool) // Receives time slot assignments, may be nil
+
+	callsign string
+	authKey  string
+	address  string
+	dial     func() (net.Conn, error)
+
+	mu       sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	partial  string
+	stop     chan struct{}
+	loggedIn bool
+	lastRx   time.Time
+}
+
+// NewDAPNET creates a new DAPNET client logging in with the given transmitter callsign and authentication
+// key to the core at the given address and port.
+func NewDAPNET(callsign, authKey, address string, port int) (*DAPNET, error) {
+	if callsign == "" || authKey == "" {
+		return nil, errors.New("DAPNET callsign and authentication key are required")
+	}
+
+	d := &DAPNET{
+		callsign: strings.ToLower(callsign),
+		authKey:  authKey,
+		address:  net.JoinHostPort(address, strconv.Itoa(port)),
+	}
+	d.dial = func() (net.Conn, error) {
+		return net.DialTimeout("tcp", d.address, netConnectTimeout)
+	}
+	return d, nil
+}
+
+// Open starts the connection loop. The core is connected in the background and reconnected after failures.
+func (d *DAPNET) Open() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != nil {
+		return errors.New("DAPNET already open")
+	}
+	d.stop = make(chan struct{})
+	go d.run(d.stop)
+	return nil
+}
+
+// Close stops the connection loop and closes the connection.
+func (d *DAPNET) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	d.stop = nil
+	d.disconnect()
+	log.Printf("POCSAG: DAPNET closed")
+}
+
+// IsLoggedIn reports whether the core has accepted the login.
+func (d *DAPNET) IsLoggedIn() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.loggedIn
+}
+
+// connect dials the core and sends the login. The caller must hold the lock.
+func (d *DAPNET) connect() error {
+	conn, err := d.dial()
+	if err != nil {
+		return fmt.Errorf("failed to connect to DAPNET core %s: %w", d.address, err)
+	}
+	d.conn = conn
+	d.reader = bufio.NewReader(conn)
+	d.partial = ""
+	d.lastRx = time.Now()
+
+	if err := d.write(fmt.Sprintf("[MMDVM v1.0 %s %s]", d.callsign, d.authKey)); err != nil {
+		d.disconnect()
+		return fmt.Errorf("failed to log in to DAPNET core: %w", err)
+	}
+	log.Printf("POCSAG: Connected to DAPNET core %s as %s", d.address, d.callsign)
+	return nil
+}
+
+// disconnect closes the connection. The caller must hold the lock.
+func (d *DAPNET) disconnect() {
+	if d.conn != nil {
+		d.conn.Close()
+		d.conn = nil
+		d.reader = nil
+	}
+	d.loggedIn = false
+}
+
+// write sends a line to the core. The caller must hold the lock.
+func (d *DAPNET) write(line string) error {
+	if d.conn == nil {
+		return errors.New("DAPNET not connected")
+	}
+	_, err := d.conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+// run connects, receives lines and reconnects after errors until stopped.
+func (d *DAPNET) run(stop chan struct{}) {
+	first := true
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		d.mu.Lock()
+		conn, reader := d.conn, d.reader
+		d.mu.Unlock()
+
+		if conn == nil {
+			if !d.reconnect(stop, first) {
+				return
+			}
+			first = false
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		line, err := reader.ReadString('\n')
+		now := time.Now()
+
+		d.mu.Lock()
+		d.partial += line
+		line = d.partial
+		if err == nil {
+			d.partial = ""
+		}
+		d.mu.Unlock()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("POCSAG: DAPNET connection lost, reconnecting: %v", err)
+				d.mu.Lock()
+				d.disconnect()
+				d.mu.Unlock()
+				continue
+			}
+		} else {
+			d.receive(strings.TrimRight(line, "\r\n"), now)
+		}
+
+		d.clock(now)
+	}
+}
+
+// reconnect waits, unless this is the first attempt, and connects to the core, returning false if the
+// client was closed meanwhile.
+func (d *DAPNET) reconnect(stop chan struct{}, first bool) bool {
+	if !first {
+		select {
+		case <-stop:
+			return false
+		case <-time.After(netReconnectDelay):
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != stop {
+		return false
+	}
+	if err := d.connect(); err != nil {
+		log.Printf("POCSAG: %v", err)
+	}
+	return true
+}
+
+// clock runs the connection watchdog.
+func (d *DAPNET) clock(now time.Time) {
+	d.mu.Lock()
+	if d.conn != nil && now.Sub(d.lastRx) > netLinkTimeout {
+		log.Printf("POCSAG: DAPNET core %s stopped responding, reconnecting", d.address)
+		d.disconnect()
+	}
+	d.mu.Unlock()
+}
+
+// receive handles a line from the core.
+func (d *DAPNET) receive(line string, now time.Time) {
+	d.mu.Lock()
+	d.lastRx = now
+	if line == "" {
+		d.mu.Unlock()
+		return
+	}
+
+	switch line[0] {
+	case '+':
+		d.login()
+		d.mu.Unlock()
+
+	case '-':
+		log.Printf("POCSAG: DAPNET core rejected the last command: %s", line)
+		d.mu.Unlock()
+
+	case '2':
+		// Time synchronisation, echoed back with our offset
+		d.login()
+		d.reply(line + ":0000")
+		d.reply("+")
+		d.mu.Unlock()
+
+	case '3':
+		// Keepalive
+		d.reply("+")
+		d.mu.Unlock()
+
+	case '4':
+		slots := parseSlots(line)
+		d.reply("+")
+		d.mu.Unlock()
+
+		log.Printf("POCSAG: DAPNET time slots %s", strings.TrimPrefix(line, "4:"))
+		if d.Slots != nil {
+			d.Slots(slots)
+		}
+
+	case '#':
+		id, m, err := parseMessage(line)
+		if err != nil {
+			d.mu.Unlock()
+			log.Printf("POCSAG: Invalid DAPNET message %q: %v", line, err)
+			return
+		}
+		d.reply(fmt.Sprintf("#%02X +", (id+1)&0xFF))
+		d.mu.Unlock()
+
+		if m != nil && d.Message != nil {
+			d.Message(m)
+		}
+
+	default:
+		d.mu.Unlock()
+		log.Printf("POCSAG: Unknown DAPNET line %q", line)
+	}
+}
+
+// login records that the core accepted the login. The caller must hold the lock.
+func (d *DAPNET) login() {
+	if !d.loggedIn {
+		log.Printf("POCSAG: Logged in to DAPNET core %s", d.address)
+		d.loggedIn = true
+	}
+}
+
+// reply sends a line to the core, logging failures. The caller must hold the lock.
+func (d *DAPNET) reply(line string) {
+	if err := d.write(line); err != nil {
+		log.Printf("POCSAG: Unable to reply to DAPNET core: %v", err)
+	}
+}
+
+// parseSlots parses a "4:" line listing the allowed time slots as hex digits.
+func parseSlots(line string) [SlotCount]bool {
+	var slots [SlotCount]bool
+	for _, c := range strings.TrimPrefix(line, "4:") {
+		if slot, err := strconv.ParseUint(string(c), 16, 8); err == nil {
+			slots[slot] = true
+		}
+	}
+	return slots
+}
+
+// parseMessage parses a "#" line, "#ID TYPE:SPEED:ADDRESS:FUNCTION:TEXT" with a hex sequence number and
+// address, returning the sequence number and the page. Pages of unsupported types are acknowledged but
+// returned as nil.
+func parseMessage(line string) (int, *Message, error) {
+	header, body, ok := strings.Cut(line[1:], " ")
+	if !ok {
+		return 0, nil, errors.New("missing message body")
+	}
+	id, err := strconv.ParseUint(header, 16, 8)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid sequence number: %w", err)
+	}
+
+	fields := strings.SplitN(body, ":", 5)
+	if len(fields) != 5 {
+		return 0, nil, errors.New("missing message fields")
+	}
+	msgType, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid message type: %w", err)
+	}
+	address, err := strconv.ParseUint(fields[2], 16, 32)
+	if err != nil || address > MaxAddress {
+		return 0, nil, fmt.Errorf("invalid address %q", fields[2])
+	}
+	function, err := strconv.ParseUint(fields[3], 10, 8)
+	if err != nil || function > 3 {
+		return 0, nil, fmt.Errorf("invalid function %q", fields[3])
+	}
+
+	m := &Message{Address: uint32(address), Function: uint8(function), Text: fields[4], Priority: PriorityNormal}
+	if broadcastAddresses[m.Address] {
+		m.Priority = PriorityLow
+	}
+	switch msgType {
+	case dapnetNumeric:
+		m.Type = TypeNumeric
+	case dapnetAlphanumeric:
+		m.Type = TypeAlphanumeric
+	default:
+		log.Printf("POCSAG: Ignoring DAPNET message type %d", msgType)
+		return int(id), nil, nil
+	}
+	return int(id), m, nil
+}
@@ -0,0 +1,186 @@
+package pocsag
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dapnetCore is a stand-in DAPNET core, connected to its transmitter through a pipe.
+type dapnetCore struct {
+	t      *testing.T
+	conns  chan net.Conn
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newDAPNETCore creates a core waiting for its transmitter.
+func newDAPNETCore(t *testing.T) *dapnetCore {
+	c := &dapnetCore{t: t, conns: make(chan net.Conn, 1)}
+	t.Cleanup(func() {
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	})
+	return c
+}
+
+// client creates a transmitter of the core, without opening it.
+func (c *dapnetCore) client(callsign, authKey string) *DAPNET {
+	c.t.Helper()
+	d, err := NewDAPNET(callsign, authKey, "dapnet.example", 43434)
+	if err != nil {
+		c.t.Fatalf("NewDAPNET: %v", err)
+	}
+	d.dial = func() (net.Conn, error) {
+		core, transmitter := net.Pipe()
+		c.conns <- core
+		return transmitter, nil
+	}
+	return d
+}
+
+// accept waits for the transmitter to connect.
+func (c *dapnetCore) accept() {
+	c.t.Helper()
+	select {
+	case c.conn = <-c.conns:
+		c.reader = bufio.NewReader(c.conn)
+	case <-time.After(2 * time.Second):
+		c.t.Fatal("transmitter did not connect")
+	}
+}
+
+// send sends a line to the transmitter.
+func (c *dapnetCore) send(line string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(line + "\r\n")); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// expect reads the next line from the transmitter and checks it.
+func (c *dapnetCore) expect(want string) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	if got := strings.TrimRight(line, "\r\n"); got != want {
+		c.t.Fatalf("transmitter sent %q, want %q", got, want)
+	}
+}
+
+func TestDAPNETSession(t *testing.T) {
+	c := newDAPNETCore(t)
+	d := c.client("N0CALL", "secret")
+
+	messages := make(chan *Message, 4)
+	slots := make(chan [SlotCount]bool, 1)
+	d.Message = func(m *Message) { messages <- m }
+	d.Slots = func(s [SlotCount]bool) { slots <- s }
+	if err := d.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	c.accept()
+	c.expect("[MMDVM v1.0 n0call secret]")
+
+	c.send("2:1A2B")
+	c.expect("2:1A2B:0000")
+	c.expect("+")
+	if !d.IsLoggedIn() {
+		t.Error("not logged in after the time synchronisation")
+	}
+
+	c.send("3:+")
+	c.expect("+")
+
+	c.send("4:0369")
+	c.expect("+")
+	select {
+	case s := <-slots:
+		for slot := range s {
+			want := slot == 0 || slot == 3 || slot == 6 || slot == 9
+			if s[slot] != want {
+				t.Errorf("slot %d assigned = %v, want %v", slot, s[slot], want)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("time slots not delivered")
+	}
+
+	c.send("#1F 6:1:9C4:3:Hello world")
+	c.expect("#20 +")
+	select {
+	case m := <-messages:
+		if m.Type != TypeAlphanumeric || m.Address != 0x9C4 || m.Function != 3 || m.Text != "Hello world" {
+			t.Errorf("page = %+v", m)
+		}
+		if m.Priority != PriorityNormal {
+			t.Errorf("page priority = %v, want normal", m.Priority)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("page not delivered")
+	}
+
+	// Sequence numbers wrap, and time broadcasts are sent at low priority
+	c.send("#FF 5:1:E0:0:123")
+	c.expect("#00 +")
+	select {
+	case m := <-messages:
+		if m.Type != TypeNumeric || m.Address != 224 || m.Text != "123" {
+			t.Errorf("page = %+v", m)
+		}
+		if m.Priority != PriorityLow {
+			t.Errorf("broadcast priority = %v, want low", m.Priority)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("broadcast not delivered")
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		line    string
+		id      int
+		address uint32
+		text    string
+		valid   bool
+	}{
+		{"#00 6:1:1234:3:Text: with colons", 0x00, 0x1234, "Text: with colons", true},
+		{"#0A 6:1:1FFFFF:0:", 0x0A, 0x1FFFFF, "", true},
+		{"#0B 6:1:200000:0:Too high", 0, 0, "", false},
+		{"#0C 6:1:10:4:Bad function", 0, 0, "", false},
+		{"#XY 6:1:10:0:Bad id", 0, 0, "", false},
+		{"#0D 6:1:10", 0, 0, "", false},
+		{"#0E", 0, 0, "", false},
+	}
+
+	for _, test := range tests {
+		id, m, err := parseMessage(test.line)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("parseMessage(%q) succeeded", test.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMessage(%q): %v", test.line, err)
+			continue
+		}
+		if id != test.id || m.Address != test.address || m.Text != test.text {
+			t.Errorf("parseMessage(%q) = %d, %+v", test.line, id, m)
+		}
+	}
+
+	// Unsupported types are acknowledged without a page
+	id, m, err := parseMessage("#10 7:1:10:0:Other")
+	if err != nil || id != 0x10 || m != nil {
+		t.Errorf("parseMessage of an unsupported type = %d, %+v, %v", id, m, err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package pocsag provides POCSAG paging protocol logic, including codeword encoding and batch layout.
+package pocsag
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// Fixed codewords.
+const (
+	SyncCodeword     = 0x7CD215D8 // Frame synchronisation codeword starting every batch
+	IdleCodeword     = 0x7A89C197 // Fills frames without address or message codewords
+	PreambleCodeword = 0xAAAAAAAA // Alternating bits sent before the first batch
+)
+
+// Transmission layout.
+const (
+	PreambleCodewords = 18 // 576 bits of preamble
+	FramesPerBatch    = 8  // Frames per batch, each two codewords
+	BatchCodewords    = 1 + 2*FramesPerBatch
+	CodewordLength    = 4 // Bytes per codeword
+)
+
+// Message types.
+const (
+	TypeTone         = 0 // Address only
+	TypeNumeric      = 1 // BCD digits
+	TypeAlphanumeric = 2 // 7-bit ASCII
+)
+
+// Function bits customarily used with each message type.
+const (
+	FunctionNumeric      = 0
+	FunctionAlphanumeric = 3
+)
+
+// MaxAddress is the largest 21-bit pager address (RIC).
+const MaxAddress = 0x1FFFFF
+
+// bchGenerator is the generator polynomial of the BCH(31,21) code, x^10+x^9+x^8+x^6+x^5+x^3+1.
+const bchGenerator = 0x769
+
+// messageBits is the number of data bits in a message codeword.
+const messageBits = 20
+
+// numericCharacters maps each BCD value to its character.
+const numericCharacters = "0123456789*U -)("
+
+// Message priorities, higher priorities being sent first.
+const (
+	PriorityLow    = 0 // Broadcasts such as time and rubric pages
+	PriorityNormal = 1 // Pages to individual pagers
+	PriorityHigh   = 2 // Urgent pages
+)
+
+// Message is a page addressed to a pager.
+type Message struct {
+	Address  uint32 // Pager address (RIC), 21 bits
+	Function uint8  // Function bits, 0 to 3
+	Type     int    // TypeTone, TypeNumeric or TypeAlphanumeric
+	Text     string // Message text, empty for tone pages
+
+	Priority int       // PriorityLow, PriorityNormal or PriorityHigh
+	Created  time.Time // When the page was queued
+	ID       uint      // Store ID, 0 when not stored
+}
+
+// bchEncode returns the codeword of its top 21 bits with the BCH(31,21) check bits and the even parity bit.
+func bchEncode(codeword uint32) uint32 {
+	codeword &= 0xFFFFF800
+	remainder := codeword >> 1
+	for i := 30; i >= 10; i-- {
+		if remainder&(1<<uint(i)) != 0 {
+			remainder ^= bchGenerator << uint(i-10)
+		}
+	}
+	codeword |= remainder << 1
+	if bits.OnesCount32(codeword)&1 != 0 {
+		codeword |= 1
+	}
+	return codeword
+}
+
+// addressCodeword returns the address codeword of a message. The low three address bits are implied by
+// the frame it is sent in.
+func addressCodeword(address uint32, function uint8) uint32 {
+	return bchEncode((address>>3)<<13 | uint32(function&0x03)<<11)
+}
+
+// messageCodeword returns a message codeword carrying 20 data bits.
+func messageCodeword(data uint32) uint32 {
+	return bchEncode(1<<31 | (data&0xFFFFF)<<11)
+}
+
+// numericValue returns the BCD value of a character, unknown characters being sent as spaces.
+func numericValue(c rune) uint32 {
+	if i := strings.IndexRune(numericCharacters, c); i >= 0 {
+		return uint32(i)
+	}
+	return 0x0C
+}
+
+// encodeNumeric packs text as BCD digits, five per codeword, each digit sent least significant bit first.
+// The last codeword is padded with spaces.
+func encodeNumeric(text string) []uint32 {
+	var codewords []uint32
+	var data uint32
+	count := 0
+	for _, c := range text {
+		data = data<<4 | uint32(bits.Reverse8(uint8(numericValue(c)))>>4)
+		count++
+		if count == 5 {
+			codewords = append(codewords, messageCodeword(data))
+			data, count = 0, 0
+		}
+	}
+	if count > 0 {
+		for ; count < 5; count++ {
+			data = data<<4 | uint32(bits.Reverse8(0x0C)>>4)
+		}
+		codewords = append(codewords, messageCodeword(data))
+	}
+	return codewords
+}
+
+// encodeAlphanumeric packs text as 7-bit characters, each sent least significant bit first, running across
+// codeword boundaries.
+func encodeAlphanumeric(text string) []uint32 {
+	var codewords []uint32
+	var data uint32
+	count := 0
+	push := func(c byte) {
+		for i := 0; i < 7; i++ {
+			data = data<<1 | uint32(c>>uint(i)&1)
+			count++
+			if count == messageBits {
+				codewords = append(codewords, messageCodeword(data))
+				data, count = 0, 0
+			}
+		}
+	}
+
+	for _, c := range []byte(text) {
+		push(c & 0x7F)
+	}
+	// Terminate a partly filled codeword with EOT and zero bits
+	if count > 0 {
+		push(0x04)
+	}
+	if count > 0 {
+		codewords = append(codewords, messageCodeword(data<<uint(messageBits-count)))
+	}
+	return codewords
+}
+
+// Codewords returns the address codeword followed by the message codewords of a message.
+func (m *Message) Codewords() []uint32 {
+	codewords := []uint32{addressCodeword(m.Address, m.Function)}
+	switch m.Type {
+	case TypeNumeric:
+		codewords = append(codewords, encodeNumeric(m.Text)...)
+	case TypeAlphanumeric:
+		codewords = append(codewords, encodeAlphanumeric(m.Text)...)
+	}
+	return codewords
+}
+
+// Encode lays out messages in batches following a preamble. Each address codeword is placed in the frame
+// given by the low three bits of the address, starting a new batch when that frame has passed; message
+// codewords run on across frames and batches. Unused codewords are idle.
+func Encode(messages []*Message) []uint32 {
+	out := make([]uint32, 0, PreambleCodewords+BatchCodewords)
+	for i := 0; i < PreambleCodewords; i++ {
+		out = append(out, PreambleCodeword)
+	}
+
+	// Position within the current batch, batchEnd when a new batch is due
+	const batchEnd = 2 * FramesPerBatch
+	slot := batchEnd
+	startBatch := func() {
+		if slot == batchEnd {
+			out = append(out, SyncCodeword)
+			slot = 0
+		}
+	}
+	emit := func(codeword uint32) {
+		startBatch()
+		out = append(out, codeword)
+		slot++
+	}
+
+	for _, m := range messages {
+		frame := int(m.Address & 0x07)
+		if slot > 2*frame {
+			for slot < batchEnd {
+				emit(IdleCodeword)
+			}
+		}
+		startBatch()
+		for slot < 2*frame {
+			emit(IdleCodeword)
+		}
+		for _, codeword := range m.Codewords() {
+			emit(codeword)
+		}
+	}
+
+	// Complete the last batch, always sending at least one
+	if len(out) == PreambleCodewords {
+		startBatch()
+	}
+	for slot < batchEnd {
+		emit(IdleCodeword)
+	}
+	return out
+}
+
+// Bytes returns codewords as big endian bytes, as sent to the modem.
+func Bytes(codewords []uint32) []byte {
+	out := make([]byte, len(codewords)*CodewordLength)
+	for i, codeword := range codewords {
+		binary.BigEndian.PutUint32(out[i*CodewordLength:], codeword)
+	}
+	return out
+}
+
+// String returns a human readable form of the page.
+func (m *Message) String() string {
+	switch m.Type {
+	case TypeNumeric:
+		return fmt.Sprintf("numeric page to %d: %s", m.Address, m.Text)
+	case TypeAlphanumeric:
+		return fmt.Sprintf("alphanumeric page to %d: %q", m.Address, m.Text)
+	default:
+		return fmt.Sprintf("tone page to %d, function %d", m.Address, m.Function)
+	}
+}
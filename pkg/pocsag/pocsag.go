@@ -2,15 +2,119 @@ package pocsag
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control queues pages for transmission.
+var control *Control
+
+// store persists undelivered pages, nil when the database cannot be opened.
+var store *Store
+
+// DatabasePath is the SQLite configuration database undelivered pages are stored in.
+var DatabasePath = "mmdvm_ghost.db"
+
+// dapnet links the transmitter to a DAPNET core, nil when disabled.
+var dapnet *DAPNET
+
+// clockInterval is how often the controller is given the chance to transmit.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock drives the controller every clockInterval until the handler is initialized again.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.Clock(now)
+			}
+		}
+	}()
+}
+
+// HandlePOCSAGPacket handles a POCSAG frame from the modem. POCSAG is transmit only, so none are expected.
 func HandlePOCSAGPacket(packet []byte) {
-	// TODO: Add POCSAG packet handling logic
+	log.Printf("POCSAG: Unexpected modem frame of %d bytes", len(packet))
+}
+
+// writeModem transmits the preamble or a batch on the modem.
+func writeModem(data []byte) {
+	if err := modem.Write(modem.CmdPOCSAGData, data); err != nil {
+		log.Printf("POCSAG: Unable to write to the modem: %v", err)
+	}
 }
 
 // Init initializes the POCSAG protocol handler with the given configuration.
 func Init(config config.PocsagConfig) {
+	control = NewControl()
+	if modem.IsOpen() {
+		control.Output = writeModem
+		control.Busy = modem.Transmitting
+		control.Space = modem.POCSAGSpace
+	}
+	if store != nil {
+		store.Close()
+		store = nil
+	}
+	if config.Enable {
+		initStore()
+	}
+
+	if dapnet != nil {
+		dapnet.Close()
+		dapnet = nil
+	}
+	if config.Enable && config.NetworkEnable {
+		initDAPNET(config)
+	}
+	startClock(control)
 	fmt.Printf("POCSAG protocol handler initialized with Frequency: %d\n", config.Frequency)
 }
+
+// initStore opens the page store and requeues the pages left undelivered by a previous run.
+func initStore() {
+	s, err := OpenStore(DatabasePath)
+	if err != nil {
+		log.Printf("POCSAG: %v", err)
+		return
+	}
+	store = s
+	control.Store = s
+	control.Restore(time.Now())
+}
+
+// initDAPNET connects to the DAPNET core and passes its pages to the controller.
+func initDAPNET(cfg config.PocsagConfig) {
+	d, err := NewDAPNET(cfg.Callsign, cfg.AuthKey, cfg.DAPNETAddress, cfg.DAPNETPort)
+	if err != nil {
+		log.Printf("POCSAG: %v", err)
+		return
+	}
+
+	c := control
+	d.Message = c.Queue
+	d.Slots = c.SetSlots
+
+	if err := d.Open(); err != nil {
+		log.Printf("POCSAG: %v", err)
+		return
+	}
+	dapnet = d
+}
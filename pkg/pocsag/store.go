@@ -0,0 +1,112 @@
+// Package pocsag provides POCSAG paging protocol logic, including persistence of undelivered pages.
+package pocsag
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// maxStoredAge is how old a stored page may be and still be sent after a restart.
+const maxStoredAge = time.Hour
+
+// storedMessage is an undelivered page in the database.
+type storedMessage struct {
+	ID       uint      `gorm:"column:id;primaryKey"`
+	Address  uint32    `gorm:"column:address"`
+	Function uint8     `gorm:"column:function"`
+	Type     int       `gorm:"column:type"`
+	Text     string    `gorm:"column:text"`
+	Priority int       `gorm:"column:priority"`
+	Created  time.Time `gorm:"column:created"`
+}
+
+// TableName specifies the table name for storedMessage.
+func (storedMessage) TableName() string {
+	return "PocsagQueue"
+}
+
+// Store persists undelivered pages in the SQLite configuration database so they survive restarts.
+type Store struct {
+	db *gorm.DB
+}
+
+// OpenStore opens the queue table of the database at the given path, creating it if needed.
+func OpenStore(path string) (*Store, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open page store: %w", err)
+	}
+	if err := db.AutoMigrate(&storedMessage{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate page store: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Add stores a page, setting its ID.
+func (s *Store) Add(m *Message) error {
+	row := storedMessage{
+		Address:  m.Address,
+		Function: m.Function,
+		Type:     m.Type,
+		Text:     m.Text,
+		Priority: m.Priority,
+		Created:  m.Created,
+	}
+	if err := s.db.Create(&row).Error; err != nil {
+		return err
+	}
+	m.ID = row.ID
+	return nil
+}
+
+// Delete removes delivered pages.
+func (s *Store) Delete(messages []*Message) error {
+	var ids []uint
+	for _, m := range messages {
+		if m.ID != 0 {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.Delete(&storedMessage{}, ids).Error
+}
+
+// Load returns the stored pages in the order they were queued, discarding those too old to send.
+func (s *Store) Load(now time.Time) ([]*Message, error) {
+	if err := s.db.Where("created < ?", now.Add(-maxStoredAge)).Delete(&storedMessage{}).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []storedMessage
+	if err := s.db.Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, &Message{
+			ID:       row.ID,
+			Address:  row.Address,
+			Function: row.Function,
+			Type:     row.Type,
+			Text:     row.Text,
+			Priority: row.Priority,
+			Created:  row.Created,
+		})
+	}
+	return messages, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	db, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
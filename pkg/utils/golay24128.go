@@ -0,0 +1,79 @@
+package utils
+
+import "math/bits"
+
+// golay23127GenPoly is the generator polynomial of the Golay(23,12) code.
+const golay23127GenPoly = 0xC75
+
+// golay23127Errors maps each 11-bit syndrome to its error pattern. The Golay(23,12)
+// code is perfect, so every syndrome corresponds to exactly one pattern of up to 3 bits.
+var golay23127Errors = buildGolay23127Errors()
+
+// golay23127Syndrome returns the remainder of a 23-bit word divided by the generator polynomial.
+func golay23127Syndrome(word uint32) uint32 {
+	for i := 22; i >= 11; i-- {
+		if word&(1<<uint(i)) != 0 {
+			word ^= golay23127GenPoly << uint(i-11)
+		}
+	}
+	return word & 0x7FF
+}
+
+// buildGolay23127Errors builds the syndrome table for all error patterns of weight 1 to 3.
+func buildGolay23127Errors() [2048]uint32 {
+	var table [2048]uint32
+	for a := 0; a < 23; a++ {
+		table[golay23127Syndrome(1<<uint(a))] = 1 << uint(a)
+		for b := a + 1; b < 23; b++ {
+			pattern := uint32(1)<<uint(a) | uint32(1)<<uint(b)
+			table[golay23127Syndrome(pattern)] = pattern
+			for c := b + 1; c < 23; c++ {
+				pattern := uint32(1)<<uint(a) | uint32(1)<<uint(b) | uint32(1)<<uint(c)
+				table[golay23127Syndrome(pattern)] = pattern
+			}
+		}
+	}
+	return table
+}
+
+// EncodeGolay23127 encodes 12 data bits into a 23-bit Golay codeword, data in the top 12 bits.
+func EncodeGolay23127(data uint32) uint32 {
+	word := (data & 0xFFF) << 11
+	return word | golay23127Syndrome(word)
+}
+
+// DecodeGolay23127 decodes a 23-bit Golay codeword, correcting up to 3 bit errors.
+// It returns the 12 data bits and the number of bits corrected.
+func DecodeGolay23127(code uint32) (uint32, int) {
+	code &= 0x7FFFFF
+	pattern := golay23127Errors[golay23127Syndrome(code)]
+	return (code ^ pattern) >> 11, bits.OnesCount32(pattern)
+}
+
+// EncodeGolay24128 encodes 12 data bits into a 24-bit extended Golay codeword,
+// the Golay(23,12) codeword followed by an even parity bit.
+func EncodeGolay24128(data uint32) uint32 {
+	code := EncodeGolay23127(data) << 1
+	return code | uint32(bits.OnesCount32(code)&1)
+}
+
+// DecodeGolay24128 decodes a 24-bit extended Golay codeword, correcting up to 3 bit errors.
+// It returns the 12 data bits and the number of bits that differ from the corrected codeword.
+func DecodeGolay24128(code uint32) (uint32, int) {
+	code &= 0xFFFFFF
+	data, _ := DecodeGolay23127(code >> 1)
+	return data, bits.OnesCount32(code ^ EncodeGolay24128(data))
+}
+
+// DecodeGolay24128Bytes decodes a 3-byte extended Golay codeword into its 12 data bits.
+func DecodeGolay24128Bytes(data []byte) (uint32, int) {
+	return DecodeGolay24128(uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]))
+}
+
+// EncodeGolay24128Bytes encodes 12 data bits into a 3-byte extended Golay codeword.
+func EncodeGolay24128Bytes(value uint32, data []byte) {
+	code := EncodeGolay24128(value)
+	data[0] = byte(code >> 16)
+	data[1] = byte(code >> 8)
+	data[2] = byte(code)
+}
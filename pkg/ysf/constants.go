@@ -0,0 +1,57 @@
+// Package ysf provides System Fusion protocol logic.
+package ysf
+
+// MMDVM modem frame tags used by YSF.
+const (
+	TagData = 0x01 // Frame carries a 120-byte YSF frame
+	TagLost = 0x02 // Modem lost the signal
+)
+
+// Frame layout: 5 bytes of sync, 25 bytes of FICH and 90 bytes of payload.
+const (
+	FrameLength    = 120
+	SyncLength     = 5
+	FICHLength     = 25
+	PayloadLength  = 90
+	CallsignLength = 10
+)
+
+// SyncBytes starts every YSF frame.
+var SyncBytes = []byte{0xD4, 0x71, 0xC9, 0x63, 0x4D}
+
+// Frame information (FI) values from the FICH.
+const (
+	FIHeader         = 0 // First frame of a transmission, carries the callsigns
+	FICommunications = 1 // Voice or data frame
+	FITerminator     = 2 // Last frame of a transmission, carries the callsigns
+	FITest           = 3 // Test frame
+)
+
+// Data type (DT) values from the FICH.
+const (
+	DTVDMode1     = 0 // Voice and data mode 1, half rate voice with a 20 byte data channel
+	DTDataFRMode  = 1 // Full rate data mode
+	DTVDMode2     = 2 // Voice and data mode 2, half rate voice with a 10 byte data channel
+	DTVoiceFRMode = 3 // Full rate voice mode
+)
+
+// Message route (MR) values from the FICH.
+const (
+	MRDirect  = 0 // Direct, no repeater or gateway involved
+	MRNotBusy = 1 // Through a repeater that is not busy
+	MRBusy    = 2 // Through a repeater that is busy
+)
+
+// RF and network states.
+const (
+	StateListening = "LISTENING" // RF idle, waiting for a transmission
+	StateAudio     = "AUDIO"     // Transmission in progress
+	StateRejected  = "REJECTED"  // RF transmission rejected, frames are ignored until it ends
+	StateIdle      = "IDLE"      // Network idle
+)
+
+// whiteningData is XORed with the callsign data carried in the data channel.
+var whiteningData = []byte{
+	0x93, 0xD7, 0x51, 0x21, 0x9C, 0x2F, 0x6C, 0xD0, 0xEF, 0x0F,
+	0xF8, 0x3D, 0xF1, 0x73, 0x20, 0x94, 0xED, 0x1E, 0x7C, 0xD8,
+}
@@ -0,0 +1,414 @@
+// Package ysf provides System Fusion protocol logic, including the RF and network state machine.
+package ysf
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// frameTimeout is how long a transmission may go without frames before it is treated as lost.
+const frameTimeout = 1500 * time.Millisecond
+
+// frameDuration is the air time of one YSF frame.
+const frameDuration = 100 * time.Millisecond
+
+// unknownCallsign stands in for a callsign not yet received, such as on a late entry.
+const unknownCallsign = "??????????"
+
+// DG-ID routes.
+const (
+	RouteNetwork = "network" // Forward to the network, the default for DG-IDs without a rule
+	RouteLocal   = "local"   // Keep on RF and answer Wires-X room list requests locally
+	RouteBlock   = "block"   // Reject the transmission
+)
+
+// wiresXNodeID is the Wires-X ID given in local room list replies.
+const wiresXNodeID = "00000"
+
+// DGIDRule routes the transmissions on one DG-ID.
+type DGIDRule struct {
+	Route string // RouteNetwork, RouteLocal or RouteBlock
+	Name  string // Room name shown in local Wires-X room lists, empty to leave the DG-ID out
+}
+
+// NetworkWriter receives RF frames to forward to the network.
+type NetworkWriter interface {
+	WriteData(source, dest string, frame []byte, end bool) error
+}
+
+// Control manages the YSF RF and network transmissions.
+type Control struct {
+	RFState  string                                                  // Current RF state
+	NetState string                                                  // Current network state
+	Network  NetworkWriter                                           // Network the RF frames are forwarded to, may be nil
+	Output   func(data []byte)                                       // Receives modem frames (tag plus frame) for transmission, may be nil
+	Events   func(event string, callsigns Callsigns)                 // Receives transmission start and end notifications, may be nil
+	WiresX   func(request *WiresXRequest, source string, dgid uint8) // Receives Wires-X requests heard on RF, may be nil
+	GPS      func(source string, position *Position)                 // Receives positions heard on RF, may be nil
+
+	Node  string             // Callsign given as the node in local Wires-X replies, and the only one accepted with SelfOnly
+	Rules map[uint8]DGIDRule // Routing rules by DG-ID
+
+	LowDeviation  bool // Mark network frames transmitted on RF as low deviation
+	RemoteGateway bool // Mark network frames transmitted on RF as direct rather than through an internet link
+	SelfOnly      bool // Only accept RF transmissions from Node
+
+	RFDGID uint8 // DG-ID of the current RF transmission
+
+	RFCallsigns  Callsigns // Callsigns of the current RF transmission
+	NetCallsigns Callsigns // Callsigns of the current network transmission
+
+	mu         sync.Mutex
+	rfFICH     *FICH
+	rfRoute    string
+	wiresX     *WiresXDecoder
+	gps        *GPSDecoder
+	reply      [][]byte
+	rfFrames   int
+	rfBadFICH  int
+	rfFICHErrs int
+	netFrames  int
+	lastRF     time.Time
+	lastNet    time.Time
+}
+
+// NewControl creates a new Control.
+func NewControl() *Control {
+	return &Control{
+		RFState:  StateListening,
+		NetState: StateIdle,
+		Rules:    make(map[uint8]DGIDRule),
+		wiresX:   NewWiresXDecoder(),
+		gps:      NewGPSDecoder(),
+	}
+}
+
+// WriteModem handles a frame received from the modem: a tag byte followed by the 120-byte frame.
+// It returns false when the frame is rejected.
+func (c *Control) WriteModem(data []byte) bool {
+	if len(data) < 1 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data[0] {
+	case TagLost:
+		if c.RFState == StateAudio {
+			log.Printf("YSF: RF transmission lost from %s", c.RFCallsigns.Source)
+			if c.rfRoute == RouteNetwork {
+				c.writeNetwork(nil, true)
+			}
+		}
+		c.endOfRF()
+		return false
+	case TagData:
+		return c.writeRFData(data[1:])
+	default:
+		log.Printf("YSF: Unknown modem tag 0x%02X", data[0])
+		return false
+	}
+}
+
+// writeRFData decodes the FICH of an RF frame and runs the RF state machine.
+func (c *Control) writeRFData(data []byte) bool {
+	if len(data) < FrameLength {
+		log.Printf("YSF: RF frame too short: %d bytes", len(data))
+		return false
+	}
+	frame := append([]byte{}, data[:FrameLength]...)
+
+	fich, errs, err := DecodeFICH(frame)
+	if err != nil {
+		if c.RFState != StateAudio || c.rfFICH == nil {
+			return false
+		}
+		// Carry on with the previous FICH, advancing the frame number
+		c.rfBadFICH++
+		last := *c.rfFICH
+		last.FN++
+		if last.FN > last.FT {
+			last.FN = 0
+		}
+		fich = &last
+	} else {
+		c.rfFICHErrs += errs
+	}
+
+	if c.RFState == StateRejected {
+		if fich.FI == FITerminator {
+			c.endOfRF()
+		}
+		return false
+	}
+
+	if c.RFState == StateListening {
+		if fich.FI == FITerminator || fich.FI == FITest {
+			return false
+		}
+		if c.NetState != StateIdle {
+			log.Printf("YSF: RF transmission ignored, network transmission in progress")
+			c.RFState = StateRejected
+			return false
+		}
+		if !c.startRF(frame, fich) {
+			return false
+		}
+	}
+
+	// Regenerate the sync and FICH
+	copy(frame, SyncBytes)
+	fich.Encode(frame)
+	c.rfFICH = fich
+	c.lastRF = time.Now()
+	c.rfFrames++
+
+	if fich.FI == FICommunications && (c.RFCallsigns.Source == unknownCallsign || c.RFCallsigns.Dest == "") {
+		found := ExtractCallsigns(frame, fich)
+		if found.Source != "" && c.RFCallsigns.Source == unknownCallsign {
+			c.RFCallsigns.Source = found.Source
+			log.Printf("YSF: RF late entry source is %s", found.Source)
+		}
+		c.RFCallsigns.merge(found)
+	}
+
+	if request := c.wiresX.Add(frame, fich); request != nil {
+		c.handleWiresX(request)
+	}
+	if position := c.gps.Add(frame, fich); position != nil && c.RFCallsigns.Source != unknownCallsign {
+		log.Printf("YSF: RF position from %s: %.5f, %.5f", c.RFCallsigns.Source, position.Latitude, position.Longitude)
+		if c.GPS != nil {
+			c.GPS(c.RFCallsigns.Source, position)
+		}
+	}
+
+	end := fich.FI == FITerminator
+	if c.rfRoute == RouteNetwork {
+		c.writeNetwork(frame, end)
+	}
+	if end {
+		c.endOfRF()
+	}
+	return true
+}
+
+// startRF starts an RF transmission from its header, or from a communications frame on a late entry.
+// It returns false when the DG-ID is blocked.
+func (c *Control) startRF(frame []byte, fich *FICH) bool {
+	c.RFCallsigns = ExtractCallsigns(frame, fich)
+	if c.RFCallsigns.Source == "" {
+		c.RFCallsigns.Source = unknownCallsign
+	}
+
+	if c.SelfOnly && !c.isSelf(c.RFCallsigns.Source) {
+		log.Printf("YSF: RF transmission from %s rejected, only %s is allowed", c.RFCallsigns.Source, c.Node)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFDGID = fich.SQ
+	c.rfRoute = RouteNetwork
+	if rule, ok := c.Rules[c.RFDGID]; ok && rule.Route != "" {
+		c.rfRoute = rule.Route
+	}
+	if c.rfRoute == RouteBlock {
+		log.Printf("YSF: RF transmission from %s on blocked DG-ID %d rejected", c.RFCallsigns.Source, c.RFDGID)
+		c.RFState = StateRejected
+		return false
+	}
+
+	c.RFState = StateAudio
+	c.wiresX.Reset()
+	c.gps.Reset()
+	c.reply = nil
+	c.rfFrames = 0
+	c.rfBadFICH = 0
+	c.rfFICHErrs = 0
+
+	if fich.FI == FIHeader {
+		log.Printf("YSF: RF header from %s to %s, %s", c.RFCallsigns.Source, c.RFCallsigns.Dest, fich)
+	} else {
+		log.Printf("YSF: RF late entry from %s to %s, %s", c.RFCallsigns.Source, c.RFCallsigns.Dest, fich)
+	}
+	c.notify("rf_start", c.RFCallsigns)
+	return true
+}
+
+// isSelf reports whether a source callsign, ignoring any suffix, is the node's own callsign.
+func (c *Control) isSelf(source string) bool {
+	base := strings.ToUpper(source)
+	if i := strings.IndexAny(base, " -/"); i >= 0 {
+		base = base[:i]
+	}
+	return base != "" && base == strings.ToUpper(strings.TrimSpace(c.Node))
+}
+
+// handleWiresX acts on a Wires-X request heard on RF. Requests on network DG-IDs pass through to the network
+// with the rest of the transmission; room list requests on local DG-IDs are answered when the transmission ends.
+func (c *Control) handleWiresX(request *WiresXRequest) {
+	if request.Command == WiresXConnect {
+		log.Printf("YSF: Wires-X connect to %s from %s on DG-ID %d", request.Room, c.RFCallsigns.Source, c.RFDGID)
+	} else {
+		log.Printf("YSF: Wires-X %s request from %s on DG-ID %d", request.Command, c.RFCallsigns.Source, c.RFDGID)
+	}
+
+	if c.WiresX != nil {
+		c.WiresX(request, c.RFCallsigns.Source, c.RFDGID)
+	}
+
+	if c.rfRoute == RouteLocal && request.Command == WiresXList {
+		c.reply = WiresXListReply(c.Node, wiresXNodeID, c.rooms())
+	}
+}
+
+// rooms returns the named DG-IDs as a Wires-X room list.
+func (c *Control) rooms() []WiresXRoom {
+	dgids := make([]int, 0, len(c.Rules))
+	for dgid, rule := range c.Rules {
+		if rule.Name != "" && rule.Route != RouteBlock {
+			dgids = append(dgids, int(dgid))
+		}
+	}
+	sort.Ints(dgids)
+
+	rooms := make([]WiresXRoom, 0, len(dgids))
+	for _, dgid := range dgids {
+		rooms = append(rooms, WiresXRoom{
+			ID:          fmt.Sprintf("%05d", dgid),
+			Name:        c.Rules[uint8(dgid)].Name,
+			Description: fmt.Sprintf("DG-ID %d", dgid),
+		})
+	}
+	return rooms
+}
+
+// writeNetwork forwards an RF frame to the network.
+func (c *Control) writeNetwork(frame []byte, end bool) {
+	if c.Network == nil {
+		return
+	}
+	if frame == nil {
+		// Transmission lost: close the stream with the last FICH marked as a terminator
+		frame = make([]byte, FrameLength)
+		copy(frame, SyncBytes)
+		if c.rfFICH != nil {
+			fich := *c.rfFICH
+			fich.FI = FITerminator
+			fich.Encode(frame)
+		}
+	}
+	if err := c.Network.WriteData(c.RFCallsigns.Source, c.RFCallsigns.Dest, frame, end); err != nil {
+		log.Printf("YSF: Unable to forward RF frame: %v", err)
+	}
+}
+
+// endOfRF ends the current RF transmission and logs its statistics.
+func (c *Control) endOfRF() {
+	if c.RFState == StateAudio {
+		log.Printf("YSF: RF end of transmission from %s, %.1f seconds, %d bad FICH, %d FICH bits corrected",
+			c.RFCallsigns.Source, float64(c.rfFrames)*frameDuration.Seconds(), c.rfBadFICH, c.rfFICHErrs)
+		c.notify("rf_end", c.RFCallsigns)
+	}
+	for _, frame := range c.reply {
+		c.output(append([]byte{TagData}, frame...))
+	}
+	c.reply = nil
+	c.RFState = StateListening
+	c.RFCallsigns = Callsigns{}
+	c.rfFICH = nil
+}
+
+// WriteNetworkData transmits a 120-byte frame received from the network on RF. The end flag closes the transmission.
+// It returns false when RF is busy.
+func (c *Control) WriteNetworkData(source, dest string, frame []byte, end bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio {
+		return false
+	}
+	if len(frame) < FrameLength || !bytes.Equal(frame[:SyncLength], SyncBytes) {
+		log.Printf("YSF: Invalid network frame")
+		return false
+	}
+
+	if c.NetState == StateIdle {
+		c.NetCallsigns = Callsigns{Source: source, Dest: dest}
+		c.NetState = StateAudio
+		c.netFrames = 0
+		log.Printf("YSF: Network transmission from %s to %s", source, dest)
+		c.notify("net_start", c.NetCallsigns)
+	}
+
+	out := append([]byte{}, frame[:FrameLength]...)
+	if fich, _, err := DecodeFICH(out); err == nil {
+		if c.RemoteGateway {
+			fich.VoIP = false
+			fich.MR = MRDirect
+		} else {
+			fich.VoIP = true
+			fich.MR = MRBusy
+		}
+		fich.Dev = c.LowDeviation
+		fich.Encode(out)
+	}
+
+	c.lastNet = time.Now()
+	c.netFrames++
+	c.output(append([]byte{TagData}, out...))
+
+	if end {
+		c.endOfNetwork()
+	}
+	return true
+}
+
+// endOfNetwork ends the current network transmission.
+func (c *Control) endOfNetwork() {
+	if c.NetState == StateAudio {
+		log.Printf("YSF: Network end of transmission from %s, %.1f seconds",
+			c.NetCallsigns.Source, float64(c.netFrames)*frameDuration.Seconds())
+		c.notify("net_end", c.NetCallsigns)
+	}
+	c.NetState = StateIdle
+	c.NetCallsigns = Callsigns{}
+}
+
+// CheckTimeouts ends RF or network transmissions that have stopped sending frames.
+func (c *Control) CheckTimeouts(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RFState == StateAudio && now.Sub(c.lastRF) > frameTimeout {
+		log.Printf("YSF: RF transmission from %s timed out", c.RFCallsigns.Source)
+		if c.rfRoute == RouteNetwork {
+			c.writeNetwork(nil, true)
+		}
+		c.endOfRF()
+	}
+
+	if c.NetState == StateAudio && now.Sub(c.lastNet) > frameTimeout {
+		log.Printf("YSF: Network transmission from %s timed out", c.NetCallsigns.Source)
+		c.endOfNetwork()
+	}
+}
+
+// output passes a modem frame to the Output callback.
+func (c *Control) output(data []byte) {
+	if c.Output != nil {
+		c.Output(data)
+	}
+}
+
+// notify passes a transmission event to the Events callback.
+func (c *Control) notify(event string, callsigns Callsigns) {
+	if c.Events != nil {
+		c.Events(event, callsigns)
+	}
+}
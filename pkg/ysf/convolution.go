@@ -0,0 +1,97 @@
+// Package ysf provides System Fusion protocol logic, including the FEC shared by the FICH and data channels.
+package ysf
+
+import "github.com/unklstewy/mmdvm_ghost/pkg/conv"
+
+// readBit returns bit n of data, MSB first.
+func readBit(data []byte, n int) byte {
+	return data[n/8] >> uint(7-n%8) & 1
+}
+
+// writeBit sets bit n of data, MSB first, to b.
+func writeBit(data []byte, n int, b byte) {
+	mask := byte(0x80) >> uint(n%8)
+	if b != 0 {
+		data[n/8] |= mask
+	} else {
+		data[n/8] &^= mask
+	}
+}
+
+// interleaveTable returns the YSF interleaver for the given number of 40-bit columns: dibit i of the coded
+// stream is placed at bit 2*(i/columns) + 40*(i%columns) of the channel.
+func interleaveTable(columns int) []int {
+	table := make([]int, 20*columns)
+	for i := range table {
+		table[i] = 2*(i/columns) + 40*(i%columns)
+	}
+	return table
+}
+
+// decodeChannel deinterleaves and Viterbi decodes a data channel, returning the decoded bytes
+// without the tail bits and the path error count.
+func decodeChannel(channel []byte, columns int) ([]byte, int) {
+	table := interleaveTable(columns)
+	coded := make([]byte, 0, len(table)*2)
+	for _, n := range table {
+		coded = append(coded, readBit(channel, n), readBit(channel, n+1))
+	}
+
+	bits, errs := conv.Decode(coded, nil)
+	out := make([]byte, (len(bits)-4)/8)
+	for i := range out {
+		for j := 0; j < 8; j++ {
+			out[i] = out[i]<<1 | bits[i*8+j]
+		}
+	}
+	return out, errs
+}
+
+// encodeChannel convolutionally encodes and interleaves data into a channel of columns*5 bytes.
+func encodeChannel(data []byte, columns int) []byte {
+	bits := make([]byte, 0, len(data)*8+4)
+	for i := 0; i < len(data)*8; i++ {
+		bits = append(bits, readBit(data, i))
+	}
+	coded := conv.Encode(append(bits, 0, 0, 0, 0))
+
+	channel := make([]byte, columns*5)
+	for i, n := range interleaveTable(columns) {
+		writeBit(channel, n, coded[2*i])
+		writeBit(channel, n+1, coded[2*i+1])
+	}
+	return channel
+}
+
+// crcCCITT calculates the CRC-16 used by the FICH and data channels: polynomial 0x1021, MSB first,
+// initial value 0 and inverted.
+func crcCCITT(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+// addCRC writes the CRC of all but the last two bytes into the last two bytes, high byte first.
+func addCRC(data []byte) {
+	crc := crcCCITT(data[:len(data)-2])
+	data[len(data)-2] = byte(crc >> 8)
+	data[len(data)-1] = byte(crc)
+}
+
+// checkCRC reports whether the last two bytes hold the CRC of the rest of the data.
+func checkCRC(data []byte) bool {
+	if len(data) < 3 {
+		return false
+	}
+	crc := crcCCITT(data[:len(data)-2])
+	return data[len(data)-2] == byte(crc>>8) && data[len(data)-1] == byte(crc)
+}
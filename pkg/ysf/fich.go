@@ -0,0 +1,109 @@
+// Package ysf provides System Fusion protocol logic, including the frame information channel header.
+package ysf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unklstewy/mmdvm_ghost/pkg/utils"
+)
+
+// fichDataLength is the length of the FICH: 4 bytes of fields and 2 bytes of CRC, Golay coded 12 bits at a time.
+const fichDataLength = 6
+
+// FICH is the frame information channel header carried by every YSF frame.
+type FICH struct {
+	FI   uint8 // Frame information, FIHeader to FITest
+	CS   uint8 // Callsign information
+	CM   uint8 // Call mode, group or individual
+	BN   uint8 // Block number
+	BT   uint8 // Block total
+	FN   uint8 // Frame number within the transmission, cycling 0 to FT
+	FT   uint8 // Frame total
+	Dev  bool  // Low deviation
+	MR   uint8 // Message route
+	VoIP bool  // Frame passed through an internet link
+	DT   uint8 // Data type, DTVDMode1 to DTVoiceFRMode
+	SQL  bool  // Squelch code enabled
+	SQ   uint8 // Squelch code, also used as the DG-ID
+}
+
+// DecodeFICH decodes the FICH of a 120-byte frame, returning the number of bit errors corrected.
+func DecodeFICH(frame []byte) (*FICH, int, error) {
+	if len(frame) < SyncLength+FICHLength {
+		return nil, 0, errors.New("frame too short for FICH")
+	}
+
+	coded, errs := decodeChannel(frame[SyncLength:SyncLength+FICHLength], 5)
+
+	var raw [fichDataLength]byte
+	var words [4]uint32
+	for i := range words {
+		w, n := utils.DecodeGolay24128Bytes(coded[i*3 : i*3+3])
+		words[i] = w
+		errs += n
+	}
+	raw[0] = byte(words[0] >> 4)
+	raw[1] = byte(words[0]<<4) | byte(words[1]>>8&0x0F)
+	raw[2] = byte(words[1])
+	raw[3] = byte(words[2] >> 4)
+	raw[4] = byte(words[2]<<4) | byte(words[3]>>8&0x0F)
+	raw[5] = byte(words[3])
+
+	if !checkCRC(raw[:]) {
+		return nil, errs, errors.New("invalid FICH CRC")
+	}
+
+	return &FICH{
+		FI:   raw[0] >> 6 & 0x03,
+		CS:   raw[0] >> 4 & 0x03,
+		CM:   raw[0] >> 2 & 0x03,
+		BN:   raw[0] & 0x03,
+		BT:   raw[1] >> 6 & 0x03,
+		FN:   raw[1] >> 3 & 0x07,
+		FT:   raw[1] & 0x07,
+		Dev:  raw[2]&0x40 != 0,
+		MR:   raw[2] >> 3 & 0x03,
+		VoIP: raw[2]&0x04 != 0,
+		DT:   raw[2] & 0x03,
+		SQL:  raw[3]&0x80 != 0,
+		SQ:   raw[3] & 0x7F,
+	}, errs, nil
+}
+
+// Encode writes the FICH into a 120-byte frame.
+func (f *FICH) Encode(frame []byte) {
+	var raw [fichDataLength]byte
+	raw[0] = f.FI<<6 | (f.CS&0x03)<<4 | (f.CM&0x03)<<2 | f.BN&0x03
+	raw[1] = (f.BT&0x03)<<6 | (f.FN&0x07)<<3 | f.FT&0x07
+	raw[2] = (f.MR&0x03)<<3 | f.DT&0x03
+	if f.Dev {
+		raw[2] |= 0x40
+	}
+	if f.VoIP {
+		raw[2] |= 0x04
+	}
+	raw[3] = f.SQ & 0x7F
+	if f.SQL {
+		raw[3] |= 0x80
+	}
+	addCRC(raw[:])
+
+	words := [4]uint32{
+		uint32(raw[0])<<4 | uint32(raw[1])>>4,
+		uint32(raw[1]&0x0F)<<8 | uint32(raw[2]),
+		uint32(raw[3])<<4 | uint32(raw[4])>>4,
+		uint32(raw[4]&0x0F)<<8 | uint32(raw[5]),
+	}
+	coded := make([]byte, 12)
+	for i, w := range words {
+		utils.EncodeGolay24128Bytes(w, coded[i*3:i*3+3])
+	}
+
+	copy(frame[SyncLength:SyncLength+FICHLength], encodeChannel(coded, 5))
+}
+
+// String returns a short description of the FICH for logging.
+func (f *FICH) String() string {
+	return fmt.Sprintf("FI=%d DT=%d FN=%d/%d DG-ID=%d", f.FI, f.DT, f.FN, f.FT, f.SQ)
+}
@@ -0,0 +1,104 @@
+// Package ysf provides System Fusion protocol logic, including decoding of GPS positions sent by radios.
+package ysf
+
+// gpsLength is the number of data channel bytes holding a position: a header followed by the Mic-E
+// destination (latitude) and information field (longitude) characters.
+const gpsLength = 14
+
+// micEOffset is the value added to the Mic-E longitude characters.
+const micEOffset = 0x1C
+
+// Position is a GPS position heard on RF.
+type Position struct {
+	Latitude  float64 // Degrees, north positive
+	Longitude float64 // Degrees, east positive
+}
+
+// GPSDecoder collects the position that radios send in V/D mode 2 in the data channel of frames 6 and 7,
+// once per transmission.
+type GPSDecoder struct {
+	buffer [20]byte // Data channels of frames 6 and 7, 10 bytes each
+	have   [2]bool
+	sent   bool
+}
+
+// NewGPSDecoder creates a new GPSDecoder.
+func NewGPSDecoder() *GPSDecoder {
+	return &GPSDecoder{}
+}
+
+// Reset prepares the decoder for a new transmission.
+func (d *GPSDecoder) Reset() {
+	d.have = [2]bool{}
+	d.sent = false
+}
+
+// Add decodes the data channel of a frame, returning the position once both frames carrying it have been
+// received, nil otherwise.
+func (d *GPSDecoder) Add(frame []byte, fich *FICH) *Position {
+	if d.sent || fich.FI != FICommunications || fich.DT != DTVDMode2 || fich.FN < 6 || fich.FN > 7 {
+		return nil
+	}
+
+	part := int(fich.FN - 6)
+	data := decodeDCH(gatherDCH(frame, 0, dchVD2Length), 5)
+	if data == nil {
+		d.have[part] = false
+		return nil
+	}
+	copy(d.buffer[part*len(data):], data)
+	d.have[part] = true
+	if !d.have[0] || !d.have[1] {
+		return nil
+	}
+
+	d.sent = true
+	return decodeMicE(d.buffer[:gpsLength])
+}
+
+// decodeMicE decodes the Mic-E encoded position in bytes 5 to 13, returning nil when no valid position is
+// present. Bytes 5 to 10 are the latitude digits, their high nibble also encoding north, the longitude offset
+// and west; bytes 11 to 13 are the longitude degrees, minutes and hundredths of minutes.
+func decodeMicE(data []byte) *Position {
+	for _, b := range data[5:11] {
+		if (b&0xF0 != 0x30 && b&0xF0 != 0x50) || b&0x0F > 9 {
+			return nil
+		}
+	}
+	digit := func(i int) int { return int(data[i] & 0x0F) }
+
+	latitude := float64(digit(5)*10+digit(6)) +
+		float64(digit(7)*10+digit(8))/60 +
+		float64(digit(9)*10+digit(10))/6000
+	if data[8]&0xF0 != 0x50 {
+		latitude = -latitude
+	}
+
+	degrees := int(data[11]) - micEOffset
+	if data[9]&0xF0 == 0x50 {
+		degrees += 100
+	}
+	switch {
+	case degrees >= 180 && degrees <= 189:
+		degrees -= 80
+	case degrees >= 190 && degrees <= 199:
+		degrees -= 190
+	}
+	minutes := int(data[12]) - micEOffset
+	if minutes >= 60 {
+		minutes -= 60
+	}
+	hundredths := int(data[13]) - micEOffset
+	if degrees < 0 || degrees > 180 || minutes < 0 || hundredths < 0 || hundredths > 99 {
+		return nil
+	}
+
+	longitude := float64(degrees) + float64(minutes)/60 + float64(hundredths)/6000
+	if data[10]&0xF0 == 0x50 {
+		longitude = -longitude
+	}
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return nil
+	}
+	return &Position{Latitude: latitude, Longitude: longitude}
+}
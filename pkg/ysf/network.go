@@ -0,0 +1,339 @@
+// Package ysf provides System Fusion protocol logic, including the YSFGateway and reflector network link.
+package ysf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Network packet signatures.
+var (
+	ysfPoll   = []byte("YSFP") // Poll, carrying the callsign
+	ysfData   = []byte("YSFD") // Frame, carrying the callsigns and a sequence counter
+	ysfUnlink = []byte("YSFU") // Unlink, sent when the repeater shuts down
+)
+
+// dataPacketLength is the length of a YSFD packet: signature, gateway, source and destination callsigns,
+// counter and the 120-byte frame.
+const dataPacketLength = 35 + FrameLength
+
+// Network timing.
+const (
+	netPollInterval     = 5 * time.Second         // Interval between polls
+	netLinkTimeout      = 60 * time.Second        // Link treated as down without any packets for this long
+	netStreamTimeout    = 1500 * time.Millisecond // Incoming stream watchdog
+	netReconnectDelay   = 5 * time.Second         // Delay before reopening a failed socket
+	netReadPollInterval = 100 * time.Millisecond  // Read deadline used to run the timers
+)
+
+// Network is a UDP client speaking the YSFP/YSFD/YSFU protocol to a YSFGateway or reflector.
+type Network struct {
+	Data func(source, dest string, frame []byte, end bool) // Receives frames of incoming streams, may be nil
+
+	callsign  []byte
+	gateway   *net.UDPAddr
+	localPort int
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	stop     chan struct{}
+	linked   bool
+	lastRx   time.Time
+	lastPoll time.Time
+	outSeq   byte
+	inSource string
+	inSeq    byte
+	inLost   int
+	lastIn   time.Time
+}
+
+// NewNetwork creates a new Network identifying itself with the given callsign to the gateway at the given
+// address and port, listening on the given local port.
+func NewNetwork(callsign, gatewayAddress string, gatewayPort, localPort int) (*Network, error) {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", gatewayAddress, gatewayPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve gateway address: %w", err)
+	}
+
+	return &Network{
+		callsign:  padCallsign(strings.ToUpper(callsign)),
+		gateway:   addr,
+		localPort: localPort,
+	}, nil
+}
+
+// Open opens the UDP socket and starts the receive loop.
+func (n *Network) Open() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != nil {
+		return errors.New("network already open")
+	}
+	if err := n.openSocket(); err != nil {
+		return err
+	}
+
+	n.stop = make(chan struct{})
+	go n.run(n.stop)
+	log.Printf("YSF: Network opened to gateway %s", n.gateway)
+	return nil
+}
+
+// Close sends an unlink to the gateway, stops the receive loop and closes the UDP socket.
+func (n *Network) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop == nil {
+		return
+	}
+	if err := n.write(append(append([]byte{}, ysfUnlink...), n.callsign...)); err != nil {
+		log.Printf("YSF: Unable to unlink from gateway: %v", err)
+	}
+	close(n.stop)
+	n.stop = nil
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	log.Printf("YSF: Network closed")
+}
+
+// openSocket binds the local UDP port. The caller must hold the lock.
+func (n *Network) openSocket() error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: n.localPort})
+	if err != nil {
+		return fmt.Errorf("failed to open YSF network socket: %w", err)
+	}
+	n.conn = conn
+	return nil
+}
+
+// WriteData sends a 120-byte frame to the gateway. The end flag marks the last frame of a transmission.
+func (n *Network) WriteData(source, dest string, frame []byte, end bool) error {
+	if len(frame) < FrameLength {
+		return errors.New("frame too short")
+	}
+	if source == "" {
+		source = unknownCallsign
+	}
+	if dest == "" {
+		dest = "ALL"
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	counter := n.outSeq << 1
+	if end {
+		counter |= 0x01
+		n.outSeq = 0
+	} else {
+		n.outSeq = (n.outSeq + 1) & 0x7F
+	}
+
+	buffer := make([]byte, 0, dataPacketLength)
+	buffer = append(buffer, ysfData...)
+	buffer = append(buffer, n.callsign...)
+	buffer = append(buffer, padCallsign(source)...)
+	buffer = append(buffer, padCallsign(dest)...)
+	buffer = append(buffer, counter)
+	buffer = append(buffer, frame[:FrameLength]...)
+	return n.write(buffer)
+}
+
+// IsLinked reports whether the gateway has answered recently.
+func (n *Network) IsLinked() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.linked
+}
+
+// writePoll sends a poll carrying the callsign. The caller must hold the lock.
+func (n *Network) writePoll(now time.Time) error {
+	n.lastPoll = now
+	return n.write(append(append([]byte{}, ysfPoll...), n.callsign...))
+}
+
+// write sends a packet to the gateway. The caller must hold the lock.
+func (n *Network) write(buffer []byte) error {
+	if n.conn == nil {
+		return errors.New("network not open")
+	}
+	_, err := n.conn.WriteToUDP(buffer, n.gateway)
+	return err
+}
+
+// run receives packets, sends polls and reopens the socket after errors until stopped.
+func (n *Network) run(stop chan struct{}) {
+	buffer := make([]byte, 1500)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		n.mu.Lock()
+		conn := n.conn
+		n.mu.Unlock()
+
+		if conn == nil {
+			if !n.reconnect(stop) {
+				return
+			}
+			continue
+		}
+
+		conn.SetReadDeadline(time.Now().Add(netReadPollInterval))
+		length, addr, err := conn.ReadFromUDP(buffer)
+		now := time.Now()
+
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				log.Printf("YSF: Network read failed, reconnecting: %v", err)
+				n.mu.Lock()
+				if n.conn != nil {
+					n.conn.Close()
+					n.conn = nil
+				}
+				n.linked = false
+				n.mu.Unlock()
+				continue
+			}
+		} else if addr.IP.Equal(n.gateway.IP) && addr.Port == n.gateway.Port {
+			n.receive(buffer[:length], now)
+		} else {
+			log.Printf("YSF: Packet received from unknown address %s", addr)
+		}
+
+		n.clock(now)
+	}
+}
+
+// reconnect waits and reopens the socket, returning false if the network was closed meanwhile.
+func (n *Network) reconnect(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(netReconnectDelay):
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stop != stop {
+		return false
+	}
+	if err := n.openSocket(); err != nil {
+		log.Printf("YSF: Network reconnect failed: %v", err)
+		return true
+	}
+	log.Printf("YSF: Network reconnected to gateway %s", n.gateway)
+	return true
+}
+
+// clock runs the poll timer, the link watchdog and the incoming stream watchdog.
+func (n *Network) clock(now time.Time) {
+	n.mu.Lock()
+	if now.Sub(n.lastPoll) >= netPollInterval {
+		if err := n.writePoll(now); err != nil {
+			log.Printf("YSF: Unable to poll gateway: %v", err)
+		}
+	}
+	if n.linked && now.Sub(n.lastRx) > netLinkTimeout {
+		log.Printf("YSF: Link to gateway %s lost", n.gateway)
+		n.linked = false
+	}
+	if n.inSource != "" && now.Sub(n.lastIn) > netStreamTimeout {
+		log.Printf("YSF: Network stream from %s timed out", n.inSource)
+		n.endStream()
+	}
+	n.mu.Unlock()
+
+}
+
+// endStream logs the statistics of the incoming stream and resets it. The caller must hold the lock.
+func (n *Network) endStream() {
+	if n.inLost > 0 {
+		log.Printf("YSF: Network stream from %s lost %d frames", n.inSource, n.inLost)
+	}
+	n.inSource = ""
+	n.inLost = 0
+}
+
+// receive dispatches a packet from the gateway.
+func (n *Network) receive(buffer []byte, now time.Time) {
+	n.mu.Lock()
+	n.lastRx = now
+	if !n.linked {
+		log.Printf("YSF: Link to gateway %s established", n.gateway)
+		n.linked = true
+	}
+
+	if len(buffer) < 4 {
+		n.mu.Unlock()
+		return
+	}
+
+	switch {
+	case bytes.Equal(buffer[:4], ysfPoll):
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], ysfUnlink):
+		log.Printf("YSF: Unlinked by gateway %s", n.gateway)
+		n.linked = false
+		n.mu.Unlock()
+
+	case bytes.Equal(buffer[:4], ysfData):
+		if len(buffer) < dataPacketLength {
+			n.mu.Unlock()
+			return
+		}
+
+		source := callsign(buffer[14:24])
+		dest := callsign(buffer[24:34])
+		seq := buffer[34] >> 1
+		end := buffer[34]&0x01 == 0x01
+		frame := append([]byte{}, buffer[35:dataPacketLength]...)
+
+		if n.inSource == "" {
+			n.inSource = source
+			n.inSeq = seq
+			n.inLost = 0
+		} else if source != n.inSource {
+			// Another stream while busy
+			n.mu.Unlock()
+			return
+		} else if seq != n.inSeq {
+			n.inLost += int((seq - n.inSeq) & 0x7F)
+		}
+		n.inSeq = (seq + 1) & 0x7F
+		n.lastIn = now
+		if end {
+			n.endStream()
+		}
+		n.mu.Unlock()
+
+		if n.Data != nil {
+			n.Data(source, dest, frame, end)
+		}
+
+	default:
+		n.mu.Unlock()
+		log.Printf("YSF: Unknown network packet %x", buffer[:4])
+	}
+}
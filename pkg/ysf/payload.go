@@ -0,0 +1,154 @@
+// Package ysf provides System Fusion protocol logic, including callsign extraction from the data channel.
+package ysf
+
+import (
+	"strings"
+)
+
+// Data channel layout: the payload is five 18-byte blocks. Header, terminator and full rate data frames
+// carry two 9-byte data channel halves per block, V/D mode 1 one 9-byte half and V/D mode 2 a 5-byte part.
+const (
+	blockLength   = 18
+	blockCount    = 5
+	dchHalfLength = 9
+	dchVD2Length  = 5
+)
+
+// Callsigns holds the callsigns carried in the data channel, empty when not (yet) known.
+type Callsigns struct {
+	Dest     string // Destination callsign
+	Source   string // Source callsign
+	Downlink string // Downlink repeater callsign
+	Uplink   string // Uplink repeater callsign
+}
+
+// merge fills empty callsigns from other.
+func (c *Callsigns) merge(other Callsigns) {
+	if c.Dest == "" {
+		c.Dest = other.Dest
+	}
+	if c.Source == "" {
+		c.Source = other.Source
+	}
+	if c.Downlink == "" {
+		c.Downlink = other.Downlink
+	}
+	if c.Uplink == "" {
+		c.Uplink = other.Uplink
+	}
+}
+
+// gatherDCH collects length bytes at the given offset of each payload block.
+func gatherDCH(frame []byte, offset, length int) []byte {
+	payload := frame[SyncLength+FICHLength:]
+	out := make([]byte, 0, length*blockCount)
+	for i := 0; i < blockCount; i++ {
+		start := i*blockLength + offset
+		out = append(out, payload[start:start+length]...)
+	}
+	return out
+}
+
+// decodeDCH decodes a data channel of the given columns, returning the dewhitened data or nil when the CRC fails.
+func decodeDCH(channel []byte, columns int) []byte {
+	data, _ := decodeChannel(channel, columns)
+	if !checkCRC(data) {
+		return nil
+	}
+	data = data[:len(data)-2]
+	for i := range data {
+		data[i] ^= whiteningData[i]
+	}
+	return data
+}
+
+// encodeDCH whitens data, adds the CRC and encodes it into a data channel of the given columns.
+func encodeDCH(data []byte, columns int) []byte {
+	raw := make([]byte, len(data)+2)
+	for i, b := range data {
+		raw[i] = b ^ whiteningData[i]
+	}
+	addCRC(raw)
+	return encodeChannel(raw, columns)
+}
+
+// scatterDCH writes length bytes of channel at the given offset of each payload block.
+func scatterDCH(frame, channel []byte, offset, length int) {
+	payload := frame[SyncLength+FICHLength:]
+	for i := 0; i < blockCount; i++ {
+		start := i*blockLength + offset
+		copy(payload[start:start+length], channel[i*length:(i+1)*length])
+	}
+}
+
+// callsign trims a 10-character callsign field.
+func callsign(data []byte) string {
+	return strings.TrimRight(string(data), " \x00")
+}
+
+// ExtractCallsigns returns the callsigns carried in the data channel of a frame, as far as its
+// frame type and number allow.
+func ExtractCallsigns(frame []byte, fich *FICH) Callsigns {
+	var c Callsigns
+
+	switch {
+	case fich.FI == FIHeader || fich.FI == FITerminator || (fich.FI == FICommunications && fich.DT == DTDataFRMode):
+		// CSD1 in the first halves and CSD2 in the second halves, on full rate data frames only for FN 0
+		if fich.FI == FICommunications && fich.FN != 0 {
+			break
+		}
+		if csd1 := decodeDCH(gatherDCH(frame, 0, dchHalfLength), 9); csd1 != nil {
+			c.Dest, c.Source = callsign(csd1[:10]), callsign(csd1[10:20])
+		}
+		if csd2 := decodeDCH(gatherDCH(frame, dchHalfLength, dchHalfLength), 9); csd2 != nil {
+			c.Downlink, c.Uplink = callsign(csd2[:10]), callsign(csd2[10:20])
+		}
+
+	case fich.FI == FICommunications && fich.DT == DTVDMode1:
+		// FN 0 carries CSD1 and FN 1 carries CSD2
+		dch := decodeDCH(gatherDCH(frame, 0, dchHalfLength), 9)
+		if dch == nil {
+			break
+		}
+		switch fich.FN {
+		case 0:
+			c.Dest, c.Source = callsign(dch[:10]), callsign(dch[10:20])
+		case 1:
+			c.Downlink, c.Uplink = callsign(dch[:10]), callsign(dch[10:20])
+		}
+
+	case fich.FI == FICommunications && fich.DT == DTVDMode2:
+		// One callsign per frame, FN 0 to 3
+		dch := decodeDCH(gatherDCH(frame, 0, dchVD2Length), 5)
+		if dch == nil {
+			break
+		}
+		switch fich.FN {
+		case 0:
+			c.Dest = callsign(dch)
+		case 1:
+			c.Source = callsign(dch)
+		case 2:
+			c.Downlink = callsign(dch)
+		case 3:
+			c.Uplink = callsign(dch)
+		}
+	}
+
+	return c
+}
+
+// padCallsign pads or truncates a callsign to 10 characters.
+func padCallsign(call string) []byte {
+	out := []byte(strings.Repeat(" ", CallsignLength))
+	copy(out, call)
+	return out
+}
+
+// WriteHeaderCallsigns writes CSD1 and CSD2 into the data channel of a header or terminator frame.
+func WriteHeaderCallsigns(frame []byte, c Callsigns) {
+	csd1 := append(padCallsign(c.Dest), padCallsign(c.Source)...)
+	csd2 := append(padCallsign(c.Downlink), padCallsign(c.Uplink)...)
+	scatterDCH(frame, encodeDCH(csd1, 9), 0, dchHalfLength)
+	scatterDCH(frame, encodeDCH(csd2, 9), dchHalfLength, dchHalfLength)
+}
@@ -0,0 +1,259 @@
+// Package ysf provides System Fusion protocol logic, including Wires-X command handling.
+package ysf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Wires-X request types, bytes 1 to 3 of a command.
+var (
+	wiresXDXReq   = []byte{0x5D, 0x71, 0x5F}
+	wiresXConnReq = []byte{0x5D, 0x23, 0x5F}
+	wiresXDiscReq = []byte{0x5D, 0x2A, 0x5F}
+	wiresXAllReq  = []byte{0x5D, 0x66, 0x5F}
+)
+
+// wiresXAllResp starts a room list reply.
+var wiresXAllResp = []byte{0x5D, 0x46, 0x5F, 0x29}
+
+// wiresXEnd follows the last byte of a command or reply, before the checksum.
+const wiresXEnd = 0x03
+
+// wiresXChunk is the length of the data carried by one half of a full rate data frame's data channel.
+const wiresXChunk = 20
+
+// WiresXCommand is a recognised Wires-X request.
+type WiresXCommand int
+
+const (
+	WiresXNone       WiresXCommand = iota // Not a Wires-X request
+	WiresXDX                              // Node information request
+	WiresXConnect                         // Connect to a room or node
+	WiresXDisconnect                      // Disconnect from the current room or node
+	WiresXList                            // Room list request
+)
+
+// String returns the name of the command.
+func (c WiresXCommand) String() string {
+	switch c {
+	case WiresXDX:
+		return "DX"
+	case WiresXConnect:
+		return "connect"
+	case WiresXDisconnect:
+		return "disconnect"
+	case WiresXList:
+		return "list"
+	default:
+		return "none"
+	}
+}
+
+// WiresXRequest is a Wires-X request decoded from an RF transmission.
+type WiresXRequest struct {
+	Command WiresXCommand
+	Room    string // Room or node ID to connect to
+}
+
+// WiresXRoom is an entry of a room list reply.
+type WiresXRoom struct {
+	ID          string // 5-digit room ID
+	Name        string // Room name, up to 16 characters
+	Description string // Room description, up to 14 characters
+}
+
+// WiresXDecoder reassembles Wires-X requests from full rate data frames.
+type WiresXDecoder struct {
+	command []byte
+	filled  int
+}
+
+// NewWiresXDecoder creates a new WiresXDecoder.
+func NewWiresXDecoder() *WiresXDecoder {
+	return &WiresXDecoder{command: make([]byte, 300)}
+}
+
+// Reset discards any partially received request.
+func (d *WiresXDecoder) Reset() {
+	for i := range d.command {
+		d.command[i] = 0
+	}
+	d.filled = 0
+}
+
+// readHalf decodes one half of a full rate data frame's data channel into the command buffer.
+func (d *WiresXDecoder) readHalf(frame []byte, half, offset int) bool {
+	data := decodeDCH(gatherDCH(frame, half*dchHalfLength, dchHalfLength), 9)
+	if data == nil || offset+wiresXChunk > len(d.command) {
+		return false
+	}
+	copy(d.command[offset:], data)
+	if offset+wiresXChunk > d.filled {
+		d.filled = offset + wiresXChunk
+	}
+	return true
+}
+
+// Add processes a frame of an RF transmission. It returns the request once its last frame has been
+// received with a valid checksum, otherwise nil.
+func (d *WiresXDecoder) Add(frame []byte, fich *FICH) *WiresXRequest {
+	if fich.FI != FICommunications || fich.DT != DTDataFRMode {
+		return nil
+	}
+
+	// FN 0 carries the callsigns, FN 1 the first 20 bytes in its second half and later frames 40 bytes each
+	switch fich.FN {
+	case 0:
+		d.Reset()
+		return nil
+	case 1:
+		if !d.readHalf(frame, 1, 0) {
+			return nil
+		}
+	default:
+		offset := int(fich.FN-2)*2*wiresXChunk + wiresXChunk
+		if !d.readHalf(frame, 0, offset) || !d.readHalf(frame, 1, offset+wiresXChunk) {
+			return nil
+		}
+	}
+
+	if fich.FN != fich.FT {
+		return nil
+	}
+
+	end := bytes.LastIndexByte(d.command[:d.filled], wiresXEnd)
+	if end < 4 || end+1 >= len(d.command) || checksum(d.command[:end+1]) != d.command[end+1] {
+		return nil
+	}
+
+	switch {
+	case bytes.Equal(d.command[1:4], wiresXDXReq):
+		return &WiresXRequest{Command: WiresXDX}
+	case bytes.Equal(d.command[1:4], wiresXConnReq):
+		return &WiresXRequest{Command: WiresXConnect, Room: strings.TrimSpace(string(d.command[5:10]))}
+	case bytes.Equal(d.command[1:4], wiresXDiscReq):
+		return &WiresXRequest{Command: WiresXDisconnect}
+	case bytes.Equal(d.command[1:4], wiresXAllReq):
+		return &WiresXRequest{Command: WiresXList}
+	}
+	return nil
+}
+
+// checksum returns the 8-bit sum of the data, used to validate Wires-X commands and replies.
+func checksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+// fixedField pads or truncates s to length characters.
+func fixedField(s string, length int) []byte {
+	out := bytes.Repeat([]byte{' '}, length)
+	copy(out, s)
+	return out
+}
+
+// WiresXListReply builds the RF frames of a room list reply from the given node.
+func WiresXListReply(node, id string, rooms []WiresXRoom) [][]byte {
+	data := []byte{0}
+	data = append(data, wiresXAllResp...)
+	data = append(data, '2', '1')
+	data = append(data, fixedField(id, 5)...)
+	data = append(data, fixedField(node, CallsignLength)...)
+	data = append(data, fmt.Sprintf("%03d%03d", len(rooms), len(rooms))...)
+	data = append(data, 0x0D)
+
+	for _, room := range rooms {
+		entry := bytes.Repeat([]byte{' '}, 50)
+		entry[0] = '5'
+		copy(entry[1:6], fixedField(room.ID, 5))
+		copy(entry[6:22], fixedField(room.Name, 16))
+		copy(entry[22:25], "000")
+		copy(entry[35:49], fixedField(room.Description, 14))
+		entry[49] = 0x0D
+		data = append(data, entry...)
+	}
+
+	data = append(data, wiresXEnd)
+	data = append(data, checksum(data))
+	return wiresXFrames(node, data)
+}
+
+// wiresXFrameTotal returns the frame total for the data remaining after offset.
+func wiresXFrameTotal(length, offset int) uint8 {
+	remaining := length - offset
+	for ft, limit := range []int{20, 60, 100, 140, 180, 220} {
+		if remaining <= limit {
+			return uint8(ft + 1)
+		}
+	}
+	return 7
+}
+
+// wiresXFrames splits reply data into a header, full rate data frames and a terminator.
+func wiresXFrames(node string, data []byte) [][]byte {
+	// Pad to the 20 bytes of FN 1 plus whole frames of 40 bytes
+	length := wiresXChunk
+	if len(data) > wiresXChunk {
+		length += (len(data) - wiresXChunk + 2*wiresXChunk - 1) / (2 * wiresXChunk) * 2 * wiresXChunk
+	}
+	padded := make([]byte, length+wiresXChunk)
+	copy(padded, data)
+
+	csd := Callsigns{Dest: "**********", Source: node}
+	csd1 := append(padCallsign(csd.Dest), padCallsign(csd.Source)...)
+	csd2 := bytes.Repeat([]byte{' '}, 2*CallsignLength)
+
+	// Each block of eight frames carries 20 bytes in FN 1 and 40 bytes in each of FN 2 to 7
+	fich := &FICH{FI: FIHeader, CS: 1, BT: uint8((length - 1) / 260), FT: wiresXFrameTotal(length, 0), DT: DTDataFRMode}
+	newFrame := func() []byte {
+		frame := make([]byte, FrameLength)
+		copy(frame, SyncBytes)
+		fich.Encode(frame)
+		return frame
+	}
+
+	header := newFrame()
+	WriteHeaderCallsigns(header, csd)
+	frames := [][]byte{header}
+
+	fich.FI = FICommunications
+	var fn, bn uint8
+	for offset := 0; offset < length; {
+		var half1, half2 []byte
+		switch fn {
+		case 0:
+			fich.FT = wiresXFrameTotal(length, offset)
+			half1, half2 = csd1, csd2
+		case 1:
+			half1 = csd2
+			half2 = padded[offset : offset+wiresXChunk]
+			offset += wiresXChunk
+		default:
+			half1 = padded[offset : offset+wiresXChunk]
+			half2 = padded[offset+wiresXChunk : offset+2*wiresXChunk]
+			offset += 2 * wiresXChunk
+		}
+		fich.FN, fich.BN = fn, bn
+
+		frame := newFrame()
+		scatterDCH(frame, encodeDCH(half1, 9), 0, dchHalfLength)
+		scatterDCH(frame, encodeDCH(half2, 9), dchHalfLength, dchHalfLength)
+		frames = append(frames, frame)
+
+		if fn++; fn > 7 {
+			fn = 0
+			bn++
+		}
+	}
+
+	fich.FI = FITerminator
+	fich.FN, fich.BN = fn, bn
+	terminator := newFrame()
+	WriteHeaderCallsigns(terminator, csd)
+	return append(frames, terminator)
+}
@@ -2,15 +2,142 @@ package ysf
 
 import (
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/unklstewy/mmdvm_ghost/pkg/ax25"
 	"github.com/unklstewy/mmdvm_ghost/pkg/config"
+	"github.com/unklstewy/mmdvm_ghost/pkg/cwid"
+	"github.com/unklstewy/mmdvm_ghost/pkg/modem"
 )
 
+// control handles the YSF RF and network transmissions.
+var control *Control
+
+// network links the controller to a YSFGateway or reflector, nil when disabled.
+var network *Network
+
+// HandleYSFPacket passes a modem frame (tag byte plus 120-byte frame) to the YSF controller.
 func HandleYSFPacket(packet []byte) {
-	// TODO: Add YSF packet handling logic
+	if control == nil {
+		log.Printf("YSF: Packet received before initialization")
+		return
+	}
+	control.WriteModem(packet)
+}
+
+// modemCommands maps the tags of controller frames to the modem commands transmitting them.
+var modemCommands = map[byte]byte{
+	TagData: modem.CmdYSFData,
+}
+
+// writeModem transmits a controller frame (tag plus payload) on the modem.
+func writeModem(data []byte) {
+	command, ok := modemCommands[data[0]]
+	if !ok {
+		log.Printf("YSF: No modem command for tag 0x%02X", data[0])
+		return
+	}
+	if err := modem.Write(command, data[1:]); err != nil {
+		log.Printf("YSF: Unable to write to the modem: %v", err)
+	}
+}
+
+// clockInterval is how often the controller timeouts are checked.
+const clockInterval = 100 * time.Millisecond
+
+// stopClock stops the timer driving the controller timeouts, nil when it is not running.
+var stopClock chan struct{}
+
+// startClock checks the controller timeouts every clockInterval until the handler is initialized again.
+// The timer is independent of the network, so RF transmissions end even without a YSFGateway link.
+func startClock(c *Control) {
+	if stopClock != nil {
+		close(stopClock)
+	}
+	stop := make(chan struct{})
+	stopClock = stop
+
+	go func() {
+		ticker := time.NewTicker(clockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				c.CheckTimeouts(now)
+			}
+		}
+	}()
 }
 
 // Init initializes the YSF protocol handler with the given configuration.
 func Init(config config.YSFConfig) {
+	control = NewControl()
+	control.Events = func(event string, _ Callsigns) { cwid.Event(event) }
+	if modem.IsOpen() {
+		control.Output = writeModem
+	}
+	control.Node = config.Callsign
+	control.LowDeviation = config.LowDeviation
+	control.RemoteGateway = config.RemoteGateway
+	control.SelfOnly = config.SelfOnly
+	control.GPS = gateGPS
+	for _, rule := range config.DGIDRules {
+		if rule.DGID < 0 || rule.DGID > 99 {
+			log.Printf("YSF: Ignoring rule for invalid DG-ID %d", rule.DGID)
+			continue
+		}
+		switch rule.Route {
+		case RouteNetwork, RouteLocal, RouteBlock:
+		default:
+			log.Printf("YSF: Ignoring rule for DG-ID %d with invalid route %q", rule.DGID, rule.Route)
+			continue
+		}
+		control.Rules[uint8(rule.DGID)] = DGIDRule{Route: rule.Route, Name: rule.Name}
+	}
+
+	if network != nil {
+		network.Close()
+		network = nil
+	}
+	if config.Enable && config.NetworkEnable {
+		initNetwork(config)
+	}
+	startClock(control)
 	fmt.Printf("YSF protocol handler initialized with Port: %s\n", config.Port)
 }
+
+// gateGPS passes a position heard on RF to APRS, gated by the APRS-IS login callsign. The callsign suffix
+// radios may add after a slash or space is not part of an APRS source.
+func gateGPS(source string, position *Position) {
+	gateway := ax25.GatewayCallsign()
+	if gateway == "" {
+		return
+	}
+	if i := strings.IndexAny(source, " /"); i >= 0 {
+		source = source[:i]
+	}
+	ax25.SendAPRS(ax25.PositionPacket(strings.ToUpper(source), "APZYSF", gateway, position.Latitude, position.Longitude, "/[", ""))
+}
+
+// initNetwork opens the gateway link and connects it to the controller.
+func initNetwork(cfg config.YSFConfig) {
+	n, err := NewNetwork(cfg.Callsign, cfg.GatewayAddress, cfg.GatewayPort, cfg.LocalPort)
+	if err != nil {
+		log.Printf("YSF: %v", err)
+		return
+	}
+
+	c := control
+	n.Data = func(source, dest string, frame []byte, end bool) { c.WriteNetworkData(source, dest, frame, end) }
+
+	if err := n.Open(); err != nil {
+		log.Printf("YSF: %v", err)
+		return
+	}
+	c.Network = n
+	network = n
+}